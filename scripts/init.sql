@@ -22,8 +22,46 @@ CREATE TYPE AddressType AS ENUM (
 	'reference'
 );
 
+CREATE TYPE DataSubjectRequestType AS ENUM (
+	'access',
+	'rectification',
+	'cancellation',
+	'opposition'
+);
+
+CREATE TYPE DataSubjectRequestStatus AS ENUM (
+	'pending',
+	'completed',
+	'rejected'
+);
+
+CREATE TYPE PaymentType AS ENUM (
+	'rent',
+	'lateFee'
+);
+
+CREATE TYPE DocumentKind AS ENUM (
+	'ownerStatement'
+);
+
+CREATE TYPE ConfirmationStatus AS ENUM (
+	'pending',
+	'confirmed',
+	'declined'
+);
+
+CREATE TABLE organizations (
+	id UUID NOT NULL UNIQUE DEFAULT uuid_generate_v4(),
+	name TEXT NOT NULL,
+	managementCommission NUMERIC NOT NULL DEFAULT 0 CHECK (managementCommission >= 0 AND managementCommission <= 100),
+	createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updatedAt TIMESTAMP,
+	PRIMARY KEY(id)
+);
+
 CREATE TABLE addresses (
 	id UUID NOT NULL UNIQUE DEFAULT uuid_generate_v4(),
+	organizationId UUID NOT NULL,
 	type AddressType NOT NULL,
 	street TEXT NOT NULL,
 	number TEXT NOT NULL,
@@ -32,6 +70,7 @@ CREATE TABLE addresses (
 	state TEXT NOT NULL,
 	zipCode TEXT NOT NULL,
 	country TEXT NOT NULL,
+	version INTEGER NOT NULL DEFAULT 1,
 	createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
 	updatedAt TIMESTAMP,
 	deletedAt TIMESTAMP,
@@ -40,6 +79,7 @@ CREATE TABLE addresses (
 
 CREATE TABLE users (
 	id UUID NOT NULL UNIQUE DEFAULT uuid_generate_v4(),
+	organizationId UUID NOT NULL,
 	type UserType NOT NULL,
 	addressId UUID NOT NULL,
 	firstName TEXT NOT NULL,
@@ -47,6 +87,8 @@ CREATE TABLE users (
 	lastName TEXT NOT NULL,
 	email TEXT NOT NULL,
 	phone TEXT NOT NULL,
+	anonymizedAt TIMESTAMP,
+	version INTEGER NOT NULL DEFAULT 1,
 	createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
 	updatedAt TIMESTAMP,
 	deletedAt TIMESTAMP,
@@ -55,11 +97,13 @@ CREATE TABLE users (
 
 CREATE TABLE contracts (
     id UUID NOT NULL UNIQUE DEFAULT uuid_generate_v4(),
+    organizationId UUID NOT NULL,
     currentVersionId UUID,
     landlordId UUID NOT NULL,
     tenantId UUID NOT NULL,
     addressId UUID NOT NULL,
     deposit NUMERIC NOT NULL,
+    version INTEGER NOT NULL DEFAULT 1,
     createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
     updatedAt TIMESTAMP,
     deletedAt TIMESTAMP,
@@ -85,12 +129,172 @@ CREATE TABLE contractVersions (
     UNIQUE(contractId, versionNumber)
 );
 
+-- Each reference is asked to confirm they vouch for the tenant of the contract
 CREATE TABLE contractReferences (
 	contractId UUID NOT NULL,
 	referenceId UUID NOT NULL,
+	status ConfirmationStatus NOT NULL DEFAULT 'pending',
+	respondedAt TIMESTAMP,
 	PRIMARY KEY(contractId, referenceId)
 );
 
+CREATE TABLE userCredentials (
+	userId UUID NOT NULL,
+	passwordHash TEXT NOT NULL,
+	totpSecret TEXT,
+	totpEnabledAt TIMESTAMP,
+	totpLastStep BIGINT NOT NULL DEFAULT 0,
+	-- Failed two-factor codes since the last valid one, locking verification once too many
+	mfaFailedAttempts INTEGER NOT NULL DEFAULT 0,
+	mfaLockedUntil TIMESTAMP,
+	createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updatedAt TIMESTAMP,
+	PRIMARY KEY(userId)
+);
+
+CREATE TABLE sessions (
+	id UUID NOT NULL UNIQUE DEFAULT uuid_generate_v4(),
+	userId UUID NOT NULL,
+	refreshTokenId UUID NOT NULL,
+	expiresAt TIMESTAMP NOT NULL,
+	revokedAt TIMESTAMP,
+	mfaVerifiedAt TIMESTAMP,
+	-- The login challenge answered to start the session, so it cannot be answered twice
+	mfaChallengeId UUID UNIQUE,
+	createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY(id)
+);
+
+CREATE TABLE recoveryCodes (
+	id UUID NOT NULL UNIQUE DEFAULT uuid_generate_v4(),
+	userId UUID NOT NULL,
+	codeHash TEXT NOT NULL,
+	usedAt TIMESTAMP,
+	createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY(id)
+);
+
+CREATE TABLE apiKeys (
+	id UUID NOT NULL UNIQUE DEFAULT uuid_generate_v4(),
+	organizationId UUID NOT NULL,
+	userId UUID NOT NULL,
+	name TEXT NOT NULL,
+	prefix TEXT NOT NULL,
+	keyHash TEXT NOT NULL UNIQUE,
+	scopes JSONB NOT NULL DEFAULT '[]',
+	allowedIps JSONB NOT NULL DEFAULT '[]',
+	expiresAt TIMESTAMP,
+	lastUsedAt TIMESTAMP,
+	revokedAt TIMESTAMP,
+	createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY(id)
+);
+
+CREATE TABLE auditLogs (
+	id UUID NOT NULL UNIQUE DEFAULT uuid_generate_v4(),
+	organizationId UUID NOT NULL,
+	actorId UUID,
+	apiKeyId UUID,
+	action TEXT NOT NULL,
+	entityType TEXT NOT NULL,
+	entityId UUID NOT NULL,
+	changes JSONB NOT NULL DEFAULT '{}',
+	requestId TEXT,
+	createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY(id)
+);
+
+-- ARCO requests made by people over the personal data held about them
+CREATE TABLE dataSubjectRequests (
+	id UUID NOT NULL UNIQUE DEFAULT uuid_generate_v4(),
+	organizationId UUID NOT NULL,
+	userId UUID NOT NULL,
+	type DataSubjectRequestType NOT NULL,
+	status DataSubjectRequestStatus NOT NULL DEFAULT 'pending',
+	details TEXT,
+	resolution TEXT,
+	dueAt TIMESTAMP NOT NULL,
+	resolvedAt TIMESTAMP,
+	resolvedBy UUID,
+	createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY(id)
+);
+
+-- Rent paid towards a contract, applied to its oldest outstanding charges first, and late fees
+CREATE TABLE payments (
+	id UUID NOT NULL UNIQUE DEFAULT uuid_generate_v4(),
+	organizationId UUID NOT NULL,
+	contractId UUID NOT NULL,
+	type PaymentType NOT NULL DEFAULT 'rent',
+	amount NUMERIC NOT NULL,
+	paidAt DATE NOT NULL,
+	reference TEXT,
+	invoiceNumber TEXT,
+	vat NUMERIC NOT NULL DEFAULT 0,
+	incomeTaxWithheld NUMERIC NOT NULL DEFAULT 0,
+	createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY(id)
+);
+
+-- Expenses paid on behalf of a landlord, deducted from their statements
+CREATE TABLE expenses (
+	id UUID NOT NULL UNIQUE DEFAULT uuid_generate_v4(),
+	organizationId UUID NOT NULL,
+	landlordId UUID NOT NULL,
+	addressId UUID,
+	amount NUMERIC NOT NULL,
+	paidAt DATE NOT NULL,
+	description TEXT NOT NULL,
+	createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY(id)
+);
+
+-- Generated documents kept so they can be issued again exactly as they were
+CREATE TABLE documents (
+	id UUID NOT NULL UNIQUE DEFAULT uuid_generate_v4(),
+	organizationId UUID NOT NULL,
+	kind DocumentKind NOT NULL,
+	subjectId UUID NOT NULL,
+	periodStart DATE NOT NULL,
+	periodEnd DATE NOT NULL,
+	fileName TEXT NOT NULL,
+	contentType TEXT NOT NULL,
+	content BYTEA NOT NULL,
+	createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY(id)
+);
+
+-- Every version of the rows of the versioned tables, valid from validFrom until validTo
+CREATE TABLE history (
+	id BIGSERIAL NOT NULL,
+	tableName TEXT NOT NULL,
+	rowId UUID NOT NULL,
+	subId UUID,
+	data JSONB NOT NULL,
+	validFrom TIMESTAMP NOT NULL,
+	validTo TIMESTAMP,
+	PRIMARY KEY(id)
+);
+
+-- Keys are scoped to the caller, the user or the API key that sent the request
+CREATE TABLE idempotencyKeys (
+	organizationId UUID NOT NULL,
+	callerId UUID NOT NULL,
+	key TEXT NOT NULL,
+	fingerprint TEXT NOT NULL,
+	statusCode INTEGER,
+	contentType TEXT,
+	etag TEXT,
+	location TEXT,
+	responseBody BYTEA,
+	createdAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	completedAt TIMESTAMP,
+	PRIMARY KEY(organizationId, callerId, key)
+);
+
+ALTER TABLE idempotencyKeys
+ADD CONSTRAINT fk_idempotency_keys_organization FOREIGN KEY(organizationId) REFERENCES organizations(id) ON DELETE CASCADE;
+
 ALTER TABLE contractVersions
 ADD CONSTRAINT fk_contract_versions_contract FOREIGN KEY(contractId) REFERENCES contracts(id) ON DELETE CASCADE,
 ADD CONSTRAINT check_valid_dates CHECK (startDate < endDate),
@@ -98,6 +302,21 @@ ADD CONSTRAINT check_percentage CHECK (rentIncreasePercentage >= 0 AND rentIncre
 ADD CONSTRAINT check_positive_amounts CHECK (rent > 0),
 ADD CONSTRAINT check_version CHECK (versionNumber > 0);
 
+ALTER TABLE addresses
+ADD CONSTRAINT fk_addresses_organization FOREIGN KEY(organizationId) REFERENCES organizations(id) ON DELETE RESTRICT,
+ADD CONSTRAINT unique_addresses_organization UNIQUE(organizationId, id);
+
+ALTER TABLE users
+ADD CONSTRAINT fk_users_organization FOREIGN KEY(organizationId) REFERENCES organizations(id) ON DELETE RESTRICT,
+ADD CONSTRAINT unique_users_organization UNIQUE(organizationId, id);
+
+-- Related records must belong to the same organization
+ALTER TABLE contracts
+ADD CONSTRAINT fk_contracts_organization FOREIGN KEY(organizationId) REFERENCES organizations(id) ON DELETE RESTRICT,
+ADD CONSTRAINT fk_contracts_tenant_organization FOREIGN KEY(organizationId, tenantId) REFERENCES users(organizationId, id),
+ADD CONSTRAINT fk_contracts_landlord_organization FOREIGN KEY(organizationId, landlordId) REFERENCES users(organizationId, id),
+ADD CONSTRAINT fk_contracts_address_organization FOREIGN KEY(organizationId, addressId) REFERENCES addresses(organizationId, id);
+
 ALTER TABLE contracts
 ADD CONSTRAINT fk_contracts_current_version FOREIGN KEY(currentVersionId) REFERENCES contractVersions(id) ON DELETE RESTRICT,
 ADD CONSTRAINT fk_contracts_tenant FOREIGN KEY(tenantId) REFERENCES users(id) ON DELETE RESTRICT,
@@ -109,9 +328,49 @@ ALTER TABLE contractReferences
 ADD CONSTRAINT fk_contract_references_reference FOREIGN KEY(referenceId) REFERENCES users(id) ON DELETE RESTRICT,
 ADD CONSTRAINT fk_contract_references_contract FOREIGN KEY(contractId) REFERENCES contracts(id) ON DELETE CASCADE;
 
+ALTER TABLE userCredentials
+ADD CONSTRAINT fk_user_credentials_user FOREIGN KEY(userId) REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE sessions
+ADD CONSTRAINT fk_sessions_user FOREIGN KEY(userId) REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE recoveryCodes
+ADD CONSTRAINT fk_recovery_codes_user FOREIGN KEY(userId) REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE apiKeys
+ADD CONSTRAINT fk_api_keys_organization FOREIGN KEY(organizationId) REFERENCES organizations(id) ON DELETE CASCADE,
+ADD CONSTRAINT fk_api_keys_user FOREIGN KEY(organizationId, userId) REFERENCES users(organizationId, id) ON DELETE CASCADE;
+
+-- Audit entries outlive the users and keys that caused them
+ALTER TABLE auditLogs
+ADD CONSTRAINT fk_audit_logs_organization FOREIGN KEY(organizationId) REFERENCES organizations(id) ON DELETE CASCADE;
+
+ALTER TABLE dataSubjectRequests
+ADD CONSTRAINT fk_data_subject_requests_organization FOREIGN KEY(organizationId) REFERENCES organizations(id) ON DELETE CASCADE,
+ADD CONSTRAINT fk_data_subject_requests_user FOREIGN KEY(organizationId, userId) REFERENCES users(organizationId, id) ON DELETE CASCADE;
+
+ALTER TABLE payments
+ADD CONSTRAINT fk_payments_organization FOREIGN KEY(organizationId) REFERENCES organizations(id) ON DELETE RESTRICT,
+ADD CONSTRAINT fk_payments_contract FOREIGN KEY(contractId) REFERENCES contracts(id) ON DELETE RESTRICT,
+ADD CONSTRAINT check_positive_amounts CHECK (amount > 0),
+ADD CONSTRAINT check_tax_amounts CHECK (vat >= 0 AND incomeTaxWithheld >= 0 AND incomeTaxWithheld <= amount);
+
+ALTER TABLE expenses
+ADD CONSTRAINT fk_expenses_organization FOREIGN KEY(organizationId) REFERENCES organizations(id) ON DELETE RESTRICT,
+ADD CONSTRAINT fk_expenses_landlord FOREIGN KEY(organizationId, landlordId) REFERENCES users(organizationId, id),
+ADD CONSTRAINT fk_expenses_address FOREIGN KEY(organizationId, addressId) REFERENCES addresses(organizationId, id),
+ADD CONSTRAINT check_positive_amounts CHECK (amount > 0);
+
+ALTER TABLE documents
+ADD CONSTRAINT fk_documents_organization FOREIGN KEY(organizationId) REFERENCES organizations(id) ON DELETE CASCADE;
+
 ALTER TABLE users
-ADD CONSTRAINT fk_users_address FOREIGN KEY(addressId) REFERENCES addresses(id) ON DELETE RESTRICT;
+ADD CONSTRAINT fk_users_address FOREIGN KEY(addressId) REFERENCES addresses(id) ON DELETE RESTRICT,
+ADD CONSTRAINT fk_users_address_organization FOREIGN KEY(organizationId, addressId) REFERENCES addresses(organizationId, id);
 
+CREATE INDEX idx_addresses_organization ON addresses(organizationId) WHERE deletedAt IS NULL;
+CREATE INDEX idx_users_organization ON users(organizationId) WHERE deletedAt IS NULL;
+CREATE INDEX idx_contracts_organization ON contracts(organizationId) WHERE deletedAt IS NULL;
 CREATE INDEX idx_contracts_tenant ON contracts(tenantId) WHERE deletedAt IS NULL;
 CREATE INDEX idx_contracts_landlord ON contracts(landlordId) WHERE deletedAt IS NULL;
 CREATE INDEX idx_contract_versions_status ON contractVersions(status);
@@ -122,6 +381,18 @@ CREATE INDEX idx_contract_versions_version_number ON contractVersions(contractId
 CREATE INDEX idx_addresses_type ON addresses(type) WHERE deletedAt IS NULL;
 CREATE INDEX idx_users_type ON users(type) WHERE deletedAt IS NULL;
 CREATE UNIQUE INDEX idx_users_email_active ON users(email) WHERE deletedAt IS NULL;
+CREATE INDEX idx_sessions_user ON sessions(userId) WHERE revokedAt IS NULL;
+CREATE INDEX idx_recovery_codes_user ON recoveryCodes(userId) WHERE usedAt IS NULL;
+CREATE INDEX idx_api_keys_organization ON apiKeys(organizationId);
+CREATE INDEX idx_payments_contract ON payments(contractId, paidAt);
+CREATE INDEX idx_expenses_landlord ON expenses(landlordId, paidAt);
+CREATE INDEX idx_documents_subject ON documents(organizationId, kind, subjectId, periodStart);
+CREATE INDEX idx_history_row ON history(tableName, rowId, validFrom);
+CREATE INDEX idx_history_contract_versions ON history((data->>'contractid'), validFrom) WHERE tableName = 'contractversions';
+CREATE INDEX idx_audit_logs_entity ON auditLogs(organizationId, entityType, entityId, createdAt);
+CREATE INDEX idx_audit_logs_actor ON auditLogs(organizationId, actorId, createdAt);
+CREATE INDEX idx_audit_logs_created ON auditLogs(organizationId, createdAt);
+CREATE INDEX idx_data_subject_requests_status ON dataSubjectRequests(organizationId, status, dueAt);
 
 -- Function to update the updatedAt timestamp on update
 CREATE OR REPLACE FUNCTION update_timestamp()
@@ -132,6 +403,15 @@ BEGIN
 END;
 $$ LANGUAGE plpgsql;
 
+-- Function to increment the row version used for optimistic concurrency
+CREATE OR REPLACE FUNCTION increment_version()
+RETURNS TRIGGER AS $$
+BEGIN
+   NEW.version = OLD.version + 1;
+   RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
 -- Function to set the current version when a new version is added
 CREATE OR REPLACE FUNCTION update_contract_current_version()
 RETURNS TRIGGER AS $$
@@ -157,6 +437,37 @@ BEGIN
 END;
 $$ LANGUAGE plpgsql;
 
+-- Function to keep the history of a row. The first trigger argument names the
+-- column identifying the row and the optional second one a column that further
+-- identifies it when the first is not unique.
+CREATE OR REPLACE FUNCTION record_history()
+RETURNS TRIGGER AS $$
+DECLARE
+    old_row JSONB := CASE WHEN TG_OP = 'INSERT' THEN NULL ELSE to_jsonb(OLD) END;
+    new_row JSONB := CASE WHEN TG_OP = 'DELETE' THEN NULL ELSE to_jsonb(NEW) END;
+BEGIN
+    IF old_row IS NOT NULL THEN
+        UPDATE history
+        SET validTo = CURRENT_TIMESTAMP
+        WHERE tableName = TG_TABLE_NAME
+          AND rowId = (old_row->>TG_ARGV[0])::UUID
+          AND subId IS NOT DISTINCT FROM (old_row->>TG_ARGV[1])::UUID
+          AND validTo IS NULL;
+    END IF;
+
+    IF new_row IS NOT NULL THEN
+        INSERT INTO history(tableName, rowId, subId, data, validFrom)
+        VALUES (TG_TABLE_NAME, (new_row->>TG_ARGV[0])::UUID, (new_row->>TG_ARGV[1])::UUID, new_row, CURRENT_TIMESTAMP);
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Trigger to update the updatedAt timestamp on update
+CREATE TRIGGER update_organizations_timestamp BEFORE UPDATE ON organizations
+FOR EACH ROW EXECUTE FUNCTION update_timestamp();
+
 -- Trigger to update the updatedAt timestamp on update
 CREATE TRIGGER update_contracts_timestamp BEFORE UPDATE ON contracts
 FOR EACH ROW EXECUTE FUNCTION update_timestamp();
@@ -169,7 +480,44 @@ FOR EACH ROW EXECUTE FUNCTION update_timestamp();
 CREATE TRIGGER update_addresses_timestamp BEFORE UPDATE ON addresses
 FOR EACH ROW EXECUTE FUNCTION update_timestamp();
 
+-- Trigger to update the updatedAt timestamp on update
+CREATE TRIGGER update_user_credentials_timestamp BEFORE UPDATE ON userCredentials
+FOR EACH ROW EXECUTE FUNCTION update_timestamp();
+
+-- Trigger to increment the row version on update
+CREATE TRIGGER increment_contracts_version BEFORE UPDATE ON contracts
+FOR EACH ROW EXECUTE FUNCTION increment_version();
+
+-- Trigger to increment the row version on update
+CREATE TRIGGER increment_users_version BEFORE UPDATE ON users
+FOR EACH ROW EXECUTE FUNCTION increment_version();
+
+-- Trigger to increment the row version on update
+CREATE TRIGGER increment_addresses_version BEFORE UPDATE ON addresses
+FOR EACH ROW EXECUTE FUNCTION increment_version();
+
 -- Trigger to update the current version
 CREATE TRIGGER set_current_version
 AFTER INSERT ON contractVersions
 FOR EACH ROW EXECUTE FUNCTION update_contract_current_version();
+
+-- Triggers to keep the history used for point-in-time reads
+CREATE TRIGGER record_addresses_history
+AFTER INSERT OR UPDATE OR DELETE ON addresses
+FOR EACH ROW EXECUTE FUNCTION record_history('id');
+
+CREATE TRIGGER record_users_history
+AFTER INSERT OR UPDATE OR DELETE ON users
+FOR EACH ROW EXECUTE FUNCTION record_history('id');
+
+CREATE TRIGGER record_contracts_history
+AFTER INSERT OR UPDATE OR DELETE ON contracts
+FOR EACH ROW EXECUTE FUNCTION record_history('id');
+
+CREATE TRIGGER record_contract_versions_history
+AFTER INSERT OR UPDATE OR DELETE ON contractVersions
+FOR EACH ROW EXECUTE FUNCTION record_history('id');
+
+CREATE TRIGGER record_contract_references_history
+AFTER INSERT OR UPDATE OR DELETE ON contractReferences
+FOR EACH ROW EXECUTE FUNCTION record_history('contractid', 'referenceid');
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"strings"
+
+	"github.com/edfloreshz/rent-contracts/src/models"
+)
+
+// Permission is an action a principal may perform on a kind of resource
+type Permission string
+
+const (
+	ReadAddresses  Permission = "addresses:read"
+	WriteAddresses Permission = "addresses:write"
+	ReadUsers      Permission = "users:read"
+	WriteUsers     Permission = "users:write"
+	ReadContracts  Permission = "contracts:read"
+	WriteContracts Permission = "contracts:write"
+	ReadDocuments  Permission = "documents:read"
+	ReadStatistics Permission = "statistics:read"
+	ReadReports    Permission = "reports:read"
+
+	ReadOrganization  Permission = "organization:read"
+	WriteOrganization Permission = "organization:write"
+
+	ManageAPIKeys Permission = "apikeys:manage"
+
+	ReadAudit Permission = "audit:read"
+
+	ManagePrivacy Permission = "privacy:manage"
+
+	ManageAccounting Permission = "accounting:manage"
+
+	ReadConfirmations  Permission = "confirmations:read"
+	WriteConfirmations Permission = "confirmations:write"
+)
+
+// rolePermissions lists what each user type may do. Admins manage everything and
+// follow the confirmation requests sent to references. Tenants may read their own
+// profile, and their contracts with the documents and payments of those.
+// References may only read and answer the confirmation requests addressed to them.
+var rolePermissions = map[models.UserType][]Permission{
+	models.AdminUser: {
+		ReadAddresses, WriteAddresses,
+		ReadUsers, WriteUsers,
+		ReadContracts, WriteContracts,
+		ReadDocuments,
+		ReadStatistics,
+		ReadReports,
+		ReadOrganization, WriteOrganization,
+		ManageAPIKeys,
+		ReadAudit,
+		ManagePrivacy,
+		ManageAccounting,
+		ReadConfirmations,
+	},
+	models.TenantUser: {
+		ReadUsers,
+		ReadContracts,
+		ReadDocuments,
+		ReadOrganization,
+	},
+	models.ReferenceUser: {
+		ReadConfirmations, WriteConfirmations,
+	},
+}
+
+// Can reports whether the principal holds the given permission. Principals
+// authenticated with an API key are further limited to the scopes of the key.
+func (p *Principal) Can(permission Permission) bool {
+	if p.System {
+		return true
+	}
+
+	if p.APIKeyID != nil && !hasPermission(p.Scopes, permission) {
+		return false
+	}
+
+	return hasPermission(rolePermissions[p.Type], permission)
+}
+
+// SeesEverything reports whether the principal's reads are not limited to its own records
+func (p *Principal) SeesEverything() bool {
+	return p.System || p.Type == models.AdminUser
+}
+
+// Permissions returns the permissions granted to the given user type
+func Permissions(userType models.UserType) []Permission {
+	return append([]Permission(nil), rolePermissions[userType]...)
+}
+
+// ReadOnly reports whether the permission only allows reading
+func (p Permission) ReadOnly() bool {
+	return strings.HasSuffix(string(p), ":read")
+}
+
+func hasPermission(permissions []Permission, permission Permission) bool {
+	for _, granted := range permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
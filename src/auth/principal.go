@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/edfloreshz/rent-contracts/src/models"
+	"github.com/google/uuid"
+)
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	UserID         uuid.UUID
+	SessionID      uuid.UUID
+	OrganizationID uuid.UUID
+	Type           models.UserType
+
+	// MFAVerifiedAt is when the session last passed two-factor verification
+	MFAVerifiedAt *time.Time
+
+	// MFAEnrolled is whether the user has two-factor authentication enabled,
+	// without which they cannot pass two-factor verification
+	MFAEnrolled bool
+
+	// APIKeyID is set when the caller authenticated with an API key, whose
+	// scopes then limit what the principal may do
+	APIKeyID *uuid.UUID
+	Scopes   []Permission
+
+	// System marks internal callers such as background jobs, which bypass access checks
+	System bool
+}
+
+// System is the principal used by background jobs that run outside of a request
+var System = &Principal{System: true}
+
+type contextKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the authenticated principal
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, principal)
+}
+
+// PrincipalFromContext returns the authenticated principal stored in ctx, if any
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(contextKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
+
+// VerifiedMFASince reports whether the principal passed two-factor verification at
+// or after the given time. API keys cannot perform two-factor verification, so they
+// can never delete, purge or export records, whatever their scopes: those actions
+// need a person who just proved their second factor, and integrations that must
+// perform them do so through a user session.
+func (p *Principal) VerifiedMFASince(since time.Time) bool {
+	if p.System {
+		return true
+	}
+	return p.APIKeyID == nil && p.MFAVerifiedAt != nil && !p.MFAVerifiedAt.Before(since)
+}
@@ -2,19 +2,45 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"time"
 )
 
 type Config struct {
-	DatabaseURL string
-	Port        string
-	Environment string
+	DatabaseURL           string
+	Port                  string
+	Environment           string
+	JWTSecret             string
+	AccessTokenTTL        time.Duration
+	RefreshTokenTTL       time.Duration
+	MFARecentWindow       time.Duration
+	TrashRetention        time.Duration
+	PersonalDataRetention time.Duration
+	// Default assumptions of the cash flow forecast
+	ForecastRenewalProbability float64
+	ForecastVacancyMonths      int
+	// How far, in percent, below the local median rent a contract is flagged as under-rented
+	UnderRentThreshold float64
+	AdminEmail         string
+	AdminPassword      string
 }
 
 func New() *Config {
 	return &Config{
-		DatabaseURL: GetEnv("DATABASE_URL", "postgres://postgres:postgres@db/rent-contracts?sslmode=disable"),
-		Port:        GetEnv("PORT", "8080"),
-		Environment: GetEnv("ENVIRONMENT", "development"),
+		DatabaseURL:                GetEnv("DATABASE_URL", "postgres://postgres:postgres@db/rent-contracts?sslmode=disable"),
+		Port:                       GetEnv("PORT", "8080"),
+		Environment:                GetEnv("ENVIRONMENT", "development"),
+		JWTSecret:                  GetEnv("JWT_SECRET", ""),
+		AccessTokenTTL:             GetDurationEnv("ACCESS_TOKEN_TTL", 15*time.Minute),
+		RefreshTokenTTL:            GetDurationEnv("REFRESH_TOKEN_TTL", 7*24*time.Hour),
+		MFARecentWindow:            GetDurationEnv("MFA_RECENT_WINDOW", 15*time.Minute),
+		TrashRetention:             GetDurationEnv("TRASH_RETENTION", 90*24*time.Hour),
+		PersonalDataRetention:      GetDurationEnv("PERSONAL_DATA_RETENTION", 5*365*24*time.Hour),
+		ForecastRenewalProbability: GetFloatEnv("FORECAST_RENEWAL_PROBABILITY", 0.8),
+		ForecastVacancyMonths:      GetIntEnv("FORECAST_VACANCY_MONTHS", 2),
+		UnderRentThreshold:         GetFloatEnv("UNDER_RENT_THRESHOLD", 15),
+		AdminEmail:                 GetEnv("ADMIN_EMAIL", ""),
+		AdminPassword:              GetEnv("ADMIN_PASSWORD", ""),
 	}
 }
 
@@ -24,3 +50,27 @@ func GetEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// GetDurationEnv reads a duration such as "15m" or "168h" from the environment
+func GetDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil && value > 0 {
+		return value
+	}
+	return defaultValue
+}
+
+// GetFloatEnv reads a non-negative number from the environment
+func GetFloatEnv(key string, defaultValue float64) float64 {
+	if value, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil && value >= 0 {
+		return value
+	}
+	return defaultValue
+}
+
+// GetIntEnv reads a non-negative integer from the environment
+func GetIntEnv(key string, defaultValue int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil && value >= 0 {
+		return value
+	}
+	return defaultValue
+}
@@ -20,6 +20,10 @@ func Initialize(databaseURL string) (*gorm.DB, error) {
 
 	log.Println("Connected to database successfully")
 
+	if err := RegisterOrganizationScope(database); err != nil {
+		return nil, err
+	}
+
 	return database, nil
 }
 
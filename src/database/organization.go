@@ -0,0 +1,95 @@
+package database
+
+import (
+	"reflect"
+
+	"github.com/edfloreshz/rent-contracts/src/auth"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// organizationField is the model field that ties a record to an organization
+const organizationField = "OrganizationID"
+
+// RegisterOrganizationScope confines every statement on models with an OrganizationID
+// field to the organization of the principal in the statement context. New records
+// are assigned to that organization, statements without a principal match no rows
+// and the system principal is not confined.
+func RegisterOrganizationScope(db *gorm.DB) error {
+	callbacks := db.Callback()
+
+	if err := callbacks.Create().Before("gorm:create").Register("organization:assign", assignOrganization); err != nil {
+		return err
+	}
+	if err := callbacks.Query().Before("gorm:query").Register("organization:filter", filterOrganization); err != nil {
+		return err
+	}
+	if err := callbacks.Row().Before("gorm:row").Register("organization:filter", filterOrganization); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("organization:filter", filterOrganization); err != nil {
+		return err
+	}
+	return callbacks.Delete().Before("gorm:delete").Register("organization:filter", filterOrganization)
+}
+
+// organizationOf returns the organization field of the statement's model and the
+// organization the statement is confined to. It returns a nil field when the
+// statement is not confined.
+func organizationOf(db *gorm.DB) (*schema.Field, uuid.UUID) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return nil, uuid.Nil
+	}
+
+	field := db.Statement.Schema.LookUpField(organizationField)
+	if field == nil {
+		return nil, uuid.Nil
+	}
+
+	principal, ok := auth.PrincipalFromContext(db.Statement.Context)
+	if !ok {
+		// The nil organization does not exist, so nothing matches
+		return field, uuid.Nil
+	}
+	if principal.System {
+		return nil, uuid.Nil
+	}
+
+	return field, principal.OrganizationID
+}
+
+func filterOrganization(db *gorm.DB) {
+	field, organizationID := organizationOf(db)
+	if field == nil {
+		return
+	}
+
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: organizationID},
+	}})
+}
+
+func assignOrganization(db *gorm.DB) {
+	field, organizationID := organizationOf(db)
+	if field == nil {
+		return
+	}
+
+	ctx := db.Statement.Context
+	value := db.Statement.ReflectValue
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := field.Set(ctx, reflect.Indirect(value.Index(i)), organizationID); err != nil {
+				db.AddError(err)
+				return
+			}
+		}
+	case reflect.Struct:
+		if err := field.Set(ctx, value, organizationID); err != nil {
+			db.AddError(err)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package database
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/edfloreshz/rent-contracts/src/auth"
+	"github.com/edfloreshz/rent-contracts/src/models"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// dryRunDB returns a database with the organization scope that builds statements
+// without running them, so their SQL can be inspected without a server
+func dryRunDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost dbname=rent-contracts sslmode=disable"}), &gorm.Config{
+		DryRun:                 true,
+		SkipDefaultTransaction: true,
+		DisableAutomaticPing:   true,
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err := RegisterOrganizationScope(db); err != nil {
+		t.Fatalf("register organization scope: %v", err)
+	}
+	return db
+}
+
+func principalContext(organizationID uuid.UUID) context.Context {
+	return auth.WithPrincipal(context.Background(), &auth.Principal{
+		UserID:         uuid.New(),
+		OrganizationID: organizationID,
+		Type:           models.AdminUser,
+	})
+}
+
+// assertConfined fails unless the statement is filtered by the given organization
+func assertConfined(t *testing.T, statement *gorm.Statement, table string, organizationID uuid.UUID) {
+	t.Helper()
+
+	column := `"` + table + `"."organizationid" = `
+	if !strings.Contains(statement.SQL.String(), column) {
+		t.Fatalf("statement is not confined to an organization: %s", statement.SQL.String())
+	}
+	for _, v := range statement.Vars {
+		if v == organizationID {
+			return
+		}
+	}
+	t.Fatalf("statement is not confined to organization %s: %s %v", organizationID, statement.SQL.String(), statement.Vars)
+}
+
+func TestOrganizationScopeFiltersStatements(t *testing.T) {
+	db := dryRunDB(t)
+	organizationID := uuid.New()
+	ctx := principalContext(organizationID)
+	id := uuid.New()
+
+	t.Run("query", func(t *testing.T) {
+		var addresses []models.Address
+		assertConfined(t, db.WithContext(ctx).Find(&addresses).Statement, "addresses", organizationID)
+	})
+
+	t.Run("first", func(t *testing.T) {
+		var user models.User
+		assertConfined(t, db.WithContext(ctx).First(&user, id).Statement, "users", organizationID)
+	})
+
+	t.Run("unscoped", func(t *testing.T) {
+		var contracts []models.Contract
+		assertConfined(t, db.WithContext(ctx).Unscoped().Where("deletedat IS NOT NULL").Find(&contracts).Statement, "contracts", organizationID)
+	})
+
+	t.Run("count", func(t *testing.T) {
+		var total int64
+		assertConfined(t, db.WithContext(ctx).Model(&models.Contract{}).Count(&total).Statement, "contracts", organizationID)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		assertConfined(t, db.WithContext(ctx).Model(&models.Address{ID: id}).Update("city", "Monterrey").Statement, "addresses", organizationID)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		assertConfined(t, db.WithContext(ctx).Delete(&models.User{}, id).Statement, "users", organizationID)
+	})
+
+	t.Run("unscoped delete", func(t *testing.T) {
+		assertConfined(t, db.WithContext(ctx).Unscoped().Delete(&models.Contract{}, id).Statement, "contracts", organizationID)
+	})
+}
+
+func TestOrganizationScopeAssignsCreatedRecords(t *testing.T) {
+	db := dryRunDB(t)
+	organizationID := uuid.New()
+	ctx := principalContext(organizationID)
+
+	address := models.Address{OrganizationID: uuid.New(), Street: "Av. Juárez"}
+	if err := db.WithContext(ctx).Create(&address).Error; err != nil {
+		t.Fatalf("create address: %v", err)
+	}
+	if address.OrganizationID != organizationID {
+		t.Fatalf("address assigned to %s, want %s", address.OrganizationID, organizationID)
+	}
+
+	users := []models.User{{FirstName: "Ana"}, {FirstName: "Luis", OrganizationID: uuid.New()}}
+	if err := db.WithContext(ctx).Create(&users).Error; err != nil {
+		t.Fatalf("create users: %v", err)
+	}
+	for _, user := range users {
+		if user.OrganizationID != organizationID {
+			t.Fatalf("user %s assigned to %s, want %s", user.FirstName, user.OrganizationID, organizationID)
+		}
+	}
+}
+
+func TestOrganizationScopeWithoutPrincipalMatchesNothing(t *testing.T) {
+	db := dryRunDB(t)
+	ctx := context.Background()
+
+	var contracts []models.Contract
+	assertConfined(t, db.WithContext(ctx).Find(&contracts).Statement, "contracts", uuid.Nil)
+	assertConfined(t, db.WithContext(ctx).Model(&models.User{ID: uuid.New()}).Update("phone", "").Statement, "users", uuid.Nil)
+
+	address := models.Address{OrganizationID: uuid.New()}
+	if err := db.WithContext(ctx).Create(&address).Error; err != nil {
+		t.Fatalf("create address: %v", err)
+	}
+	if address.OrganizationID != uuid.Nil {
+		t.Fatalf("address created without a principal assigned to %s", address.OrganizationID)
+	}
+}
+
+func TestOrganizationScopeSkipsSystemAndUnscopedModels(t *testing.T) {
+	db := dryRunDB(t)
+
+	var addresses []models.Address
+	statement := db.WithContext(auth.WithPrincipal(context.Background(), auth.System)).Find(&addresses).Statement
+	if strings.Contains(statement.SQL.String(), "organizationid") {
+		t.Fatalf("system statement is confined: %s", statement.SQL.String())
+	}
+
+	var versions []models.ContractVersion
+	statement = db.WithContext(principalContext(uuid.New())).Find(&versions).Statement
+	if strings.Contains(statement.SQL.String(), "organizationid") {
+		t.Fatalf("statement on a model without an organization is confined: %s", statement.SQL.String())
+	}
+}
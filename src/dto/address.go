@@ -26,6 +26,18 @@ type UpdateAddressRequest struct {
 	Country      *string `json:"country,omitempty"`
 }
 
+// AddressPatch is the document JSON merge patches for an address are applied to
+type AddressPatch struct {
+	Type         string `json:"type"`
+	Street       string `json:"street"`
+	Number       string `json:"number"`
+	Neighborhood string `json:"neighborhood"`
+	City         string `json:"city"`
+	State        string `json:"state"`
+	ZipCode      string `json:"zipCode"`
+	Country      string `json:"country"`
+}
+
 type AddressResponse struct {
 	ID           uuid.UUID `json:"id"`
 	Type         string    `json:"type"`
@@ -36,6 +48,8 @@ type AddressResponse struct {
 	State        string    `json:"state"`
 	ZipCode      string    `json:"zipCode"`
 	Country      string    `json:"country"`
+	Version      int       `json:"version"`
 	CreatedAt    string    `json:"createdAt"`
 	UpdatedAt    *string   `json:"updatedAt"`
+	DeletedAt    *string   `json:"deletedAt,omitempty"`
 }
@@ -0,0 +1,37 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type CreateAPIKeyRequest struct {
+	Name string `json:"name" binding:"required"`
+
+	// Scopes lists the permissions granted to the key, such as "contracts:read".
+	// Use "read" to grant every read permission of the creator.
+	Scopes []string `json:"scopes" binding:"required,min=1"`
+
+	// AllowedIPs optionally restricts the key to the given addresses or CIDR ranges
+	AllowedIPs []string   `json:"allowedIps,omitempty"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+}
+
+type APIKeyResponse struct {
+	ID         uuid.UUID `json:"id"`
+	Name       string    `json:"name"`
+	Prefix     string    `json:"prefix"`
+	Scopes     []string  `json:"scopes"`
+	AllowedIPs []string  `json:"allowedIps"`
+	ExpiresAt  *string   `json:"expiresAt"`
+	LastUsedAt *string   `json:"lastUsedAt"`
+	RevokedAt  *string   `json:"revokedAt"`
+	CreatedAt  string    `json:"createdAt"`
+}
+
+// CreateAPIKeyResponse carries the plaintext key, which is only ever returned once
+type CreateAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
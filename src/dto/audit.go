@@ -0,0 +1,22 @@
+package dto
+
+import (
+	"github.com/google/uuid"
+)
+
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+type AuditLogResponse struct {
+	ID         uuid.UUID              `json:"id"`
+	ActorID    *uuid.UUID             `json:"actorId"`
+	APIKeyID   *uuid.UUID             `json:"apiKeyId"`
+	Action     string                 `json:"action"`
+	EntityType string                 `json:"entityType"`
+	EntityID   uuid.UUID              `json:"entityId"`
+	Changes    map[string]AuditChange `json:"changes"`
+	RequestID  *string                `json:"requestId"`
+	CreatedAt  string                 `json:"createdAt"`
+}
@@ -0,0 +1,36 @@
+package dto
+
+import (
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+type BulkRequest struct {
+	Mode       string          `json:"mode" binding:"omitempty,oneof=atomic bestEffort"`
+	Operations []BulkOperation `json:"operations" binding:"required"`
+}
+
+type BulkOperation struct {
+	Op      string          `json:"op" binding:"required,oneof=create update delete"`
+	ID      *uuid.UUID      `json:"id,omitempty"`
+	Version *int            `json:"version,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Cascade bool            `json:"cascade,omitempty"`
+}
+
+type BulkItemResult struct {
+	Index  int        `json:"index"`
+	Op     string     `json:"op"`
+	Status string     `json:"status"`
+	ID     *uuid.UUID `json:"id,omitempty"`
+	Error  string     `json:"error,omitempty"`
+}
+
+type BulkResponse struct {
+	Mode      string           `json:"mode"`
+	Committed bool             `json:"committed"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Results   []BulkItemResult `json:"results"`
+}
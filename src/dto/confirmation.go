@@ -0,0 +1,22 @@
+package dto
+
+import (
+	"github.com/edfloreshz/rent-contracts/src/models"
+	"github.com/google/uuid"
+)
+
+type RespondConfirmationRequest struct {
+	Status models.ConfirmationStatus `json:"status" binding:"required,oneof=confirmed declined"`
+}
+
+type ConfirmationResponse struct {
+	ContractID  uuid.UUID                 `json:"contractId"`
+	ReferenceID uuid.UUID                 `json:"referenceId"`
+	Status      models.ConfirmationStatus `json:"status"`
+	RespondedAt *string                   `json:"respondedAt"`
+	Tenant      string                    `json:"tenant"`
+	Landlord    string                    `json:"landlord"`
+	Property    string                    `json:"property"`
+	StartDate   string                    `json:"startDate"`
+	EndDate     string                    `json:"endDate"`
+}
@@ -18,10 +18,19 @@ type UpdateContractRequest struct {
 	LandlordID   *uuid.UUID  `json:"landlordId,omitempty"`
 	TenantID     *uuid.UUID  `json:"tenantId,omitempty"`
 	AddressID    *uuid.UUID  `json:"addressId,omitempty"`
-	Deposit      float64     `json:"deposit" binding:"required,min=0"`
+	Deposit      *float64    `json:"deposit,omitempty" binding:"omitempty,min=0"`
 	ReferenceIDs []uuid.UUID `json:"referenceIds,omitempty"`
 }
 
+// ContractPatch is the document JSON merge patches for a contract are applied to
+type ContractPatch struct {
+	LandlordID   uuid.UUID   `json:"landlordId"`
+	TenantID     uuid.UUID   `json:"tenantId"`
+	AddressID    uuid.UUID   `json:"addressId"`
+	Deposit      float64     `json:"deposit"`
+	ReferenceIDs []uuid.UUID `json:"referenceIds"`
+}
+
 type ContractResponse struct {
 	ID               uuid.UUID                 `json:"id"`
 	CurrentVersionID *uuid.UUID                `json:"currentVersionId"`
@@ -29,8 +38,10 @@ type ContractResponse struct {
 	TenantID         uuid.UUID                 `json:"tenantId"`
 	AddressID        uuid.UUID                 `json:"addressId"`
 	Deposit          float64                   `json:"deposit" binding:"required,min=0"`
+	Version          int                       `json:"version"`
 	CreatedAt        string                    `json:"createdAt"`
 	UpdatedAt        *string                   `json:"updatedAt"`
+	DeletedAt        *string                   `json:"deletedAt,omitempty"`
 	CurrentVersion   *ContractVersionResponse  `json:"currentVersion,omitempty"`
 	Landlord         *UserResponse             `json:"landlord,omitempty"`
 	Tenant           *UserResponse             `json:"tenant,omitempty"`
@@ -65,6 +76,19 @@ type UpdateContractVersionRequest struct {
 	SpecialTerms           *string    `json:"specialTerms,omitempty"`
 }
 
+// ContractVersionPatch is the document JSON merge patches for a contract version are applied to
+type ContractVersionPatch struct {
+	Rent                   float64    `json:"rent"`
+	RentIncreasePercentage float64    `json:"rentIncreasePercentage"`
+	Business               string     `json:"business"`
+	Status                 string     `json:"status"`
+	Type                   string     `json:"type"`
+	StartDate              time.Time  `json:"startDate"`
+	EndDate                time.Time  `json:"endDate"`
+	RenewalDate            *time.Time `json:"renewalDate"`
+	SpecialTerms           *string    `json:"specialTerms"`
+}
+
 type ContractVersionResponse struct {
 	ID                     uuid.UUID `json:"id"`
 	ContractID             uuid.UUID `json:"contractId"`
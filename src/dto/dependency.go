@@ -0,0 +1,15 @@
+package dto
+
+import "github.com/google/uuid"
+
+type DependentResponse struct {
+	Type     string    `json:"type"`
+	ID       uuid.UUID `json:"id"`
+	Relation string    `json:"relation"`
+}
+
+// DependencyConflictResponse lists the records that stop a record from being deleted
+type DependencyConflictResponse struct {
+	Error      string              `json:"error"`
+	Dependents []DependentResponse `json:"dependents"`
+}
@@ -0,0 +1,25 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type CreateExpenseRequest struct {
+	LandlordID  uuid.UUID  `json:"landlordId" binding:"required"`
+	AddressID   *uuid.UUID `json:"addressId"`
+	Amount      float64    `json:"amount" binding:"required,gt=0"`
+	PaidAt      time.Time  `json:"paidAt" binding:"required"`
+	Description string     `json:"description" binding:"required"`
+}
+
+type ExpenseResponse struct {
+	ID          uuid.UUID  `json:"id"`
+	LandlordID  uuid.UUID  `json:"landlordId"`
+	AddressID   *uuid.UUID `json:"addressId"`
+	Amount      float64    `json:"amount"`
+	PaidAt      string     `json:"paidAt"`
+	Description string     `json:"description"`
+	CreatedAt   string     `json:"createdAt"`
+}
@@ -0,0 +1,18 @@
+package dto
+
+import (
+	"github.com/google/uuid"
+)
+
+type UpdateOrganizationRequest struct {
+	Name                 string   `json:"name" binding:"required"`
+	ManagementCommission *float64 `json:"managementCommission" binding:"omitempty,min=0,max=100"`
+}
+
+type OrganizationResponse struct {
+	ID                   uuid.UUID `json:"id"`
+	Name                 string    `json:"name"`
+	ManagementCommission float64   `json:"managementCommission"`
+	CreatedAt            string    `json:"createdAt"`
+	UpdatedAt            *string   `json:"updatedAt"`
+}
@@ -0,0 +1,31 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type CreatePaymentRequest struct {
+	Type      string    `json:"type" binding:"omitempty,oneof=rent lateFee"`
+	Amount    float64   `json:"amount" binding:"required,gt=0"`
+	PaidAt    time.Time `json:"paidAt" binding:"required"`
+	Reference *string   `json:"reference"`
+	// Tax figures of the invoice issued for the payment
+	InvoiceNumber     *string `json:"invoiceNumber"`
+	VAT               float64 `json:"vat" binding:"omitempty,gte=0"`
+	IncomeTaxWithheld float64 `json:"incomeTaxWithheld" binding:"omitempty,gte=0"`
+}
+
+type PaymentResponse struct {
+	ID                uuid.UUID `json:"id"`
+	ContractID        uuid.UUID `json:"contractId"`
+	Type              string    `json:"type"`
+	Amount            float64   `json:"amount"`
+	PaidAt            string    `json:"paidAt"`
+	Reference         *string   `json:"reference"`
+	InvoiceNumber     *string   `json:"invoiceNumber"`
+	VAT               float64   `json:"vat"`
+	IncomeTaxWithheld float64   `json:"incomeTaxWithheld"`
+	CreatedAt         string    `json:"createdAt"`
+}
@@ -0,0 +1,29 @@
+package dto
+
+import (
+	"github.com/google/uuid"
+)
+
+type CreateDataSubjectRequest struct {
+	UserID  uuid.UUID `json:"userId" binding:"required"`
+	Type    string    `json:"type" binding:"required,oneof=access rectification cancellation opposition"`
+	Details *string   `json:"details"`
+}
+
+type ResolveDataSubjectRequest struct {
+	Status     string  `json:"status" binding:"required,oneof=completed rejected"`
+	Resolution *string `json:"resolution"`
+}
+
+type DataSubjectRequestResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"userId"`
+	Type       string     `json:"type"`
+	Status     string     `json:"status"`
+	Details    *string    `json:"details"`
+	Resolution *string    `json:"resolution"`
+	DueAt      string     `json:"dueAt"`
+	ResolvedAt *string    `json:"resolvedAt"`
+	ResolvedBy *uuid.UUID `json:"resolvedBy"`
+	CreatedAt  string     `json:"createdAt"`
+}
@@ -0,0 +1,22 @@
+package dto
+
+import (
+	"github.com/google/uuid"
+)
+
+type CreateOwnerStatementRequest struct {
+	LandlordID uuid.UUID `json:"landlordId" binding:"required"`
+	// Period is the month of the statement, as YYYY-MM
+	Period string `json:"period" binding:"required"`
+}
+
+type DocumentResponse struct {
+	ID          uuid.UUID `json:"id"`
+	Kind        string    `json:"kind"`
+	SubjectID   uuid.UUID `json:"subjectId"`
+	PeriodStart string    `json:"periodStart"`
+	PeriodEnd   string    `json:"periodEnd"`
+	FileName    string    `json:"fileName"`
+	ContentType string    `json:"contentType"`
+	CreatedAt   string    `json:"createdAt"`
+}
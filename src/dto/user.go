@@ -24,6 +24,17 @@ type UpdateUserRequest struct {
 	Phone      *string    `json:"phone,omitempty"`
 }
 
+// UserPatch is the document JSON merge patches for a user are applied to
+type UserPatch struct {
+	Type       string    `json:"type"`
+	AddressID  uuid.UUID `json:"addressId"`
+	FirstName  string    `json:"firstName"`
+	MiddleName *string   `json:"middleName"`
+	LastName   string    `json:"lastName"`
+	Email      string    `json:"email"`
+	Phone      string    `json:"phone"`
+}
+
 type UserResponse struct {
 	ID         uuid.UUID        `json:"id"`
 	Type       string           `json:"type"`
@@ -33,7 +44,9 @@ type UserResponse struct {
 	LastName   string           `json:"lastName"`
 	Email      string           `json:"email"`
 	Phone      string           `json:"phone"`
+	Version    int              `json:"version"`
 	CreatedAt  string           `json:"createdAt"`
 	UpdatedAt  *string          `json:"updatedAt"`
+	DeletedAt  *string          `json:"deletedAt,omitempty"`
 	Address    *AddressResponse `json:"address,omitempty"`
 }
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -31,7 +32,7 @@ func (h *AddressHandler) CreateAddress(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	address, err := h.addressService.CreateAddress(&req)
+	address, err := h.addressService.CreateAddress(r.Context(), &req)
 	if err != nil {
 		writeJSONError(w, http.StatusBadRequest, err.Error())
 		return
@@ -47,6 +48,7 @@ func (h *AddressHandler) CreateAddress(w http.ResponseWriter, r *http.Request) {
 		State:        address.State,
 		ZipCode:      address.ZipCode,
 		Country:      address.Country,
+		Version:      address.Version,
 		CreatedAt:    address.CreatedAt.Format(time.RFC3339),
 	}
 
@@ -55,6 +57,7 @@ func (h *AddressHandler) CreateAddress(w http.ResponseWriter, r *http.Request) {
 		response.UpdatedAt = &updatedAt
 	}
 
+	w.Header().Set("ETag", etag(address.Version))
 	writeJSON(w, http.StatusOK, response)
 }
 
@@ -66,12 +69,30 @@ func (h *AddressHandler) GetAddress(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	address, err := h.addressService.GetAddressByID(id)
+	asOf, err := asOfParam(r)
 	if err != nil {
 		writeJSONError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
+	var address *models.Address
+	if asOf != nil {
+		address, err = h.addressService.GetAddressAsOf(r.Context(), id, *asOf)
+	} else {
+		address, err = h.addressService.GetAddressByID(r.Context(), id)
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tag := etag(address.Version)
+	if asOf == nil && notModified(r, tag) {
+		w.Header().Set("ETag", tag)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
 	response := &dto.AddressResponse{
 		ID:           address.ID,
 		Type:         string(address.Type),
@@ -82,6 +103,7 @@ func (h *AddressHandler) GetAddress(w http.ResponseWriter, r *http.Request) {
 		State:        address.State,
 		ZipCode:      address.ZipCode,
 		Country:      address.Country,
+		Version:      address.Version,
 		CreatedAt:    address.CreatedAt.Format(time.RFC3339),
 	}
 
@@ -90,6 +112,7 @@ func (h *AddressHandler) GetAddress(w http.ResponseWriter, r *http.Request) {
 		response.UpdatedAt = &updatedAt
 	}
 
+	w.Header().Set("ETag", tag)
 	writeJSON(w, http.StatusOK, response)
 }
 
@@ -118,9 +141,9 @@ func (h *AddressHandler) GetAllAddresses(w http.ResponseWriter, r *http.Request)
 	}
 
 	if filter.Type != nil || filter.Available != nil || filter.Limit != nil {
-		addresses, err = h.addressService.GetAllAddresses(&filter)
+		addresses, err = h.addressService.GetAllAddresses(r.Context(), &filter)
 	} else {
-		addresses, err = h.addressService.GetAllAddresses(nil)
+		addresses, err = h.addressService.GetAllAddresses(r.Context(), nil)
 	}
 
 	if err != nil {
@@ -140,6 +163,7 @@ func (h *AddressHandler) GetAllAddresses(w http.ResponseWriter, r *http.Request)
 			State:        address.State,
 			ZipCode:      address.ZipCode,
 			Country:      address.Country,
+			Version:      address.Version,
 			CreatedAt:    address.CreatedAt.Format(time.RFC3339),
 		}
 
@@ -162,18 +186,87 @@ func (h *AddressHandler) UpdateAddress(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	expectedVersions, err := ifMatchVersions(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	var req dto.UpdateAddressRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeJSONError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	address, err := h.addressService.UpdateAddress(id, &req)
+	address, err := h.addressService.UpdateAddress(r.Context(), id, &req, expectedVersions)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrPreconditionFailed):
+			writeJSONError(w, http.StatusPreconditionFailed, err.Error())
+		case errors.Is(err, services.ErrVersionConflict):
+			writeJSONError(w, http.StatusConflict, err.Error())
+		default:
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	response := &dto.AddressResponse{
+		ID:           address.ID,
+		Type:         string(address.Type),
+		Street:       address.Street,
+		Number:       address.Number,
+		Neighborhood: address.Neighborhood,
+		City:         address.City,
+		State:        address.State,
+		ZipCode:      address.ZipCode,
+		Country:      address.Country,
+		Version:      address.Version,
+		CreatedAt:    address.CreatedAt.Format(time.RFC3339),
+	}
+
+	if address.UpdatedAt != nil {
+		updatedAt := address.UpdatedAt.Format(time.RFC3339)
+		response.UpdatedAt = &updatedAt
+	}
+
+	w.Header().Set("ETag", etag(address.Version))
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *AddressHandler) PatchAddress(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+
+	expectedVersions, err := ifMatchVersions(r)
 	if err != nil {
 		writeJSONError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
+	patch, err := readMergePatch(r)
+	if err != nil {
+		writeJSONError(w, http.StatusUnsupportedMediaType, err.Error())
+		return
+	}
+
+	address, changed, err := h.addressService.PatchAddress(r.Context(), id, patch, expectedVersions)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrPreconditionFailed):
+			writeJSONError(w, http.StatusPreconditionFailed, err.Error())
+		case errors.Is(err, services.ErrVersionConflict):
+			writeJSONError(w, http.StatusConflict, err.Error())
+		default:
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
 	response := &dto.AddressResponse{
 		ID:           address.ID,
 		Type:         string(address.Type),
@@ -184,6 +277,7 @@ func (h *AddressHandler) UpdateAddress(w http.ResponseWriter, r *http.Request) {
 		State:        address.State,
 		ZipCode:      address.ZipCode,
 		Country:      address.Country,
+		Version:      address.Version,
 		CreatedAt:    address.CreatedAt.Format(time.RFC3339),
 	}
 
@@ -192,6 +286,8 @@ func (h *AddressHandler) UpdateAddress(w http.ResponseWriter, r *http.Request) {
 		response.UpdatedAt = &updatedAt
 	}
 
+	setChangedFields(w, changed)
+	w.Header().Set("ETag", etag(address.Version))
 	writeJSON(w, http.StatusOK, response)
 }
 
@@ -203,9 +299,110 @@ func (h *AddressHandler) DeleteAddress(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.addressService.DeleteAddress(id)
+	err = h.addressService.DeleteAddress(r.Context(), id, cascadeParam(r))
 	if err != nil {
-		writeJSONError(w, http.StatusBadRequest, err.Error())
+		if writeDependencyError(w, err) {
+			return
+		}
+		writeJSONError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AddressHandler) GetDeletedAddresses(w http.ResponseWriter, r *http.Request) {
+	addresses, err := h.addressService.GetDeletedAddresses(r.Context())
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var responses []dto.AddressResponse
+	for _, address := range addresses {
+		response := dto.AddressResponse{
+			ID:           address.ID,
+			Type:         string(address.Type),
+			Street:       address.Street,
+			Number:       address.Number,
+			Neighborhood: address.Neighborhood,
+			City:         address.City,
+			State:        address.State,
+			ZipCode:      address.ZipCode,
+			Country:      address.Country,
+			Version:      address.Version,
+			CreatedAt:    address.CreatedAt.Format(time.RFC3339),
+		}
+
+		if address.UpdatedAt != nil {
+			updatedAt := address.UpdatedAt.Format(time.RFC3339)
+			response.UpdatedAt = &updatedAt
+		}
+
+		if address.DeletedAt.Valid {
+			deletedAt := address.DeletedAt.Time.Format(time.RFC3339)
+			response.DeletedAt = &deletedAt
+		}
+
+		responses = append(responses, response)
+	}
+
+	writeJSON(w, http.StatusOK, responses)
+}
+
+func (h *AddressHandler) RestoreAddress(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+
+	address, err := h.addressService.RestoreAddress(r.Context(), id)
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	response := &dto.AddressResponse{
+		ID:           address.ID,
+		Type:         string(address.Type),
+		Street:       address.Street,
+		Number:       address.Number,
+		Neighborhood: address.Neighborhood,
+		City:         address.City,
+		State:        address.State,
+		ZipCode:      address.ZipCode,
+		Country:      address.Country,
+		Version:      address.Version,
+		CreatedAt:    address.CreatedAt.Format(time.RFC3339),
+	}
+
+	if address.UpdatedAt != nil {
+		updatedAt := address.UpdatedAt.Format(time.RFC3339)
+		response.UpdatedAt = &updatedAt
+	}
+
+	w.Header().Set("ETag", etag(address.Version))
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *AddressHandler) PurgeAddress(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+
+	err = h.addressService.PurgeAddress(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrStillReferenced):
+			writeJSONError(w, http.StatusConflict, err.Error())
+		default:
+			writeJSONError(w, http.StatusNotFound, err.Error())
+		}
 		return
 	}
 
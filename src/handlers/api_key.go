@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/edfloreshz/rent-contracts/src/dto"
+	"github.com/edfloreshz/rent-contracts/src/models"
+	"github.com/edfloreshz/rent-contracts/src/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type APIKeyHandler struct {
+	apiKeyService *services.APIKeyService
+}
+
+func NewAPIKeyHandler(apiKeyService *services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+	}
+}
+
+func (h *APIKeyHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	apiKey, key, err := h.apiKeyService.CreateAPIKey(r.Context(), &req)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, &dto.CreateAPIKeyResponse{
+		APIKeyResponse: *apiKeyResponse(apiKey),
+		Key:            key,
+	})
+}
+
+func (h *APIKeyHandler) GetAllAPIKeys(w http.ResponseWriter, r *http.Request) {
+	apiKeys, err := h.apiKeyService.GetAllAPIKeys(r.Context())
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve API keys")
+		return
+	}
+
+	response := make([]*dto.APIKeyResponse, len(apiKeys))
+	for i := range apiKeys {
+		response[i] = apiKeyResponse(&apiKeys[i])
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *APIKeyHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+
+	if err := h.apiKeyService.RevokeAPIKey(r.Context(), id); err != nil {
+		writeJSONError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func apiKeyResponse(apiKey *models.APIKey) *dto.APIKeyResponse {
+	response := &dto.APIKeyResponse{
+		ID:         apiKey.ID,
+		Name:       apiKey.Name,
+		Prefix:     apiKey.Prefix,
+		Scopes:     apiKey.Scopes,
+		AllowedIPs: apiKey.AllowedIPs,
+		CreatedAt:  apiKey.CreatedAt.Format(time.RFC3339),
+	}
+
+	if apiKey.ExpiresAt != nil {
+		expiresAt := apiKey.ExpiresAt.Format(time.RFC3339)
+		response.ExpiresAt = &expiresAt
+	}
+	if apiKey.LastUsedAt != nil {
+		lastUsedAt := apiKey.LastUsedAt.Format(time.RFC3339)
+		response.LastUsedAt = &lastUsedAt
+	}
+	if apiKey.RevokedAt != nil {
+		revokedAt := apiKey.RevokedAt.Format(time.RFC3339)
+		response.RevokedAt = &revokedAt
+	}
+
+	return response
+}
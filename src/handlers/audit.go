@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/edfloreshz/rent-contracts/src/dto"
+	"github.com/edfloreshz/rent-contracts/src/models"
+	"github.com/edfloreshz/rent-contracts/src/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type AuditHandler struct {
+	auditService *services.AuditService
+}
+
+func NewAuditHandler(auditService *services.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+// GetAuditLogs supports ?entityType=, ?entityId=, ?actorId=, ?action=, ?from=, ?to=, ?limit= and ?offset=
+func (h *AuditHandler) GetAuditLogs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := services.AuditFilter{}
+
+	if entityType := query.Get("entityType"); entityType != "" {
+		if !services.ValidAuditEntity(entityType) {
+			writeJSONError(w, http.StatusBadRequest, "Invalid entityType")
+			return
+		}
+		filter.EntityType = &entityType
+	}
+
+	for name, target := range map[string]**uuid.UUID{"entityId": &filter.EntityID, "actorId": &filter.ActorID} {
+		if value := query.Get(name); value != "" {
+			id, err := uuid.Parse(value)
+			if err != nil {
+				writeJSONError(w, http.StatusBadRequest, "Invalid "+name)
+				return
+			}
+			*target = &id
+		}
+	}
+
+	if action := query.Get("action"); action != "" {
+		auditAction := models.AuditAction(action)
+		if !services.ValidAuditAction(auditAction) {
+			writeJSONError(w, http.StatusBadRequest, "Invalid action")
+			return
+		}
+		filter.Action = &auditAction
+	}
+
+	for name, target := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := query.Get(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				writeJSONError(w, http.StatusBadRequest, "Invalid "+name+", expected an RFC 3339 timestamp")
+				return
+			}
+			*target = &t
+		}
+	}
+
+	if limit, err := strconv.Atoi(query.Get("limit")); err == nil && limit > 0 {
+		filter.Limit = &limit
+	}
+	if offset, err := strconv.Atoi(query.Get("offset")); err == nil && offset > 0 {
+		filter.Offset = &offset
+	}
+
+	logs, err := h.auditService.GetAuditLogs(r.Context(), &filter)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve audit log")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, auditLogResponses(logs))
+}
+
+func (h *AuditHandler) GetEntityHistory(w http.ResponseWriter, r *http.Request) {
+	entityType := chi.URLParam(r, "entityType")
+	if !services.ValidAuditEntity(entityType) {
+		writeJSONError(w, http.StatusBadRequest, "Invalid entityType")
+		return
+	}
+
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+
+	logs, err := h.auditService.GetEntityHistory(r.Context(), entityType, id)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve history")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, auditLogResponses(logs))
+}
+
+func auditLogResponses(logs []models.AuditLog) []dto.AuditLogResponse {
+	responses := make([]dto.AuditLogResponse, 0, len(logs))
+	for _, log := range logs {
+		response := dto.AuditLogResponse{
+			ID:         log.ID,
+			ActorID:    log.ActorID,
+			APIKeyID:   log.APIKeyID,
+			Action:     string(log.Action),
+			EntityType: log.EntityType,
+			EntityID:   log.EntityID,
+			Changes:    make(map[string]dto.AuditChange, len(log.Changes)),
+			RequestID:  log.RequestID,
+			CreatedAt:  log.CreatedAt.Format(time.RFC3339),
+		}
+
+		for field, change := range log.Changes {
+			response.Changes[field] = dto.AuditChange{Before: change.Before, After: change.After}
+		}
+
+		responses = append(responses, response)
+	}
+	return responses
+}
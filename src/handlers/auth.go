@@ -0,0 +1,306 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/edfloreshz/rent-contracts/src/auth"
+	"github.com/edfloreshz/rent-contracts/src/dto"
+	"github.com/edfloreshz/rent-contracts/src/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type AuthHandler struct {
+	authService *services.AuthService
+}
+
+func NewAuthHandler(authService *services.AuthService) *AuthHandler {
+	return &AuthHandler{
+		authService: authService,
+	}
+}
+
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var req dto.LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tokens, challenge, err := h.authService.Login(&req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCredentials) {
+			writeJSONError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, "Failed to log in")
+		return
+	}
+
+	if challenge != nil {
+		writeJSON(w, http.StatusOK, challenge)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tokens)
+}
+
+func (h *AuthHandler) VerifyLogin(w http.ResponseWriter, r *http.Request) {
+	var req dto.VerifyLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tokens, err := h.authService.VerifyLogin(&req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidToken) || errors.Is(err, services.ErrInvalidMFACode) {
+			writeJSONError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrMFALocked) {
+			writeJSONError(w, http.StatusTooManyRequests, err.Error())
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, "Failed to log in")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tokens)
+}
+
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req dto.RefreshTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	tokens, err := h.authService.Refresh(req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidToken) {
+			writeJSONError(w, http.StatusUnauthorized, err.Error())
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, "Failed to refresh token")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tokens)
+}
+
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	principal, _ := auth.PrincipalFromContext(r.Context())
+
+	if err := h.authService.Logout(principal.SessionID); err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to log out")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AuthHandler) SetPassword(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+
+	// Users may change their own password, anyone else's needs permission to manage users.
+	// API keys act for their creator but never count as the user themselves.
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	self := principal != nil && principal.APIKeyID == nil && principal.UserID == id
+	if !ok || (!self && !principal.Can(auth.WriteUsers)) {
+		writeJSONError(w, http.StatusForbidden, "Not allowed to change the password of this user")
+		return
+	}
+
+	var req dto.SetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.authService.SetPassword(r.Context(), id, req.Password); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AuthHandler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	enrolment, err := h.authService.EnrollTOTP(r.Context())
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, enrolment)
+}
+
+func (h *AuthHandler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	var req dto.VerifyCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	codes, err := h.authService.ConfirmTOTP(r.Context(), req.Code)
+	if err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, codes)
+}
+
+func (h *AuthHandler) VerifyMFA(w http.ResponseWriter, r *http.Request) {
+	var req dto.VerifyCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.authService.VerifyMFA(r.Context(), req.Code); err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AuthHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	codes, err := h.authService.RegenerateRecoveryCodes(r.Context())
+	if err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, codes)
+}
+
+func (h *AuthHandler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	if err := h.authService.DisableTOTP(r.Context()); err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeMFAError(w http.ResponseWriter, err error) {
+	if errors.Is(err, services.ErrInvalidMFACode) {
+		writeJSONError(w, http.StatusUnauthorized, err.Error())
+		return
+	}
+	if errors.Is(err, services.ErrMFALocked) {
+		writeJSONError(w, http.StatusTooManyRequests, err.Error())
+		return
+	}
+	writeJSONError(w, http.StatusBadRequest, err.Error())
+}
+
+// Authenticate rejects requests without a valid bearer access token or API key and
+// stores the authenticated principal in the request context. API keys are accepted
+// in the X-API-Key header or as a bearer token.
+func Authenticate(authService *services.AuthService, apiKeyService *services.APIKeyService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := r.Header.Get("X-API-Key")
+			if token == "" {
+				scheme, bearer, found := strings.Cut(r.Header.Get("Authorization"), " ")
+				if !found || !strings.EqualFold(scheme, "Bearer") || bearer == "" {
+					w.Header().Set("WWW-Authenticate", `Bearer realm="api"`)
+					writeJSONError(w, http.StatusUnauthorized, "Missing bearer token")
+					return
+				}
+				token = bearer
+			}
+
+			var principal *auth.Principal
+			var err error
+			if strings.HasPrefix(token, services.APIKeyPrefix) {
+				principal, err = apiKeyService.Authenticate(token, remoteIP(r))
+			} else {
+				principal, err = authService.Authenticate(token)
+			}
+			if err != nil {
+				switch {
+				case errors.Is(err, services.ErrInvalidToken):
+					w.Header().Set("WWW-Authenticate", `Bearer realm="api", error="invalid_token"`)
+					writeJSONError(w, http.StatusUnauthorized, err.Error())
+				case errors.Is(err, services.ErrAPIKeyIPNotAllowed):
+					writeJSONError(w, http.StatusForbidden, err.Error())
+				default:
+					writeJSONError(w, http.StatusInternalServerError, "Failed to authenticate request")
+				}
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+		})
+	}
+}
+
+// RequirePermission rejects requests whose principal does not hold the permission
+func RequirePermission(permission auth.Permission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := auth.PrincipalFromContext(r.Context())
+			if !ok {
+				writeJSONError(w, http.StatusUnauthorized, "Missing bearer token")
+				return
+			}
+
+			if !principal.Can(permission) {
+				writeJSONError(w, http.StatusForbidden, "Missing permission "+string(permission))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireRecentMFA rejects requests whose session has not passed two-factor
+// verification within the given window
+func RequireRecentMFA(window time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !checkRecentMFA(w, r, window) {
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// checkRecentMFA writes a step-up error (RFC 9470) and returns false when the
+// caller has not passed two-factor verification within the window. Callers that
+// cannot pass it at all, API keys and users without two-factor authentication, are
+// told so instead.
+func checkRecentMFA(w http.ResponseWriter, r *http.Request, window time.Duration) bool {
+	principal, ok := auth.PrincipalFromContext(r.Context())
+	if ok && principal.VerifiedMFASince(time.Now().Add(-window)) {
+		return true
+	}
+
+	switch {
+	case ok && principal.APIKeyID != nil:
+		writeJSONError(w, http.StatusForbidden, "API keys cannot perform this action, it requires a user session with recent two-factor verification")
+	case ok && !principal.MFAEnrolled:
+		w.Header().Set("WWW-Authenticate", `Bearer realm="api", error="insufficient_user_authentication", error_description="Two-factor authentication must be enabled"`)
+		writeJSONError(w, http.StatusForbidden, "Two-factor authentication must be enabled to perform this action, enroll with POST /api/v1/auth/mfa/totp")
+	default:
+		w.Header().Set("WWW-Authenticate", `Bearer realm="api", error="insufficient_user_authentication", error_description="Recent two-factor verification required"`)
+		writeJSONError(w, http.StatusForbidden, "Recent two-factor verification required")
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/edfloreshz/rent-contracts/src/dto"
+	"github.com/edfloreshz/rent-contracts/src/services"
+)
+
+type BulkHandler struct {
+	bulkService     *services.BulkService
+	recentMFAWindow time.Duration
+}
+
+func NewBulkHandler(bulkService *services.BulkService, recentMFAWindow time.Duration) *BulkHandler {
+	return &BulkHandler{
+		bulkService:     bulkService,
+		recentMFAWindow: recentMFAWindow,
+	}
+}
+
+func (h *BulkHandler) BulkAddresses(w http.ResponseWriter, r *http.Request) {
+	var req dto.BulkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Deleting in bulk is as sensitive as deleting a single record
+	if hasDeleteOperation(&req) && !checkRecentMFA(w, r, h.recentMFAWindow) {
+		return
+	}
+
+	response, err := h.bulkService.Addresses(r.Context(), &req)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, bulkStatus(response), response)
+}
+
+func (h *BulkHandler) BulkUsers(w http.ResponseWriter, r *http.Request) {
+	var req dto.BulkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Deleting in bulk is as sensitive as deleting a single record
+	if hasDeleteOperation(&req) && !checkRecentMFA(w, r, h.recentMFAWindow) {
+		return
+	}
+
+	response, err := h.bulkService.Users(r.Context(), &req)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, bulkStatus(response), response)
+}
+
+func (h *BulkHandler) BulkContracts(w http.ResponseWriter, r *http.Request) {
+	var req dto.BulkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Deleting in bulk is as sensitive as deleting a single record
+	if hasDeleteOperation(&req) && !checkRecentMFA(w, r, h.recentMFAWindow) {
+		return
+	}
+
+	response, err := h.bulkService.Contracts(r.Context(), &req)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, bulkStatus(response), response)
+}
+
+// bulkStatus returns 200 when every operation succeeded, 207 when a best-effort
+// request partially succeeded and 422 when nothing was committed
+func bulkStatus(response *dto.BulkResponse) int {
+	switch {
+	case !response.Committed:
+		return http.StatusUnprocessableEntity
+	case response.Failed > 0:
+		return http.StatusMultiStatus
+	default:
+		return http.StatusOK
+	}
+}
+
+func hasDeleteOperation(req *dto.BulkRequest) bool {
+	for _, op := range req.Operations {
+		if op.Op == "delete" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/edfloreshz/rent-contracts/src/dto"
+	"github.com/edfloreshz/rent-contracts/src/models"
+	"github.com/edfloreshz/rent-contracts/src/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type ConfirmationHandler struct {
+	confirmationService *services.ConfirmationService
+}
+
+func NewConfirmationHandler(confirmationService *services.ConfirmationService) *ConfirmationHandler {
+	return &ConfirmationHandler{
+		confirmationService: confirmationService,
+	}
+}
+
+// GetConfirmationRequests lists the requests addressed to the calling reference,
+// or every request of the organization for admins
+func (h *ConfirmationHandler) GetConfirmationRequests(w http.ResponseWriter, r *http.Request) {
+	requests, err := h.confirmationService.GetConfirmationRequests(r.Context())
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve confirmation requests")
+		return
+	}
+
+	responses := make([]*dto.ConfirmationResponse, len(requests))
+	for i := range requests {
+		responses[i] = confirmationResponse(&requests[i])
+	}
+
+	writeJSON(w, http.StatusOK, responses)
+}
+
+// RespondToConfirmation confirms or declines the request of a contract addressed
+// to the calling reference
+func (h *ConfirmationHandler) RespondToConfirmation(w http.ResponseWriter, r *http.Request) {
+	contractID, err := uuid.Parse(chi.URLParam(r, "contractId"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+
+	var req dto.RespondConfirmationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Status != models.ConfirmedConfirmation && req.Status != models.DeclinedConfirmation {
+		writeJSONError(w, http.StatusBadRequest, "status must be confirmed or declined")
+		return
+	}
+
+	request, err := h.confirmationService.RespondToConfirmation(r.Context(), contractID, req.Status)
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, confirmationResponse(request))
+}
+
+func confirmationResponse(request *models.ContractReference) *dto.ConfirmationResponse {
+	response := &dto.ConfirmationResponse{
+		ContractID:  request.ContractID,
+		ReferenceID: request.ReferenceID,
+		Status:      request.Status,
+		Tenant:      request.Contract.Tenant.FullName(),
+		Landlord:    request.Contract.Landlord.FullName(),
+		Property:    request.Contract.Address.FullAddress(),
+	}
+	if request.RespondedAt != nil {
+		respondedAt := request.RespondedAt.Format(time.RFC3339)
+		response.RespondedAt = &respondedAt
+	}
+	if version := request.Contract.CurrentVersion; version != nil {
+		response.StartDate = version.StartDate.Format("2006-01-02")
+		response.EndDate = version.EndDate.Format("2006-01-02")
+	}
+	return response
+}
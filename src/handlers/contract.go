@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
@@ -30,7 +31,7 @@ func (h *ContractHandler) CreateContract(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	contract, err := h.contractService.CreateContract(&req)
+	contract, err := h.contractService.CreateContract(r.Context(), &req)
 	if err != nil {
 		writeJSONError(w, http.StatusBadRequest, err.Error())
 		return
@@ -43,6 +44,7 @@ func (h *ContractHandler) CreateContract(w http.ResponseWriter, r *http.Request)
 		TenantID:         contract.TenantID,
 		AddressID:        contract.AddressID,
 		Deposit:          contract.Deposit,
+		Version:          contract.Version,
 		CreatedAt:        contract.CreatedAt.Format(time.RFC3339),
 	}
 
@@ -51,6 +53,12 @@ func (h *ContractHandler) CreateContract(w http.ResponseWriter, r *http.Request)
 		response.UpdatedAt = &updatedAt
 	}
 
+	if contract.DeletedAt.Valid {
+		deletedAt := contract.DeletedAt.Time.Format(time.RFC3339)
+		response.DeletedAt = &deletedAt
+	}
+
+	w.Header().Set("ETag", etag(contract.Version))
 	writeJSON(w, http.StatusCreated, response)
 }
 
@@ -62,13 +70,32 @@ func (h *ContractHandler) GetContract(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	contract, err := h.contractService.GetContractByID(id)
+	asOf, err := asOfParam(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var contract *models.Contract
+	if asOf != nil {
+		contract, err = h.contractService.GetContractAsOf(r.Context(), id, *asOf)
+	} else {
+		contract, err = h.contractService.GetContractByID(r.Context(), id)
+	}
 	if err != nil {
 		writeJSONError(w, http.StatusNotFound, err.Error())
 		return
 	}
 
+	tag := etag(contract.Version)
+	if asOf == nil && notModified(r, tag) {
+		w.Header().Set("ETag", tag)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
 	response := h.buildContractResponse(contract)
+	w.Header().Set("ETag", tag)
 	writeJSON(w, http.StatusCreated, response)
 }
 
@@ -83,9 +110,9 @@ func (h *ContractHandler) GetAllContracts(w http.ResponseWriter, r *http.Request
 			writeJSONError(w, http.StatusBadRequest, "Invalid UUID")
 			return
 		}
-		contracts, err = h.contractService.GetContractsByTenant(tenantID)
+		contracts, err = h.contractService.GetContractsByTenant(r.Context(), tenantID)
 	} else {
-		contracts, err = h.contractService.GetAllContracts()
+		contracts, err = h.contractService.GetAllContracts(r.Context())
 	}
 
 	if err != nil {
@@ -110,22 +137,75 @@ func (h *ContractHandler) UpdateContract(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	expectedVersions, err := ifMatchVersions(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	var req dto.UpdateContractRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeJSONError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	contract, err := h.contractService.UpdateContract(id, &req)
+	contract, err := h.contractService.UpdateContract(r.Context(), id, &req, expectedVersions)
 	if err != nil {
-		writeJSONError(w, http.StatusNotFound, err.Error())
+		switch {
+		case errors.Is(err, services.ErrPreconditionFailed):
+			writeJSONError(w, http.StatusPreconditionFailed, err.Error())
+		case errors.Is(err, services.ErrVersionConflict):
+			writeJSONError(w, http.StatusConflict, err.Error())
+		default:
+			writeJSONError(w, http.StatusNotFound, err.Error())
+		}
 		return
 	}
 
 	response := h.buildContractResponse(contract)
+	w.Header().Set("ETag", etag(contract.Version))
 	writeJSON(w, http.StatusCreated, response)
 }
 
+func (h *ContractHandler) PatchContract(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+
+	expectedVersions, err := ifMatchVersions(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	patch, err := readMergePatch(r)
+	if err != nil {
+		writeJSONError(w, http.StatusUnsupportedMediaType, err.Error())
+		return
+	}
+
+	contract, changed, err := h.contractService.PatchContract(r.Context(), id, patch, expectedVersions)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrPreconditionFailed):
+			writeJSONError(w, http.StatusPreconditionFailed, err.Error())
+		case errors.Is(err, services.ErrVersionConflict):
+			writeJSONError(w, http.StatusConflict, err.Error())
+		default:
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	response := h.buildContractResponse(contract)
+	setChangedFields(w, changed)
+	w.Header().Set("ETag", etag(contract.Version))
+	writeJSON(w, http.StatusOK, response)
+}
+
 func (h *ContractHandler) DeleteContract(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	id, err := uuid.Parse(idStr)
@@ -134,7 +214,7 @@ func (h *ContractHandler) DeleteContract(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = h.contractService.DeleteContract(id)
+	err = h.contractService.DeleteContract(r.Context(), id)
 	if err != nil {
 		writeJSONError(w, http.StatusNotFound, err.Error())
 		return
@@ -143,6 +223,68 @@ func (h *ContractHandler) DeleteContract(w http.ResponseWriter, r *http.Request)
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (h *ContractHandler) GetDeletedContracts(w http.ResponseWriter, r *http.Request) {
+	contracts, err := h.contractService.GetDeletedContracts(r.Context())
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	var responses []dto.ContractResponse
+	for _, contract := range contracts {
+		response := h.buildContractResponse(&contract)
+		responses = append(responses, *response)
+	}
+
+	writeJSON(w, http.StatusOK, responses)
+}
+
+func (h *ContractHandler) RestoreContract(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+
+	contract, err := h.contractService.RestoreContract(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrRestoreConflict):
+			writeJSONError(w, http.StatusConflict, err.Error())
+		default:
+			writeJSONError(w, http.StatusNotFound, err.Error())
+		}
+		return
+	}
+
+	response := h.buildContractResponse(contract)
+	w.Header().Set("ETag", etag(contract.Version))
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *ContractHandler) PurgeContract(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+
+	err = h.contractService.PurgeContract(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrStillReferenced):
+			writeJSONError(w, http.StatusConflict, err.Error())
+		default:
+			writeJSONError(w, http.StatusNotFound, err.Error())
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (h *ContractHandler) CreateContractVersion(w http.ResponseWriter, r *http.Request) {
 	var req dto.CreateContractVersionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -150,7 +292,7 @@ func (h *ContractHandler) CreateContractVersion(w http.ResponseWriter, r *http.R
 		return
 	}
 
-	version, err := h.contractService.CreateContractVersion(&req)
+	version, err := h.contractService.CreateContractVersion(r.Context(), &req)
 	if err != nil {
 		writeJSONError(w, http.StatusNotFound, err.Error())
 		return
@@ -168,7 +310,18 @@ func (h *ContractHandler) GetContractVersions(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	versions, err := h.contractService.GetContractVersionsByContractID(contractID)
+	asOf, err := asOfParam(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var versions []models.ContractVersion
+	if asOf != nil {
+		versions, err = h.contractService.GetContractVersionsAsOf(r.Context(), contractID, *asOf)
+	} else {
+		versions, err = h.contractService.GetContractVersionsByContractID(r.Context(), contractID)
+	}
 	if err != nil {
 		writeJSONError(w, http.StatusNotFound, err.Error())
 		return
@@ -183,6 +336,38 @@ func (h *ContractHandler) GetContractVersions(w http.ResponseWriter, r *http.Req
 	writeJSON(w, http.StatusOK, responses)
 }
 
+func (h *ContractHandler) PatchContractVersion(w http.ResponseWriter, r *http.Request) {
+	contractIDStr := chi.URLParam(r, "id")
+	contractID, err := uuid.Parse(contractIDStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+
+	versionIDStr := chi.URLParam(r, "versionId")
+	versionID, err := uuid.Parse(versionIDStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+
+	patch, err := readMergePatch(r)
+	if err != nil {
+		writeJSONError(w, http.StatusUnsupportedMediaType, err.Error())
+		return
+	}
+
+	version, changed, err := h.contractService.PatchContractVersion(r.Context(), contractID, versionID, patch)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response := h.buildContractVersionResponse(version)
+	setChangedFields(w, changed)
+	writeJSON(w, http.StatusOK, response)
+}
+
 func (h *ContractHandler) GetContractDocument(w http.ResponseWriter, r *http.Request) {
 	contractIDStr := chi.URLParam(r, "id")
 	contractID, err := uuid.Parse(contractIDStr)
@@ -203,7 +388,13 @@ func (h *ContractHandler) GetContractDocument(w http.ResponseWriter, r *http.Req
 		versionID = &parsedVersionID
 	}
 
-	document, err := h.contractService.GetContractDocument(contractID, versionID)
+	asOf, err := asOfParam(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	document, err := h.contractService.GetContractDocument(r.Context(), contractID, versionID, asOf)
 	if err != nil {
 		writeJSONError(w, http.StatusNotFound, err.Error())
 		return
@@ -222,6 +413,7 @@ func (h *ContractHandler) buildContractResponse(contract *models.Contract) *dto.
 		TenantID:         contract.TenantID,
 		AddressID:        contract.AddressID,
 		Deposit:          contract.Deposit,
+		Version:          contract.Version,
 		CreatedAt:        contract.CreatedAt.Format(time.RFC3339),
 	}
 
@@ -246,6 +438,7 @@ func (h *ContractHandler) buildContractResponse(contract *models.Contract) *dto.
 			LastName:   contract.Landlord.LastName,
 			Email:      contract.Landlord.Email,
 			Phone:      contract.Landlord.Phone,
+			Version:    contract.Landlord.Version,
 			CreatedAt:  contract.Landlord.CreatedAt.Format(time.RFC3339),
 		}
 	}
@@ -261,6 +454,7 @@ func (h *ContractHandler) buildContractResponse(contract *models.Contract) *dto.
 			LastName:   contract.Tenant.LastName,
 			Email:      contract.Tenant.Email,
 			Phone:      contract.Tenant.Phone,
+			Version:    contract.Tenant.Version,
 			CreatedAt:  contract.Tenant.CreatedAt.Format(time.RFC3339),
 		}
 	}
@@ -277,6 +471,7 @@ func (h *ContractHandler) buildContractResponse(contract *models.Contract) *dto.
 			State:        contract.Tenant.Address.State,
 			ZipCode:      contract.Tenant.Address.ZipCode,
 			Country:      contract.Tenant.Address.Country,
+			Version:      contract.Tenant.Address.Version,
 			CreatedAt:    contract.Tenant.Address.CreatedAt.Format(time.RFC3339),
 		}
 	}
@@ -293,6 +488,7 @@ func (h *ContractHandler) buildContractResponse(contract *models.Contract) *dto.
 			State:        contract.Tenant.Address.State,
 			ZipCode:      contract.Tenant.Address.ZipCode,
 			Country:      contract.Tenant.Address.Country,
+			Version:      contract.Tenant.Address.Version,
 			CreatedAt:    contract.Tenant.Address.CreatedAt.Format(time.RFC3339),
 		}
 	}
@@ -317,6 +513,7 @@ func (h *ContractHandler) buildContractResponse(contract *models.Contract) *dto.
 				LastName:   reference.LastName,
 				Email:      reference.Email,
 				Phone:      reference.Phone,
+				Version:    reference.Version,
 				CreatedAt:  reference.CreatedAt.Format(time.RFC3339),
 			}
 			response.References = append(response.References, *referenceResponse)
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/edfloreshz/rent-contracts/src/dto"
+	"github.com/edfloreshz/rent-contracts/src/models"
+	"github.com/edfloreshz/rent-contracts/src/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type ExpenseHandler struct {
+	expenseService *services.ExpenseService
+}
+
+func NewExpenseHandler(expenseService *services.ExpenseService) *ExpenseHandler {
+	return &ExpenseHandler{
+		expenseService: expenseService,
+	}
+}
+
+func (h *ExpenseHandler) CreateExpense(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateExpenseRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.LandlordID == uuid.Nil || req.Amount <= 0 || req.PaidAt.IsZero() {
+		writeJSONError(w, http.StatusBadRequest, "landlordId, a positive amount and paidAt are required")
+		return
+	}
+
+	expense, err := h.expenseService.CreateExpense(r.Context(), &req)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, expenseResponse(expense))
+}
+
+// GetExpenses supports ?landlordId= to list the expenses of one landlord
+func (h *ExpenseHandler) GetExpenses(w http.ResponseWriter, r *http.Request) {
+	landlordID, ok := landlordIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	expenses, err := h.expenseService.GetExpenses(r.Context(), landlordID)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve expenses")
+		return
+	}
+
+	responses := make([]*dto.ExpenseResponse, len(expenses))
+	for i := range expenses {
+		responses[i] = expenseResponse(&expenses[i])
+	}
+
+	writeJSON(w, http.StatusOK, responses)
+}
+
+func (h *ExpenseHandler) DeleteExpense(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+
+	if err := h.expenseService.DeleteExpense(r.Context(), id); err != nil {
+		writeJSONError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// landlordIDParam reads the optional ?landlordId= filter, writing an error when it is invalid
+func landlordIDParam(w http.ResponseWriter, r *http.Request) (*uuid.UUID, bool) {
+	value := r.URL.Query().Get("landlordId")
+	if value == "" {
+		return nil, true
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid landlordId")
+		return nil, false
+	}
+	return &id, true
+}
+
+func expenseResponse(expense *models.Expense) *dto.ExpenseResponse {
+	return &dto.ExpenseResponse{
+		ID:          expense.ID,
+		LandlordID:  expense.LandlordID,
+		AddressID:   expense.AddressID,
+		Amount:      expense.Amount,
+		PaidAt:      expense.PaidAt.Format("2006-01-02"),
+		Description: expense.Description,
+		CreatedAt:   expense.CreatedAt.Format(time.RFC3339),
+	}
+}
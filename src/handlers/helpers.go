@@ -2,7 +2,20 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
 	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/edfloreshz/rent-contracts/src/dto"
+	"github.com/edfloreshz/rent-contracts/src/services"
+	"github.com/go-chi/chi/v5/middleware"
 )
 
 type JSONError struct {
@@ -20,3 +33,210 @@ func writeJSON(w http.ResponseWriter, status int, data interface{}) {
 func writeJSONError(w http.ResponseWriter, status int, message string) {
 	writeJSON(w, status, JSONError{Error: message})
 }
+
+// etag formats a record version as a strong entity tag
+func etag(version int) string {
+	return fmt.Sprintf("%q", strconv.Itoa(version))
+}
+
+// ifMatchVersions parses the If-Match header into the record versions expected by
+// the client. It returns nil when the header is absent or matches any version. If-Match
+// uses the strong comparison of RFC 7232, so weak tags and tags that are not versions
+// never match and leave the returned list without them.
+func ifMatchVersions(r *http.Request) ([]int, error) {
+	header := strings.TrimSpace(r.Header.Get("If-Match"))
+	if header == "" || header == "*" {
+		return nil, nil
+	}
+
+	tags, err := parseETags(header)
+	if err != nil {
+		return nil, errors.New("Invalid If-Match header")
+	}
+
+	versions := []int{}
+	for _, tag := range tags {
+		if strings.HasPrefix(tag, "W/") {
+			continue
+		}
+		if version, err := strconv.Atoi(strings.Trim(tag, `"`)); err == nil {
+			versions = append(versions, version)
+		}
+	}
+
+	return versions, nil
+}
+
+// notModified reports whether the If-None-Match header matches the given entity tag
+func notModified(r *http.Request, tag string) bool {
+	header := strings.TrimSpace(r.Header.Get("If-None-Match"))
+	if header == "" {
+		return false
+	}
+	if header == "*" {
+		return true
+	}
+
+	tags, err := parseETags(header)
+	if err != nil {
+		return false
+	}
+	for _, candidate := range tags {
+		if strings.TrimPrefix(candidate, "W/") == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parseETags splits a comma separated list of entity tags (RFC 7232, section 2.3),
+// keeping the W/ prefix of weak tags
+func parseETags(header string) ([]string, error) {
+	var tags []string
+	rest := header
+	for {
+		rest = strings.TrimLeft(rest, " \t")
+		if rest == "" {
+			break
+		}
+		if rest[0] == ',' {
+			rest = rest[1:]
+			continue
+		}
+
+		start := 0
+		if strings.HasPrefix(rest, "W/") {
+			start = 2
+		}
+		if len(rest) <= start || rest[start] != '"' {
+			return nil, fmt.Errorf("invalid entity tag %q", rest)
+		}
+		end := strings.IndexByte(rest[start+1:], '"')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated entity tag %q", rest)
+		}
+		end += start + 2
+		for _, c := range []byte(rest[start+1 : end-1]) {
+			if c < 0x21 || c == 0x7f {
+				return nil, fmt.Errorf("invalid entity tag %q", rest[:end])
+			}
+		}
+		tags = append(tags, rest[:end])
+
+		rest = strings.TrimLeft(rest[end:], " \t")
+		if rest != "" && rest[0] != ',' {
+			return nil, fmt.Errorf("expected a comma after entity tag %q", tags[len(tags)-1])
+		}
+	}
+
+	if len(tags) == 0 {
+		return nil, errors.New("no entity tags")
+	}
+	return tags, nil
+}
+
+// readMergePatch reads a JSON merge patch (RFC 7396) from the request body
+func readMergePatch(r *http.Request) ([]byte, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || (mediaType != "application/merge-patch+json" && mediaType != "application/json") {
+		return nil, errors.New("Content-Type must be application/merge-patch+json")
+	}
+
+	return io.ReadAll(r.Body)
+}
+
+// setChangedFields reports the fields modified by a patch in the X-Changed-Fields header
+func setChangedFields(w http.ResponseWriter, changed []string) {
+	w.Header().Set("X-Changed-Fields", strings.Join(changed, ","))
+}
+
+// cascadeParam reports whether the caller asked a delete to take the records that
+// depend on the deleted one along with it
+func cascadeParam(r *http.Request) bool {
+	cascade, _ := strconv.ParseBool(r.URL.Query().Get("cascade"))
+	return cascade
+}
+
+// writeDependencyError writes a 409 listing the records that stopped a delete.
+// It reports false when err is not a dependency error.
+func writeDependencyError(w http.ResponseWriter, err error) bool {
+	var dependencyErr *services.DependencyError
+	if !errors.As(err, &dependencyErr) {
+		return false
+	}
+
+	response := dto.DependencyConflictResponse{
+		Error:      services.ErrHasDependents.Error(),
+		Dependents: []dto.DependentResponse{},
+	}
+	for _, dependent := range dependencyErr.Dependents {
+		response.Dependents = append(response.Dependents, dto.DependentResponse{
+			Type:     dependent.Type,
+			ID:       dependent.ID,
+			Relation: dependent.Relation,
+		})
+	}
+
+	writeJSON(w, http.StatusConflict, response)
+	return true
+}
+
+// asOfParam parses the asOf query parameter, the moment a point-in-time read is made at.
+// A date without a time means the end of that day. It returns nil when the parameter is absent.
+func asOfParam(r *http.Request) (*time.Time, error) {
+	value := r.URL.Query().Get("asOf")
+	if value == "" {
+		return nil, nil
+	}
+
+	if asOf, err := time.Parse(time.RFC3339, value); err == nil {
+		return &asOf, nil
+	}
+
+	day, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, errors.New("Invalid asOf, expected an RFC 3339 timestamp or a date")
+	}
+
+	asOf := day.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	return &asOf, nil
+}
+
+// parseDateParam parses a query parameter holding an RFC 3339 timestamp or a date,
+// which means the start of that day in UTC
+func parseDateParam(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+// remoteIP returns the address of the client connected to the server
+func remoteIP(r *http.Request) netip.Addr {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	addr, _ := netip.ParseAddr(host)
+	return addr
+}
+
+// ExposeRequestID returns the ID assigned to the request in the X-Request-Id response header
+func ExposeRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requestID := middleware.GetReqID(r.Context()); requestID != "" {
+			w.Header().Set(middleware.RequestIDHeader, requestID)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeFile sends a file as an attachment with the given name
+func writeFile(w http.ResponseWriter, contentType, name string, file []byte) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+	w.WriteHeader(http.StatusOK)
+	w.Write(file)
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestIfMatchVersions(t *testing.T) {
+	tests := []struct {
+		header   string
+		versions []int
+		invalid  bool
+	}{
+		{header: "", versions: nil},
+		{header: "*", versions: nil},
+		{header: `"3"`, versions: []int{3}},
+		{header: `"1", "2"`, versions: []int{1, 2}},
+		{header: ` "1" ,, "2" `, versions: []int{1, 2}},
+		{header: `W/"3"`, versions: []int{}},
+		{header: `W/"3", "4"`, versions: []int{4}},
+		{header: `"abc"`, versions: []int{}},
+		{header: `"a,b", "5"`, versions: []int{5}},
+		{header: `3`, invalid: true},
+		{header: `"3`, invalid: true},
+		{header: `"3" "4"`, invalid: true},
+		{header: `W/3`, invalid: true},
+		{header: `"3", *`, invalid: true},
+		{header: `,`, invalid: true},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("PUT", "/", nil)
+		if tt.header != "" {
+			r.Header.Set("If-Match", tt.header)
+		}
+
+		versions, err := ifMatchVersions(r)
+		if tt.invalid {
+			if err == nil {
+				t.Errorf("If-Match %s: got versions %v, want an error", tt.header, versions)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("If-Match %s: %v", tt.header, err)
+			continue
+		}
+		if !reflect.DeepEqual(versions, tt.versions) {
+			t.Errorf("If-Match %s: got versions %#v, want %#v", tt.header, versions, tt.versions)
+		}
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	tests := []struct {
+		header string
+		match  bool
+	}{
+		{header: "", match: false},
+		{header: "*", match: true},
+		{header: `"3"`, match: true},
+		{header: `W/"3"`, match: true},
+		{header: `"1", W/"3"`, match: true},
+		{header: `"4"`, match: false},
+		{header: `3`, match: false},
+	}
+
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "/", nil)
+		if tt.header != "" {
+			r.Header.Set("If-None-Match", tt.header)
+		}
+
+		if match := notModified(r, etag(3)); match != tt.match {
+			t.Errorf("If-None-Match %s: got %v, want %v", tt.header, match, tt.match)
+		}
+	}
+}
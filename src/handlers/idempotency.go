@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/edfloreshz/rent-contracts/src/services"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// maxIdempotencyKeyLength bounds the size of client supplied keys
+const maxIdempotencyKeyLength = 255
+
+// Idempotency replays the stored response, with its Content-Type, ETag and Location
+// headers, when a request is retried with the same Idempotency-Key header, and rejects
+// reuse of a key with a different request.
+func Idempotency(idempotencyService *services.IdempotencyService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get("Idempotency-Key")
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if len(key) > maxIdempotencyKeyLength {
+				writeJSONError(w, http.StatusBadRequest, "Idempotency-Key is too long")
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				writeJSONError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			// Keys are scoped to the caller, so the fingerprint only has to tell
+			// their requests apart
+			hash := sha256.New()
+			hash.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+			hash.Write(body)
+			fingerprint := hex.EncodeToString(hash.Sum(nil))
+
+			stored, err := idempotencyService.Begin(r.Context(), key, fingerprint)
+			if err != nil {
+				switch {
+				case errors.Is(err, services.ErrIdempotencyKeyReused):
+					writeJSONError(w, http.StatusUnprocessableEntity, err.Error())
+				case errors.Is(err, services.ErrIdempotencyKeyInProgress):
+					writeJSONError(w, http.StatusConflict, err.Error())
+				default:
+					writeJSONError(w, http.StatusInternalServerError, err.Error())
+				}
+				return
+			}
+
+			// Replay the stored response
+			if stored != nil {
+				if stored.ContentType != "" {
+					w.Header().Set("Content-Type", stored.ContentType)
+				}
+				if stored.ETag != "" {
+					w.Header().Set("ETag", stored.ETag)
+				}
+				if stored.Location != "" {
+					w.Header().Set("Location", stored.Location)
+				}
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(stored.StatusCode)
+				w.Write(stored.ResponseBody)
+				return
+			}
+
+			// Release the key if the handler panics so the request can be retried
+			defer func() {
+				if rec := recover(); rec != nil {
+					idempotencyService.Release(r.Context(), key)
+					panic(rec)
+				}
+			}()
+
+			var response bytes.Buffer
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			ww.Tee(&response)
+
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+
+			// Server errors are not stored so the client can retry them
+			if status >= http.StatusInternalServerError {
+				if err := idempotencyService.Release(r.Context(), key); err != nil {
+					log.Println("Failed to release idempotency key:", err)
+				}
+				return
+			}
+
+			if err := idempotencyService.Complete(r.Context(), key, services.IdempotentResponse{
+				StatusCode:  status,
+				ContentType: ww.Header().Get("Content-Type"),
+				ETag:        ww.Header().Get("ETag"),
+				Location:    ww.Header().Get("Location"),
+				Body:        response.Bytes(),
+			}); err != nil {
+				log.Println("Failed to store idempotent response:", err)
+			}
+		})
+	}
+}
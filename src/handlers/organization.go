@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/edfloreshz/rent-contracts/src/dto"
+	"github.com/edfloreshz/rent-contracts/src/models"
+	"github.com/edfloreshz/rent-contracts/src/services"
+)
+
+type OrganizationHandler struct {
+	organizationService *services.OrganizationService
+}
+
+func NewOrganizationHandler(organizationService *services.OrganizationService) *OrganizationHandler {
+	return &OrganizationHandler{
+		organizationService: organizationService,
+	}
+}
+
+func (h *OrganizationHandler) GetOrganization(w http.ResponseWriter, r *http.Request) {
+	organization, err := h.organizationService.GetCurrentOrganization(r.Context())
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, organizationResponse(organization))
+}
+
+func (h *OrganizationHandler) UpdateOrganization(w http.ResponseWriter, r *http.Request) {
+	var req dto.UpdateOrganizationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	organization, err := h.organizationService.UpdateCurrentOrganization(r.Context(), &req)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, organizationResponse(organization))
+}
+
+func organizationResponse(organization *models.Organization) *dto.OrganizationResponse {
+	response := &dto.OrganizationResponse{
+		ID:                   organization.ID,
+		Name:                 organization.Name,
+		ManagementCommission: organization.ManagementCommission,
+		CreatedAt:            organization.CreatedAt.Format(time.RFC3339),
+	}
+
+	if organization.UpdatedAt != nil {
+		updatedAt := organization.UpdatedAt.Format(time.RFC3339)
+		response.UpdatedAt = &updatedAt
+	}
+
+	return response
+}
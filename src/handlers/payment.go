@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/edfloreshz/rent-contracts/src/dto"
+	"github.com/edfloreshz/rent-contracts/src/models"
+	"github.com/edfloreshz/rent-contracts/src/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type PaymentHandler struct {
+	paymentService *services.PaymentService
+}
+
+func NewPaymentHandler(paymentService *services.PaymentService) *PaymentHandler {
+	return &PaymentHandler{
+		paymentService: paymentService,
+	}
+}
+
+func (h *PaymentHandler) CreatePayment(w http.ResponseWriter, r *http.Request) {
+	contractID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+
+	var req dto.CreatePaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Amount <= 0 || req.PaidAt.IsZero() {
+		writeJSONError(w, http.StatusBadRequest, "A positive amount and paidAt are required")
+		return
+	}
+	if req.VAT < 0 || req.IncomeTaxWithheld < 0 || req.IncomeTaxWithheld > req.Amount {
+		writeJSONError(w, http.StatusBadRequest, "vat and incomeTaxWithheld cannot be negative, nor the withholding exceed the amount")
+		return
+	}
+	switch models.PaymentType(req.Type) {
+	case "", models.RentPayment, models.LateFeePayment:
+	default:
+		writeJSONError(w, http.StatusBadRequest, "Invalid type")
+		return
+	}
+
+	payment, err := h.paymentService.CreatePayment(r.Context(), contractID, &req)
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, paymentResponse(payment))
+}
+
+func (h *PaymentHandler) GetPayments(w http.ResponseWriter, r *http.Request) {
+	contractID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+
+	payments, err := h.paymentService.GetPayments(r.Context(), contractID)
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	responses := make([]*dto.PaymentResponse, len(payments))
+	for i := range payments {
+		responses[i] = paymentResponse(&payments[i])
+	}
+
+	writeJSON(w, http.StatusOK, responses)
+}
+
+func (h *PaymentHandler) DeletePayment(w http.ResponseWriter, r *http.Request) {
+	contractID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+	paymentID, err := uuid.Parse(chi.URLParam(r, "paymentId"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+
+	if err := h.paymentService.DeletePayment(r.Context(), contractID, paymentID); err != nil {
+		writeJSONError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetCharges returns the monthly rent charges of a contract with what is paid and owed
+func (h *PaymentHandler) GetCharges(w http.ResponseWriter, r *http.Request) {
+	contractID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+
+	charges, err := h.paymentService.GetCharges(r.Context(), contractID)
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, charges)
+}
+
+func paymentResponse(payment *models.Payment) *dto.PaymentResponse {
+	return &dto.PaymentResponse{
+		ID:                payment.ID,
+		ContractID:        payment.ContractID,
+		Type:              string(payment.Type),
+		Amount:            payment.Amount,
+		PaidAt:            payment.PaidAt.Format("2006-01-02"),
+		Reference:         payment.Reference,
+		InvoiceNumber:     payment.InvoiceNumber,
+		VAT:               payment.VAT,
+		IncomeTaxWithheld: payment.IncomeTaxWithheld,
+		CreatedAt:         payment.CreatedAt.Format(time.RFC3339),
+	}
+}
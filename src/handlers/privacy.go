@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/edfloreshz/rent-contracts/src/dto"
+	"github.com/edfloreshz/rent-contracts/src/models"
+	"github.com/edfloreshz/rent-contracts/src/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type PrivacyHandler struct {
+	privacyService *services.PrivacyService
+}
+
+func NewPrivacyHandler(privacyService *services.PrivacyService) *PrivacyHandler {
+	return &PrivacyHandler{
+		privacyService: privacyService,
+	}
+}
+
+func (h *PrivacyHandler) CreateRequest(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateDataSubjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	switch models.DataSubjectRequestType(req.Type) {
+	case models.AccessRequest, models.RectificationRequest, models.CancellationRequest, models.OppositionRequest:
+	default:
+		writeJSONError(w, http.StatusBadRequest, "Invalid type")
+		return
+	}
+
+	request, err := h.privacyService.CreateRequest(r.Context(), &req)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, dataSubjectRequestResponse(request))
+}
+
+// GetRequests supports ?status=
+func (h *PrivacyHandler) GetRequests(w http.ResponseWriter, r *http.Request) {
+	var status *models.DataSubjectRequestStatus
+	if value := r.URL.Query().Get("status"); value != "" {
+		requestStatus := models.DataSubjectRequestStatus(value)
+		if requestStatus != models.PendingRequest && requestStatus != models.CompletedRequest && requestStatus != models.RejectedRequest {
+			writeJSONError(w, http.StatusBadRequest, "Invalid status")
+			return
+		}
+		status = &requestStatus
+	}
+
+	requests, err := h.privacyService.GetRequests(r.Context(), status)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	responses := make([]dto.DataSubjectRequestResponse, 0, len(requests))
+	for _, request := range requests {
+		responses = append(responses, *dataSubjectRequestResponse(&request))
+	}
+
+	writeJSON(w, http.StatusOK, responses)
+}
+
+func (h *PrivacyHandler) ResolveRequest(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+
+	var req dto.ResolveDataSubjectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if status := models.DataSubjectRequestStatus(req.Status); status != models.CompletedRequest && status != models.RejectedRequest {
+		writeJSONError(w, http.StatusBadRequest, "Invalid status")
+		return
+	}
+
+	request, err := h.privacyService.ResolveRequest(r.Context(), id, &req)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, dataSubjectRequestResponse(request))
+}
+
+// ExportUserData returns everything held about a user as a ZIP archive with their
+// contract documents, or as JSON with ?format=json
+func (h *PrivacyHandler) ExportUserData(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+
+	if r.URL.Query().Get("format") == "json" {
+		export, err := h.privacyService.ExportUserData(r.Context(), id)
+		if err != nil {
+			writeJSONError(w, http.StatusNotFound, err.Error())
+			return
+		}
+
+		writeJSON(w, http.StatusOK, export)
+		return
+	}
+
+	archive, err := h.privacyService.ExportUserDataArchive(r.Context(), id)
+	if err != nil {
+		writeJSONError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%s.zip"`, id))
+	w.WriteHeader(http.StatusOK)
+	w.Write(archive)
+}
+
+func (h *PrivacyHandler) AnonymizeUser(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+
+	user, err := h.privacyService.AnonymizeUser(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrRetentionNotEnded):
+			writeJSONError(w, http.StatusConflict, err.Error())
+		default:
+			writeJSONError(w, http.StatusNotFound, err.Error())
+		}
+		return
+	}
+
+	response := &dto.UserResponse{
+		ID:         user.ID,
+		Type:       string(user.Type),
+		AddressID:  user.AddressID,
+		FirstName:  user.FirstName,
+		MiddleName: user.MiddleName,
+		LastName:   user.LastName,
+		Email:      user.Email,
+		Phone:      user.Phone,
+		Version:    user.Version,
+		CreatedAt:  user.CreatedAt.Format(time.RFC3339),
+	}
+
+	if user.UpdatedAt != nil {
+		updatedAt := user.UpdatedAt.Format(time.RFC3339)
+		response.UpdatedAt = &updatedAt
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+func dataSubjectRequestResponse(request *models.DataSubjectRequest) *dto.DataSubjectRequestResponse {
+	response := &dto.DataSubjectRequestResponse{
+		ID:         request.ID,
+		UserID:     request.UserID,
+		Type:       string(request.Type),
+		Status:     string(request.Status),
+		Details:    request.Details,
+		Resolution: request.Resolution,
+		DueAt:      request.DueAt.Format(time.RFC3339),
+		ResolvedBy: request.ResolvedBy,
+		CreatedAt:  request.CreatedAt.Format(time.RFC3339),
+	}
+
+	if request.ResolvedAt != nil {
+		resolvedAt := request.ResolvedAt.Format(time.RFC3339)
+		response.ResolvedAt = &resolvedAt
+	}
+
+	return response
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/edfloreshz/rent-contracts/src/services"
+)
+
+// reportFormats maps the formats a report can be exported as to their content type
+var reportFormats = map[string]string{
+	"csv":  "text/csv",
+	"xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"pdf":  "application/pdf",
+}
+
+type ReportHandler struct {
+	reportService      *services.ReportService
+	defaultAssumptions services.ForecastAssumptions
+	underRentThreshold float64
+}
+
+func NewReportHandler(reportService *services.ReportService, defaultAssumptions services.ForecastAssumptions, underRentThreshold float64) *ReportHandler {
+	return &ReportHandler{
+		reportService:      reportService,
+		defaultAssumptions: defaultAssumptions,
+		underRentThreshold: underRentThreshold,
+	}
+}
+
+// GetRentRoll returns the rent roll as JSON, or as a file with ?format=csv|xlsx|pdf
+func (h *ReportHandler) GetRentRoll(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if _, ok := reportFormats[format]; !ok && format != "" && format != "json" {
+		writeJSONError(w, http.StatusBadRequest, "Invalid format, expected json, csv, xlsx or pdf")
+		return
+	}
+
+	rentRoll, err := h.reportService.GetRentRoll(r.Context())
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve rent roll")
+		return
+	}
+
+	var file []byte
+	switch format {
+	case "csv":
+		file, err = services.RentRollCSV(rentRoll)
+	case "xlsx":
+		file, err = services.RentRollXLSX(rentRoll)
+	case "pdf":
+		file, err = services.RentRollPDF(rentRoll)
+	default:
+		writeJSON(w, http.StatusOK, rentRoll)
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to render rent roll")
+		return
+	}
+
+	writeFile(w, reportFormats[format], fmt.Sprintf("rent-roll-%s.%s", rentRoll.GeneratedAt.Format(time.DateOnly), format), file)
+}
+
+// GetLeaseExpirations returns the contracts expiring in the next 30, 60, 90 and
+// 180 days with their renewal status and the rent at risk per month
+func (h *ReportHandler) GetLeaseExpirations(w http.ResponseWriter, r *http.Request) {
+	expirations, err := h.reportService.GetLeaseExpirations(r.Context())
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve lease expirations")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, expirations)
+}
+
+// GetCashFlowForecast supports ?months= (12 by default), ?renewalProbability= and
+// ?vacancyMonths=, which default to the configured assumptions
+func (h *ReportHandler) GetCashFlowForecast(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	months := 12
+	assumptions := h.defaultAssumptions
+
+	if value := query.Get("months"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Invalid months")
+			return
+		}
+		months = parsed
+	}
+	if value := query.Get("renewalProbability"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Invalid renewalProbability")
+			return
+		}
+		assumptions.RenewalProbability = parsed
+	}
+	if value := query.Get("vacancyMonths"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Invalid vacancyMonths")
+			return
+		}
+		assumptions.VacancyMonths = parsed
+	}
+
+	forecast, err := h.reportService.GetCashFlowForecast(r.Context(), months, assumptions)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidForecast) {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve cash flow forecast")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, forecast)
+}
+
+// GetDelinquencyAging supports ?groupBy=tenant|property|landlord, by tenant by default
+func (h *ReportHandler) GetDelinquencyAging(w http.ResponseWriter, r *http.Request) {
+	groupBy := r.URL.Query().Get("groupBy")
+	if groupBy == "" {
+		groupBy = services.AgingByTenant
+	}
+
+	aging, err := h.reportService.GetDelinquencyAging(r.Context(), groupBy)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidAgingGroup) {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve delinquency aging")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, aging)
+}
+
+// GetRentBenchmarks supports ?threshold=, the percentage below the local median
+// rent at which contracts are flagged, which defaults to the configured one
+func (h *ReportHandler) GetRentBenchmarks(w http.ResponseWriter, r *http.Request) {
+	threshold := h.underRentThreshold
+	if value := r.URL.Query().Get("threshold"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Invalid threshold")
+			return
+		}
+		threshold = parsed
+	}
+
+	benchmarks, err := h.reportService.GetRentBenchmarks(r.Context(), threshold)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidBenchmarkThreshold) {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve rent benchmarks")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, benchmarks)
+}
+
+// GetTaxSummary returns the income, VAT and withheld ISR of each landlord per
+// property for ?year= (the current one by default), as JSON or as a file with
+// ?format=csv|pdf. ?landlordId= limits it to one landlord.
+func (h *ReportHandler) GetTaxSummary(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format != "" && format != "json" && format != "csv" && format != "pdf" {
+		writeJSONError(w, http.StatusBadRequest, "Invalid format, expected json, csv or pdf")
+		return
+	}
+
+	year := time.Now().Year()
+	if value := query.Get("year"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Invalid year")
+			return
+		}
+		year = parsed
+	}
+	landlordID, ok := landlordIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	summary, err := h.reportService.GetTaxSummary(r.Context(), year, landlordID)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidTaxYear) {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve tax summary")
+		return
+	}
+
+	var file []byte
+	switch format {
+	case "csv":
+		file, err = services.TaxSummaryCSV(summary)
+	case "pdf":
+		file, err = services.TaxSummaryPDF(summary)
+	default:
+		writeJSON(w, http.StatusOK, summary)
+		return
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to render tax summary")
+		return
+	}
+
+	name := fmt.Sprintf("tax-summary-%d", year)
+	if landlordID != nil {
+		name += "-" + landlordID.String()
+	}
+	writeFile(w, reportFormats[format], name+"."+format, file)
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/edfloreshz/rent-contracts/src/dto"
+	"github.com/edfloreshz/rent-contracts/src/models"
+	"github.com/edfloreshz/rent-contracts/src/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type StatementHandler struct {
+	statementService *services.StatementService
+}
+
+func NewStatementHandler(statementService *services.StatementService) *StatementHandler {
+	return &StatementHandler{
+		statementService: statementService,
+	}
+}
+
+// CreateOwnerStatement generates the statement of a landlord for a month and
+// archives it as PDF and CSV
+func (h *StatementHandler) CreateOwnerStatement(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateOwnerStatementRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.LandlordID == uuid.Nil {
+		writeJSONError(w, http.StatusBadRequest, "landlordId is required")
+		return
+	}
+	period, err := time.Parse("2006-01", req.Period)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid period, expected YYYY-MM")
+		return
+	}
+
+	statement, err := h.statementService.CreateOwnerStatement(r.Context(), req.LandlordID, period)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, statement)
+}
+
+// GetOwnerStatements lists the archived statements, with ?landlordId= to list
+// those of one landlord
+func (h *StatementHandler) GetOwnerStatements(w http.ResponseWriter, r *http.Request) {
+	landlordID, ok := landlordIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	documents, err := h.statementService.GetOwnerStatements(r.Context(), landlordID)
+	if err != nil {
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve statements")
+		return
+	}
+
+	responses := make([]*dto.DocumentResponse, len(documents))
+	for i := range documents {
+		responses[i] = documentResponse(&documents[i])
+	}
+
+	writeJSON(w, http.StatusOK, responses)
+}
+
+// DownloadOwnerStatement returns an archived statement file as it was issued
+func (h *StatementHandler) DownloadOwnerStatement(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+
+	document, err := h.statementService.GetDocument(r.Context(), id)
+	if err != nil || document.Kind != models.OwnerStatementDocument {
+		writeJSONError(w, http.StatusNotFound, "statement not found")
+		return
+	}
+
+	writeFile(w, document.ContentType, document.FileName, document.Content)
+}
+
+func documentResponse(document *models.Document) *dto.DocumentResponse {
+	return &dto.DocumentResponse{
+		ID:          document.ID,
+		Kind:        string(document.Kind),
+		SubjectID:   document.SubjectID,
+		PeriodStart: document.PeriodStart.Format("2006-01-02"),
+		PeriodEnd:   document.PeriodEnd.Format("2006-01-02"),
+		FileName:    document.FileName,
+		ContentType: document.ContentType,
+		CreatedAt:   document.CreatedAt.Format(time.RFC3339),
+	}
+}
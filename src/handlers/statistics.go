@@ -1,9 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/edfloreshz/rent-contracts/src/services"
+	"github.com/google/uuid"
 )
 
 type StatisticsHandler struct {
@@ -16,12 +21,138 @@ func NewStatisticsHandler(statisticsService *services.StatisticsService) *Statis
 	}
 }
 
+// GetOverallStatistics supports the filters of statisticsFilterParams
 func (h *StatisticsHandler) GetOverallStatistics(w http.ResponseWriter, r *http.Request) {
-	stats, err := h.statisticsService.GetOverallContractStatistics()
+	filter, ok := statisticsFilterParams(w, r)
+	if !ok {
+		return
+	}
+
+	stats, err := h.statisticsService.GetOverallContractStatistics(r.Context(), filter)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidStatisticsFilter) {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve overall statistics")
 		return
 	}
 
 	writeJSON(w, http.StatusOK, stats)
 }
+
+// GetTimeSeries supports ?from=, ?to= and ?interval=month|quarter|year besides the
+// filters of statisticsFilterParams. It defaults to the last twelve months.
+func (h *StatisticsHandler) GetTimeSeries(w http.ResponseWriter, r *http.Request) {
+	statisticsFilter, ok := statisticsFilterParams(w, r)
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	now := time.Now().UTC()
+	filter := services.TimeSeriesFilter{
+		StatisticsFilter: *statisticsFilter,
+		From:             time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -11, 0),
+		To:               now,
+		Interval:         services.MonthInterval,
+	}
+
+	for name, target := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := query.Get(name); value != "" {
+			t, err := parseDateParam(value)
+			if err != nil {
+				writeJSONError(w, http.StatusBadRequest, "Invalid "+name+", expected an RFC 3339 timestamp or a date")
+				return
+			}
+			*target = t
+		}
+	}
+	if interval := query.Get("interval"); interval != "" {
+		filter.Interval = interval
+	}
+
+	buckets, err := h.statisticsService.GetTimeSeries(r.Context(), &filter)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidStatisticsFilter) || errors.Is(err, services.ErrInvalidTimeSeries) {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve time series")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, buckets)
+}
+
+// GetRetention supports ?months=, how many months of churn to return (12 by
+// default), besides the filters of statisticsFilterParams
+func (h *StatisticsHandler) GetRetention(w http.ResponseWriter, r *http.Request) {
+	filter, ok := statisticsFilterParams(w, r)
+	if !ok {
+		return
+	}
+
+	months := 12
+	if value := r.URL.Query().Get("months"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Invalid months")
+			return
+		}
+		months = parsed
+	}
+
+	retention, err := h.statisticsService.GetRetention(r.Context(), filter, months)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidStatisticsFilter) || errors.Is(err, services.ErrInvalidChurnMonths) {
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSONError(w, http.StatusInternalServerError, "Failed to retrieve retention statistics")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, retention)
+}
+
+// statisticsFilterParams reads ?landlordId=, ?city=, ?state=, ?neighborhood=,
+// ?addressId= (repeated or comma separated) and ?groupBy=landlord|city|state|
+// neighborhood|address. It writes a 400 and returns false when one is malformed.
+func statisticsFilterParams(w http.ResponseWriter, r *http.Request) (*services.StatisticsFilter, bool) {
+	query := r.URL.Query()
+	filter := &services.StatisticsFilter{}
+
+	if value := query.Get("landlordId"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "Invalid landlordId")
+			return nil, false
+		}
+		filter.LandlordID = &id
+	}
+	for name, target := range map[string]**string{"city": &filter.City, "state": &filter.State, "neighborhood": &filter.Neighborhood} {
+		if value := query.Get(name); value != "" {
+			*target = &value
+		}
+	}
+	for _, values := range query["addressId"] {
+		for _, value := range strings.Split(values, ",") {
+			id, err := uuid.Parse(strings.TrimSpace(value))
+			if err != nil {
+				writeJSONError(w, http.StatusBadRequest, "Invalid addressId")
+				return nil, false
+			}
+			filter.AddressIDs = append(filter.AddressIDs, id)
+		}
+	}
+	if value := query.Get("groupBy"); value != "" {
+		filter.GroupBy = &value
+	}
+
+	if err := filter.Validate(); err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	return filter, true
+}
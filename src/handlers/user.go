@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
@@ -30,7 +31,7 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.userService.CreateUser(&req)
+	user, err := h.userService.CreateUser(r.Context(), &req)
 	if err != nil {
 		writeJSONError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -45,6 +46,7 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 		LastName:   user.LastName,
 		Email:      user.Email,
 		Phone:      user.Phone,
+		Version:    user.Version,
 		CreatedAt:  user.CreatedAt.Format(time.RFC3339),
 	}
 
@@ -53,6 +55,7 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 		response.UpdatedAt = &updatedAt
 	}
 
+	w.Header().Set("ETag", etag(user.Version))
 	writeJSON(w, http.StatusCreated, response)
 }
 
@@ -64,12 +67,30 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.userService.GetUserByID(id)
+	asOf, err := asOfParam(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var user *models.User
+	if asOf != nil {
+		user, err = h.userService.GetUserAsOf(r.Context(), id, *asOf)
+	} else {
+		user, err = h.userService.GetUserByID(r.Context(), id)
+	}
 	if err != nil {
 		writeJSONError(w, http.StatusNotFound, err.Error())
 		return
 	}
 
+	tag := etag(user.Version)
+	if asOf == nil && notModified(r, tag) {
+		w.Header().Set("ETag", tag)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
 	response := &dto.UserResponse{
 		ID:         user.ID,
 		Type:       string(user.Type),
@@ -79,6 +100,7 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 		LastName:   user.LastName,
 		Email:      user.Email,
 		Phone:      user.Phone,
+		Version:    user.Version,
 		CreatedAt:  user.CreatedAt.Format(time.RFC3339),
 	}
 
@@ -99,10 +121,12 @@ func (h *UserHandler) GetUser(w http.ResponseWriter, r *http.Request) {
 			State:        user.Address.State,
 			ZipCode:      user.Address.ZipCode,
 			Country:      user.Address.Country,
+			Version:      user.Address.Version,
 			CreatedAt:    user.Address.CreatedAt.Format(time.RFC3339),
 		}
 	}
 
+	w.Header().Set("ETag", tag)
 	writeJSON(w, http.StatusOK, response)
 }
 
@@ -113,9 +137,9 @@ func (h *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
 	var err error
 
 	if typeFilter != "" {
-		users, err = h.userService.GetUsersByType(typeFilter)
+		users, err = h.userService.GetUsersByType(r.Context(), typeFilter)
 	} else {
-		users, err = h.userService.GetAllUsers()
+		users, err = h.userService.GetAllUsers(r.Context())
 	}
 
 	if err != nil {
@@ -134,6 +158,7 @@ func (h *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
 			LastName:   user.LastName,
 			Email:      user.Email,
 			Phone:      user.Phone,
+			Version:    user.Version,
 			CreatedAt:  user.CreatedAt.Format(time.RFC3339),
 		}
 
@@ -154,6 +179,7 @@ func (h *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
 				State:        user.Address.State,
 				ZipCode:      user.Address.ZipCode,
 				Country:      user.Address.Country,
+				Version:      user.Address.Version,
 				CreatedAt:    user.Address.CreatedAt.Format(time.RFC3339),
 			}
 		}
@@ -172,15 +198,28 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	expectedVersions, err := ifMatchVersions(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	var req dto.UpdateUserRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeJSONError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	user, err := h.userService.UpdateUser(id, &req)
+	user, err := h.userService.UpdateUser(r.Context(), id, &req, expectedVersions)
 	if err != nil {
-		writeJSONError(w, http.StatusNotFound, err.Error())
+		switch {
+		case errors.Is(err, services.ErrPreconditionFailed):
+			writeJSONError(w, http.StatusPreconditionFailed, err.Error())
+		case errors.Is(err, services.ErrVersionConflict):
+			writeJSONError(w, http.StatusConflict, err.Error())
+		default:
+			writeJSONError(w, http.StatusNotFound, err.Error())
+		}
 		return
 	}
 
@@ -193,6 +232,7 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		LastName:   user.LastName,
 		Email:      user.Email,
 		Phone:      user.Phone,
+		Version:    user.Version,
 		CreatedAt:  user.CreatedAt.Format(time.RFC3339),
 	}
 
@@ -201,6 +241,63 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		response.UpdatedAt = &updatedAt
 	}
 
+	w.Header().Set("ETag", etag(user.Version))
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *UserHandler) PatchUser(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+
+	expectedVersions, err := ifMatchVersions(r)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	patch, err := readMergePatch(r)
+	if err != nil {
+		writeJSONError(w, http.StatusUnsupportedMediaType, err.Error())
+		return
+	}
+
+	user, changed, err := h.userService.PatchUser(r.Context(), id, patch, expectedVersions)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrPreconditionFailed):
+			writeJSONError(w, http.StatusPreconditionFailed, err.Error())
+		case errors.Is(err, services.ErrVersionConflict):
+			writeJSONError(w, http.StatusConflict, err.Error())
+		default:
+			writeJSONError(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	response := &dto.UserResponse{
+		ID:         user.ID,
+		Type:       string(user.Type),
+		AddressID:  user.AddressID,
+		FirstName:  user.FirstName,
+		MiddleName: user.MiddleName,
+		LastName:   user.LastName,
+		Email:      user.Email,
+		Phone:      user.Phone,
+		Version:    user.Version,
+		CreatedAt:  user.CreatedAt.Format(time.RFC3339),
+	}
+
+	if user.UpdatedAt != nil {
+		updatedAt := user.UpdatedAt.Format(time.RFC3339)
+		response.UpdatedAt = &updatedAt
+	}
+
+	setChangedFields(w, changed)
+	w.Header().Set("ETag", etag(user.Version))
 	writeJSON(w, http.StatusOK, response)
 }
 
@@ -212,11 +309,115 @@ func (h *UserHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.userService.DeleteUser(id)
+	err = h.userService.DeleteUser(r.Context(), id, cascadeParam(r))
+	if err != nil {
+		if writeDependencyError(w, err) {
+			return
+		}
+		writeJSONError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *UserHandler) GetDeletedUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.userService.GetDeletedUsers(r.Context())
 	if err != nil {
 		writeJSONError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	var responses []dto.UserResponse
+	for _, user := range users {
+		response := dto.UserResponse{
+			ID:         user.ID,
+			Type:       string(user.Type),
+			AddressID:  user.AddressID,
+			FirstName:  user.FirstName,
+			MiddleName: user.MiddleName,
+			LastName:   user.LastName,
+			Email:      user.Email,
+			Phone:      user.Phone,
+			Version:    user.Version,
+			CreatedAt:  user.CreatedAt.Format(time.RFC3339),
+		}
+
+		if user.UpdatedAt != nil {
+			updatedAt := user.UpdatedAt.Format(time.RFC3339)
+			response.UpdatedAt = &updatedAt
+		}
+
+		if user.DeletedAt.Valid {
+			deletedAt := user.DeletedAt.Time.Format(time.RFC3339)
+			response.DeletedAt = &deletedAt
+		}
+
+		responses = append(responses, response)
+	}
+
+	writeJSON(w, http.StatusOK, responses)
+}
+
+func (h *UserHandler) RestoreUser(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+
+	user, err := h.userService.RestoreUser(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrRestoreConflict):
+			writeJSONError(w, http.StatusConflict, err.Error())
+		default:
+			writeJSONError(w, http.StatusNotFound, err.Error())
+		}
+		return
+	}
+
+	response := &dto.UserResponse{
+		ID:         user.ID,
+		Type:       string(user.Type),
+		AddressID:  user.AddressID,
+		FirstName:  user.FirstName,
+		MiddleName: user.MiddleName,
+		LastName:   user.LastName,
+		Email:      user.Email,
+		Phone:      user.Phone,
+		Version:    user.Version,
+		CreatedAt:  user.CreatedAt.Format(time.RFC3339),
+	}
+
+	if user.UpdatedAt != nil {
+		updatedAt := user.UpdatedAt.Format(time.RFC3339)
+		response.UpdatedAt = &updatedAt
+	}
+
+	w.Header().Set("ETag", etag(user.Version))
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (h *UserHandler) PurgeUser(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, "Invalid UUID")
+		return
+	}
+
+	err = h.userService.PurgeUser(r.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrStillReferenced):
+			writeJSONError(w, http.StatusConflict, err.Error())
+		default:
+			writeJSONError(w, http.StatusNotFound, err.Error())
+		}
+		return
+	}
+
 	w.WriteHeader(http.StatusNoContent)
 }
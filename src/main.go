@@ -1,12 +1,16 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"log"
 	"net/http"
 
 	"github.com/edfloreshz/rent-contracts/src/config"
 	"github.com/edfloreshz/rent-contracts/src/database"
 	"github.com/edfloreshz/rent-contracts/src/routes"
+	"github.com/edfloreshz/rent-contracts/src/services"
 	"github.com/joho/godotenv"
 )
 
@@ -18,14 +22,38 @@ func main() {
 	// Initialize configuration
 	cfg := config.New()
 
+	// Generate a signing secret when none is configured
+	if cfg.JWTSecret == "" {
+		if cfg.Environment == "production" {
+			log.Fatal("JWT_SECRET must be set in production")
+		}
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			log.Fatal("Failed to generate JWT secret: ", err)
+		}
+		cfg.JWTSecret = hex.EncodeToString(secret)
+		log.Println("JWT_SECRET not set, using a random secret; sessions will not survive restarts")
+	}
+
 	// Connect to database
 	db, err := database.Initialize(cfg.DatabaseURL)
 	if err != nil {
 		log.Fatal("Failed to initialize database:", err)
 	}
 
+	// Set the password of the initial admin
+	if cfg.AdminEmail != "" && cfg.AdminPassword != "" {
+		authService := services.NewAuthService(db, []byte(cfg.JWTSecret), cfg.AccessTokenTTL, cfg.RefreshTokenTTL)
+		if err := authService.BootstrapAdmin(cfg.AdminEmail, cfg.AdminPassword); err != nil {
+			log.Println("Failed to bootstrap admin credentials:", err)
+		}
+	}
+
+	// Purge records that have been in the trash longer than the retention period
+	go services.NewTrashService(db).RunRetention(context.Background(), cfg.TrashRetention)
+
 	// Setup routes
-	router := routes.Router(db)
+	router := routes.Router(db, cfg)
 
 	// Get port from environment or use default
 	port := config.GetEnv("PORT", "8080")
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StringList is a list of strings stored as a JSON array
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	data, err := json.Marshal([]string(l))
+	return string(data), err
+}
+
+func (l *StringList) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, (*[]string)(l))
+	case string:
+		return json.Unmarshal([]byte(v), (*[]string)(l))
+	default:
+		return fmt.Errorf("cannot scan %T into StringList", value)
+	}
+}
+
+// APIKey grants a machine integration non-interactive access on behalf of the user who created it
+type APIKey struct {
+	ID             uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	OrganizationID uuid.UUID  `json:"organizationId" gorm:"column:organizationid;type:uuid;not null"`
+	UserID         uuid.UUID  `json:"userId" gorm:"column:userid;type:uuid;not null"`
+	Name           string     `json:"name" gorm:"column:name;not null"`
+	Prefix         string     `json:"prefix" gorm:"column:prefix;not null"`
+	KeyHash        string     `json:"-" gorm:"column:keyhash;not null"`
+	Scopes         StringList `json:"scopes" gorm:"column:scopes;type:jsonb;not null"`
+	AllowedIPs     StringList `json:"allowedIps" gorm:"column:allowedips;type:jsonb;not null"`
+	ExpiresAt      *time.Time `json:"expiresAt" gorm:"column:expiresat"`
+	LastUsedAt     *time.Time `json:"lastUsedAt" gorm:"column:lastusedat"`
+	RevokedAt      *time.Time `json:"revokedAt" gorm:"column:revokedat"`
+	CreatedAt      time.Time  `json:"createdAt" gorm:"column:createdat;default:CURRENT_TIMESTAMP"`
+
+	// Relationships
+	User User `json:"user" gorm:"foreignKey:UserID;references:id"`
+}
+
+func (APIKey) TableName() string {
+	return "apikeys"
+}
+
+// Active reports whether the key can still be used at the given time
+func (k APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
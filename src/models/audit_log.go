@@ -0,0 +1,70 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AuditAction string
+
+const (
+	AuditCreate    AuditAction = "create"
+	AuditUpdate    AuditAction = "update"
+	AuditDelete    AuditAction = "delete"
+	AuditRestore   AuditAction = "restore"
+	AuditPurge     AuditAction = "purge"
+	AuditAnonymize AuditAction = "anonymize"
+)
+
+// AuditChange holds the value of a field before and after a change
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditChanges maps field names to their change, stored as a JSON object
+type AuditChanges map[string]AuditChange
+
+func (c AuditChanges) Value() (driver.Value, error) {
+	if c == nil {
+		return "{}", nil
+	}
+	data, err := json.Marshal(map[string]AuditChange(c))
+	return string(data), err
+}
+
+func (c *AuditChanges) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*c = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, (*map[string]AuditChange)(c))
+	case string:
+		return json.Unmarshal([]byte(v), (*map[string]AuditChange)(c))
+	default:
+		return fmt.Errorf("cannot scan %T into AuditChanges", value)
+	}
+}
+
+// AuditLog records who created, updated or deleted a record and what changed
+type AuditLog struct {
+	ID             uuid.UUID    `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	OrganizationID uuid.UUID    `json:"organizationId" gorm:"column:organizationid;type:uuid;not null"`
+	ActorID        *uuid.UUID   `json:"actorId" gorm:"column:actorid;type:uuid"`
+	APIKeyID       *uuid.UUID   `json:"apiKeyId" gorm:"column:apikeyid;type:uuid"`
+	Action         AuditAction  `json:"action" gorm:"column:action;not null"`
+	EntityType     string       `json:"entityType" gorm:"column:entitytype;not null"`
+	EntityID       uuid.UUID    `json:"entityId" gorm:"column:entityid;type:uuid;not null"`
+	Changes        AuditChanges `json:"changes" gorm:"column:changes;type:jsonb;not null"`
+	RequestID      *string      `json:"requestId" gorm:"column:requestid"`
+	CreatedAt      time.Time    `json:"createdAt" gorm:"column:createdat;default:CURRENT_TIMESTAMP"`
+}
+
+func (AuditLog) TableName() string {
+	return "auditlogs"
+}
@@ -22,11 +22,13 @@ const (
 
 type Contract struct {
 	ID               uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	OrganizationID   uuid.UUID      `json:"organizationId" gorm:"column:organizationid;type:uuid;not null"`
 	CurrentVersionID *uuid.UUID     `json:"currentVersionId" gorm:"column:currentversionid;type:uuid"`
 	LandlordID       uuid.UUID      `json:"landlordId" gorm:"column:landlordid;type:uuid;not null"`
 	TenantID         uuid.UUID      `json:"tenantId" gorm:"column:tenantid;type:uuid;not null"`
 	AddressID        uuid.UUID      `json:"addressId" gorm:"column:addressid;type:uuid;not null"`
 	Deposit          float64        `json:"deposit" gorm:"column:deposit;type:numeric;not null"`
+	Version          int            `json:"version" gorm:"column:version;not null;default:1"`
 	CreatedAt        time.Time      `json:"createdAt" gorm:"column:createdat;default:CURRENT_TIMESTAMP"`
 	UpdatedAt        *time.Time     `json:"updatedAt" gorm:"column:updatedat"`
 	DeletedAt        gorm.DeletedAt `json:"deletedAt" gorm:"column:deletedat;index"`
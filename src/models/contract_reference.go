@@ -1,12 +1,27 @@
 package models
 
 import (
+	"time"
+
 	"github.com/google/uuid"
 )
 
+// ConfirmationStatus is how a reference answered the request to vouch for a tenant
+type ConfirmationStatus string
+
+const (
+	PendingConfirmation   ConfirmationStatus = "pending"
+	ConfirmedConfirmation ConfirmationStatus = "confirmed"
+	DeclinedConfirmation  ConfirmationStatus = "declined"
+)
+
+// ContractReference links a reference to a contract. It doubles as the request for
+// the reference to confirm they vouch for the tenant.
 type ContractReference struct {
-	ContractID  uuid.UUID `json:"contractId" gorm:"column:contractid;type:uuid;primaryKey"`
-	ReferenceID uuid.UUID `json:"referenceId" gorm:"column:referenceid;type:uuid;primaryKey"`
+	ContractID  uuid.UUID          `json:"contractId" gorm:"column:contractid;type:uuid;primaryKey"`
+	ReferenceID uuid.UUID          `json:"referenceId" gorm:"column:referenceid;type:uuid;primaryKey"`
+	Status      ConfirmationStatus `json:"status" gorm:"column:status;type:confirmationstatus;not null;default:pending"`
+	RespondedAt *time.Time         `json:"respondedAt" gorm:"column:respondedat"`
 
 	// Relationships
 	Contract  Contract `json:"contract" gorm:"foreignKey:ContractID;references:id"`
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DataSubjectRequestType is one of the ARCO rights a person may exercise over their
+// personal data: access, rectification, cancellation or opposition
+type DataSubjectRequestType string
+
+type DataSubjectRequestStatus string
+
+const (
+	AccessRequest        DataSubjectRequestType = "access"
+	RectificationRequest DataSubjectRequestType = "rectification"
+	CancellationRequest  DataSubjectRequestType = "cancellation"
+	OppositionRequest    DataSubjectRequestType = "opposition"
+)
+
+const (
+	PendingRequest   DataSubjectRequestStatus = "pending"
+	CompletedRequest DataSubjectRequestStatus = "completed"
+	RejectedRequest  DataSubjectRequestStatus = "rejected"
+)
+
+type DataSubjectRequest struct {
+	ID             uuid.UUID                `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	OrganizationID uuid.UUID                `json:"organizationId" gorm:"column:organizationid;type:uuid;not null"`
+	UserID         uuid.UUID                `json:"userId" gorm:"column:userid;type:uuid;not null"`
+	Type           DataSubjectRequestType   `json:"type" gorm:"column:type;type:datasubjectrequesttype;not null"`
+	Status         DataSubjectRequestStatus `json:"status" gorm:"column:status;type:datasubjectrequeststatus;not null;default:pending"`
+	Details        *string                  `json:"details" gorm:"column:details"`
+	Resolution     *string                  `json:"resolution" gorm:"column:resolution"`
+	DueAt          time.Time                `json:"dueAt" gorm:"column:dueat;not null"`
+	ResolvedAt     *time.Time               `json:"resolvedAt" gorm:"column:resolvedat"`
+	ResolvedBy     *uuid.UUID               `json:"resolvedBy" gorm:"column:resolvedby;type:uuid"`
+	CreatedAt      time.Time                `json:"createdAt" gorm:"column:createdat;default:CURRENT_TIMESTAMP"`
+}
+
+func (DataSubjectRequest) TableName() string {
+	return "datasubjectrequests"
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type DocumentKind string
+
+const (
+	OwnerStatementDocument DocumentKind = "ownerStatement"
+)
+
+// Document is a generated file kept in the archive. SubjectID is the record it
+// was generated for, such as the landlord of an owner statement.
+type Document struct {
+	ID             uuid.UUID    `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	OrganizationID uuid.UUID    `json:"organizationId" gorm:"column:organizationid;type:uuid;not null"`
+	Kind           DocumentKind `json:"kind" gorm:"column:kind;type:documentkind;not null"`
+	SubjectID      uuid.UUID    `json:"subjectId" gorm:"column:subjectid;type:uuid;not null"`
+	PeriodStart    time.Time    `json:"periodStart" gorm:"column:periodstart;type:date;not null"`
+	PeriodEnd      time.Time    `json:"periodEnd" gorm:"column:periodend;type:date;not null"`
+	FileName       string       `json:"fileName" gorm:"column:filename;not null"`
+	ContentType    string       `json:"contentType" gorm:"column:contenttype;not null"`
+	Content        []byte       `json:"-" gorm:"column:content;not null"`
+	CreatedAt      time.Time    `json:"createdAt" gorm:"column:createdat;default:CURRENT_TIMESTAMP"`
+}
+
+func (Document) TableName() string {
+	return "documents"
+}
@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Expense is paid on behalf of a landlord and deducted from their statement
+type Expense struct {
+	ID             uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	OrganizationID uuid.UUID  `json:"organizationId" gorm:"column:organizationid;type:uuid;not null"`
+	LandlordID     uuid.UUID  `json:"landlordId" gorm:"column:landlordid;type:uuid;not null"`
+	AddressID      *uuid.UUID `json:"addressId" gorm:"column:addressid;type:uuid"`
+	Amount         float64    `json:"amount" gorm:"column:amount;type:numeric;not null"`
+	PaidAt         time.Time  `json:"paidAt" gorm:"column:paidat;type:date;not null"`
+	Description    string     `json:"description" gorm:"column:description;not null"`
+	CreatedAt      time.Time  `json:"createdAt" gorm:"column:createdat;default:CURRENT_TIMESTAMP"`
+}
+
+func (Expense) TableName() string {
+	return "expenses"
+}
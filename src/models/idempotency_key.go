@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IdempotencyKey is a key sent by a caller, the user or the API key a request was
+// authenticated with, and the response to replay for it
+type IdempotencyKey struct {
+	OrganizationID uuid.UUID  `json:"organizationId" gorm:"column:organizationid;type:uuid;primaryKey"`
+	CallerID       uuid.UUID  `json:"callerId" gorm:"column:callerid;type:uuid;primaryKey"`
+	Key            string     `json:"key" gorm:"column:key;primaryKey"`
+	Fingerprint    string     `json:"fingerprint" gorm:"column:fingerprint;not null"`
+	StatusCode     int        `json:"statusCode" gorm:"column:statuscode"`
+	ContentType    string     `json:"contentType" gorm:"column:contenttype"`
+	ETag           string     `json:"etag" gorm:"column:etag"`
+	Location       string     `json:"location" gorm:"column:location"`
+	ResponseBody   []byte     `json:"responseBody" gorm:"column:responsebody"`
+	CreatedAt      time.Time  `json:"createdAt" gorm:"column:createdat;default:CURRENT_TIMESTAMP"`
+	CompletedAt    *time.Time `json:"completedAt" gorm:"column:completedat"`
+}
+
+func (IdempotencyKey) TableName() string {
+	return "idempotencykeys"
+}
+
+func (k IdempotencyKey) Completed() bool {
+	return k.CompletedAt != nil
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Organization is a property management portfolio whose data is isolated from every other organization
+type Organization struct {
+	ID   uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	Name string    `json:"name" gorm:"column:name;not null"`
+	// ManagementCommission is the percentage of the rent collected for a landlord kept as commission
+	ManagementCommission float64    `json:"managementCommission" gorm:"column:managementcommission;type:numeric;not null;default:0"`
+	CreatedAt            time.Time  `json:"createdAt" gorm:"column:createdat;default:CURRENT_TIMESTAMP"`
+	UpdatedAt            *time.Time `json:"updatedAt" gorm:"column:updatedat"`
+}
+
+func (Organization) TableName() string {
+	return "organizations"
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PaymentType tells rent, which settles the charges of a contract, from late fees
+type PaymentType string
+
+const (
+	RentPayment    PaymentType = "rent"
+	LateFeePayment PaymentType = "lateFee"
+)
+
+type Payment struct {
+	ID             uuid.UUID   `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	OrganizationID uuid.UUID   `json:"organizationId" gorm:"column:organizationid;type:uuid;not null"`
+	ContractID     uuid.UUID   `json:"contractId" gorm:"column:contractid;type:uuid;not null"`
+	Type           PaymentType `json:"type" gorm:"column:type;type:paymenttype;not null;default:rent"`
+	Amount         float64     `json:"amount" gorm:"column:amount;type:numeric;not null"`
+	PaidAt         time.Time   `json:"paidAt" gorm:"column:paidat;type:date;not null"`
+	Reference      *string     `json:"reference" gorm:"column:reference"`
+	// Tax figures of the invoice issued for the payment. Amount is the subtotal
+	// before VAT; corporate tenants withhold income tax from what they pay.
+	InvoiceNumber     *string   `json:"invoiceNumber" gorm:"column:invoicenumber"`
+	VAT               float64   `json:"vat" gorm:"column:vat;type:numeric;not null;default:0"`
+	IncomeTaxWithheld float64   `json:"incomeTaxWithheld" gorm:"column:incometaxwithheld;type:numeric;not null;default:0"`
+	CreatedAt         time.Time `json:"createdAt" gorm:"column:createdat;default:CURRENT_TIMESTAMP"`
+}
+
+func (Payment) TableName() string {
+	return "payments"
+}
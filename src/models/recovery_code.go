@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RecoveryCode is a single-use code that replaces a TOTP code when the authenticator is lost
+type RecoveryCode struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	UserID    uuid.UUID  `json:"userId" gorm:"column:userid;type:uuid;not null"`
+	CodeHash  string     `json:"-" gorm:"column:codehash;not null"`
+	UsedAt    *time.Time `json:"usedAt" gorm:"column:usedat"`
+	CreatedAt time.Time  `json:"createdAt" gorm:"column:createdat;default:CURRENT_TIMESTAMP"`
+}
+
+func (RecoveryCode) TableName() string {
+	return "recoverycodes"
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Session struct {
+	ID             uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:uuid_generate_v4()"`
+	UserID         uuid.UUID  `json:"userId" gorm:"column:userid;type:uuid;not null"`
+	RefreshTokenID uuid.UUID  `json:"-" gorm:"column:refreshtokenid;type:uuid;not null"`
+	ExpiresAt      time.Time  `json:"expiresAt" gorm:"column:expiresat;not null"`
+	RevokedAt      *time.Time `json:"revokedAt" gorm:"column:revokedat"`
+	MFAVerifiedAt  *time.Time `json:"mfaVerifiedAt" gorm:"column:mfaverifiedat"`
+	MFAChallengeID *uuid.UUID `json:"-" gorm:"column:mfachallengeid;type:uuid"`
+	CreatedAt      time.Time  `json:"createdAt" gorm:"column:createdat;default:CURRENT_TIMESTAMP"`
+
+	// Relationships
+	User User `json:"user" gorm:"foreignKey:UserID;references:id"`
+}
+
+func (Session) TableName() string {
+	return "sessions"
+}
+
+// Active reports whether the session can still be used at the given time
+func (s Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
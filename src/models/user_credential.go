@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type UserCredential struct {
+	UserID       uuid.UUID  `json:"userId" gorm:"column:userid;type:uuid;primaryKey"`
+	PasswordHash string     `json:"-" gorm:"column:passwordhash;not null"`
+	CreatedAt    time.Time  `json:"createdAt" gorm:"column:createdat;default:CURRENT_TIMESTAMP"`
+	UpdatedAt    *time.Time `json:"updatedAt" gorm:"column:updatedat"`
+
+	// Two-factor authentication. The secret is set on enrolment and only
+	// enforced once the user confirmed it with a first code.
+	TOTPSecret    *string    `json:"-" gorm:"column:totpsecret"`
+	TOTPEnabledAt *time.Time `json:"totpEnabledAt" gorm:"column:totpenabledat"`
+	TOTPLastStep  int64      `json:"-" gorm:"column:totplaststep;not null;default:0"`
+
+	// Failed two-factor codes since the last valid one. Too many lock verification
+	// until MFALockedUntil.
+	MFAFailedAttempts int        `json:"-" gorm:"column:mfafailedattempts;not null;default:0"`
+	MFALockedUntil    *time.Time `json:"-" gorm:"column:mfalockeduntil"`
+
+	// Relationships
+	User User `json:"user" gorm:"foreignKey:UserID;references:id"`
+}
+
+func (UserCredential) TableName() string {
+	return "usercredentials"
+}
+
+// TOTPEnabled reports whether logins must be verified with a second factor
+func (c UserCredential) TOTPEnabled() bool {
+	return c.TOTPSecret != nil && c.TOTPEnabledAt != nil
+}
@@ -7,6 +7,8 @@ import (
 
 	"github.com/MarceloPetrucio/go-scalar-api-reference"
 	"github.com/Zachacious/go-respec/respec"
+	"github.com/edfloreshz/rent-contracts/src/auth"
+	"github.com/edfloreshz/rent-contracts/src/config"
 	"github.com/edfloreshz/rent-contracts/src/handlers"
 	"github.com/edfloreshz/rent-contracts/src/services"
 	"github.com/go-chi/chi/v5"
@@ -15,19 +17,21 @@ import (
 	"gorm.io/gorm"
 )
 
-func Router(db *gorm.DB) http.Handler {
+func Router(db *gorm.DB, cfg *config.Config) http.Handler {
 	router := chi.NewRouter()
 
 	// Add middleware
+	router.Use(middleware.RequestID)
+	router.Use(handlers.ExposeRequestID)
 	router.Use(middleware.Logger)
 	router.Use(middleware.Recoverer)
 
 	// Configure CORS
 	router.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:5173"},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Origin", "Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "accept", "origin", "Cache-Control", "X-Requested-With"},
-		ExposedHeaders:   []string{"Content-Length"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Origin", "Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "accept", "origin", "Cache-Control", "X-Requested-With", "If-Match", "If-None-Match", "Idempotency-Key", "X-API-Key"},
+		ExposedHeaders:   []string{"Content-Length", "ETag", "Idempotent-Replayed", "X-Changed-Fields", "X-Request-Id"},
 		AllowCredentials: true,
 		MaxAge:           int(12 * time.Hour / time.Second),
 	}))
@@ -37,56 +41,214 @@ func Router(db *gorm.DB) http.Handler {
 	userService := services.NewUserService(db)
 	contractService := services.NewContractService(db)
 	statisticsService := services.NewStatisticsService(db)
+	confirmationService := services.NewConfirmationService(db)
+	idempotencyService := services.NewIdempotencyService(db)
+	bulkService := services.NewBulkService(db, addressService, userService, contractService)
+	organizationService := services.NewOrganizationService(db)
+	apiKeyService := services.NewAPIKeyService(db)
+	auditService := services.NewAuditService(db)
+	privacyService := services.NewPrivacyService(db, contractService, cfg.PersonalDataRetention)
+	reportService := services.NewReportService(db)
+	paymentService := services.NewPaymentService(db)
+	expenseService := services.NewExpenseService(db)
+	statementService := services.NewStatementService(db)
+	authService := services.NewAuthService(db, []byte(cfg.JWTSecret), cfg.AccessTokenTTL, cfg.RefreshTokenTTL)
 
 	// Initialize handlers
 	addressHandler := handlers.NewAddressHandler(addressService)
 	userHandler := handlers.NewUserHandler(userService)
 	contractHandler := handlers.NewContractHandler(contractService)
 	statisticsHandler := handlers.NewStatisticsHandler(statisticsService)
+	confirmationHandler := handlers.NewConfirmationHandler(confirmationService)
+	bulkHandler := handlers.NewBulkHandler(bulkService, cfg.MFARecentWindow)
+	authHandler := handlers.NewAuthHandler(authService)
+	organizationHandler := handlers.NewOrganizationHandler(organizationService)
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+	auditHandler := handlers.NewAuditHandler(auditService)
+	privacyHandler := handlers.NewPrivacyHandler(privacyService)
+	paymentHandler := handlers.NewPaymentHandler(paymentService)
+	expenseHandler := handlers.NewExpenseHandler(expenseService)
+	statementHandler := handlers.NewStatementHandler(statementService)
+	reportHandler := handlers.NewReportHandler(reportService, services.ForecastAssumptions{
+		RenewalProbability: cfg.ForecastRenewalProbability,
+		VacancyMonths:      cfg.ForecastVacancyMonths,
+	}, cfg.UnderRentThreshold)
+
+	// Initialize middleware
+	idempotent := handlers.Idempotency(idempotencyService)
+	authenticated := handlers.Authenticate(authService, apiKeyService)
+	can := handlers.RequirePermission
+	recentMFA := handlers.RequireRecentMFA(cfg.MFARecentWindow)
 
 	// API v1 routes
 	router.Route("/api/v1", func(r chi.Router) {
-		// Address routes
-		r.Route("/addresses", func(r chi.Router) {
-			respec.Meta(r).Tag("Addresses")
-			r.Post("/", respec.Handler(addressHandler.CreateAddress).Summary("Create a new address").Unwrap())
-			r.Get("/", respec.Handler(addressHandler.GetAllAddresses).Summary("Get all addresses").Unwrap())
-			r.Get("/{id}", respec.Handler(addressHandler.GetAddress).Summary("Get a single address").Unwrap())
-			r.Put("/{id}", respec.Handler(addressHandler.UpdateAddress).Summary("Update an address").Unwrap())
-			r.Delete("/{id}", respec.Handler(addressHandler.DeleteAddress).Summary("Delete an address").Unwrap())
-		})
+		// Authentication routes
+		r.Route("/auth", func(r chi.Router) {
+			respec.Meta(r).Tag("Authentication")
+			r.Post("/login", respec.Handler(authHandler.Login).Summary("Log in with email and password").Unwrap())
+			r.Post("/login/verify", respec.Handler(authHandler.VerifyLogin).Summary("Complete a login with a two-factor code").Unwrap())
+			r.Post("/refresh", respec.Handler(authHandler.Refresh).Summary("Exchange a refresh token for new tokens").Unwrap())
+			r.With(authenticated).Post("/logout", respec.Handler(authHandler.Logout).Summary("Log out and revoke the session").Security("BearerAuth").Unwrap())
 
-		// User routes
-		r.Route("/users", func(r chi.Router) {
-			respec.Meta(r).Tag("Users")
-			r.Post("/", respec.Handler(userHandler.CreateUser).Summary("Create a new user").Unwrap())
-			r.Get("/", respec.Handler(userHandler.GetAllUsers).Summary("Get all users").Unwrap())
-			r.Get("/{id}", respec.Handler(userHandler.GetUser).Summary("Get a single user").Unwrap())
-			r.Put("/{id}", respec.Handler(userHandler.UpdateUser).Summary("Update a user").Unwrap())
-			r.Delete("/{id}", respec.Handler(userHandler.DeleteUser).Summary("Delte a user").Unwrap())
+			// Two-factor authentication routes
+			r.Route("/mfa", func(r chi.Router) {
+				respec.Meta(r).Security("BearerAuth")
+				r.Use(authenticated)
+				r.Post("/totp", respec.Handler(authHandler.EnrollTOTP).Summary("Start TOTP enrolment").Unwrap())
+				r.Post("/totp/confirm", respec.Handler(authHandler.ConfirmTOTP).Summary("Confirm TOTP enrolment and get recovery codes").Unwrap())
+				r.With(recentMFA).Delete("/totp", respec.Handler(authHandler.DisableTOTP).Summary("Disable two-factor authentication").Unwrap())
+				r.Post("/verify", respec.Handler(authHandler.VerifyMFA).Summary("Verify a two-factor code for the current session").Unwrap())
+				r.With(recentMFA).Post("/recovery-codes", respec.Handler(authHandler.RegenerateRecoveryCodes).Summary("Regenerate recovery codes").Unwrap())
+			})
 		})
 
-		// Contract routes
-		r.Route("/contracts", func(r chi.Router) {
-			respec.Meta(r).Tag("Contracts")
-			r.Post("/", respec.Handler(contractHandler.CreateContract).Summary("Create a new contract").Unwrap())
-			r.Get("/", respec.Handler(contractHandler.GetAllContracts).Summary("Get all contracts").Unwrap()) // Supports ?tenantId=uuid
-			r.Get("/{id}", respec.Handler(contractHandler.GetContract).Summary("Get a single contract").Unwrap())
-			r.Put("/{id}", respec.Handler(contractHandler.UpdateContract).Summary("Update a contract").Unwrap())
-			r.Delete("/{id}", respec.Handler(contractHandler.DeleteContract).Summary("Delte a contract").Unwrap())
-
-			// Contract version routes
-			r.Post("/versions", respec.Handler(contractHandler.CreateContractVersion).Summary("Create a new contract version").Unwrap())
-			r.Get("/{id}/versions", respec.Handler(contractHandler.GetContractVersions).Summary("Get all versions for a contract").Unwrap())
-
-			// Contract document routes
-			r.Get("/{id}/document", respec.Handler(contractHandler.GetContractDocument).Summary("Get the document for a contract").Unwrap())
-		})
+		// Routes below require an authenticated caller
+		r.Group(func(r chi.Router) {
+			respec.Meta(r).Security("BearerAuth")
+			r.Use(authenticated)
+
+			// Organization routes
+			r.Route("/organization", func(r chi.Router) {
+				respec.Meta(r).Tag("Organization")
+				r.With(can(auth.ReadOrganization)).Get("/", respec.Handler(organizationHandler.GetOrganization).Summary("Get the caller's organization").Unwrap())
+				r.With(can(auth.WriteOrganization)).Put("/", respec.Handler(organizationHandler.UpdateOrganization).Summary("Update the caller's organization").Unwrap())
+			})
+
+			// API key routes
+			r.Route("/api-keys", func(r chi.Router) {
+				respec.Meta(r).Tag("API Keys")
+				r.Use(can(auth.ManageAPIKeys))
+				r.Post("/", respec.Handler(apiKeyHandler.CreateAPIKey).Summary("Create an API key").Unwrap())
+				r.Get("/", respec.Handler(apiKeyHandler.GetAllAPIKeys).Summary("Get all API keys").Unwrap())
+				r.Delete("/{id}", respec.Handler(apiKeyHandler.RevokeAPIKey).Summary("Revoke an API key").Unwrap())
+			})
+
+			// Address routes
+			r.Route("/addresses", func(r chi.Router) {
+				respec.Meta(r).Tag("Addresses")
+				r.With(can(auth.WriteAddresses), idempotent).Post("/", respec.Handler(addressHandler.CreateAddress).Summary("Create a new address").Unwrap())
+				r.With(can(auth.ReadAddresses)).Get("/", respec.Handler(addressHandler.GetAllAddresses).Summary("Get all addresses").Unwrap())
+				r.With(can(auth.WriteAddresses), idempotent).Post("/bulk", respec.Handler(bulkHandler.BulkAddresses).Summary("Create, update and delete addresses in bulk").Unwrap())
+				r.With(can(auth.WriteAddresses)).Get("/trash", respec.Handler(addressHandler.GetDeletedAddresses).Summary("Get the deleted addresses").Unwrap())
+				r.With(can(auth.WriteAddresses)).Post("/trash/{id}/restore", respec.Handler(addressHandler.RestoreAddress).Summary("Restore a deleted address").Unwrap())
+				r.With(can(auth.WriteAddresses), recentMFA).Delete("/trash/{id}", respec.Handler(addressHandler.PurgeAddress).Summary("Permanently delete an address").Unwrap())
+				r.With(can(auth.ReadAddresses)).Get("/{id}", respec.Handler(addressHandler.GetAddress).Summary("Get a single address").Unwrap())
+				r.With(can(auth.WriteAddresses)).Put("/{id}", respec.Handler(addressHandler.UpdateAddress).Summary("Update an address").Unwrap())
+				r.With(can(auth.WriteAddresses)).Patch("/{id}", respec.Handler(addressHandler.PatchAddress).Summary("Partially update an address").Unwrap())
+				r.With(can(auth.WriteAddresses), recentMFA).Delete("/{id}", respec.Handler(addressHandler.DeleteAddress).Summary("Delete an address").Unwrap())
+			})
+
+			// User routes
+			r.Route("/users", func(r chi.Router) {
+				respec.Meta(r).Tag("Users")
+				r.With(can(auth.WriteUsers), idempotent).Post("/", respec.Handler(userHandler.CreateUser).Summary("Create a new user").Unwrap())
+				r.With(can(auth.ReadUsers)).Get("/", respec.Handler(userHandler.GetAllUsers).Summary("Get all users").Unwrap())
+				r.With(can(auth.WriteUsers), idempotent).Post("/bulk", respec.Handler(bulkHandler.BulkUsers).Summary("Create, update and delete users in bulk").Unwrap())
+				r.With(can(auth.WriteUsers)).Get("/trash", respec.Handler(userHandler.GetDeletedUsers).Summary("Get the deleted users").Unwrap())
+				r.With(can(auth.WriteUsers)).Post("/trash/{id}/restore", respec.Handler(userHandler.RestoreUser).Summary("Restore a deleted user").Unwrap())
+				r.With(can(auth.WriteUsers), recentMFA).Delete("/trash/{id}", respec.Handler(userHandler.PurgeUser).Summary("Permanently delete a user").Unwrap())
+				r.With(can(auth.ReadUsers)).Get("/{id}", respec.Handler(userHandler.GetUser).Summary("Get a single user").Unwrap())
+				r.With(can(auth.WriteUsers)).Put("/{id}", respec.Handler(userHandler.UpdateUser).Summary("Update a user").Unwrap())
+				r.With(can(auth.WriteUsers)).Patch("/{id}", respec.Handler(userHandler.PatchUser).Summary("Partially update a user").Unwrap())
+				r.With(can(auth.WriteUsers), recentMFA).Delete("/{id}", respec.Handler(userHandler.DeleteUser).Summary("Delte a user").Unwrap())
+				r.Put("/{id}/password", respec.Handler(authHandler.SetPassword).Summary("Set the password of a user").Unwrap())
+			})
+
+			// Contract routes
+			r.Route("/contracts", func(r chi.Router) {
+				respec.Meta(r).Tag("Contracts")
+				r.With(can(auth.WriteContracts), idempotent).Post("/", respec.Handler(contractHandler.CreateContract).Summary("Create a new contract").Unwrap())
+				r.With(can(auth.ReadContracts)).Get("/", respec.Handler(contractHandler.GetAllContracts).Summary("Get all contracts").Unwrap()) // Supports ?tenantId=uuid
+				r.With(can(auth.WriteContracts), idempotent).Post("/bulk", respec.Handler(bulkHandler.BulkContracts).Summary("Create, update and delete contracts in bulk").Unwrap())
+				r.With(can(auth.WriteContracts)).Get("/trash", respec.Handler(contractHandler.GetDeletedContracts).Summary("Get the deleted contracts").Unwrap())
+				r.With(can(auth.WriteContracts)).Post("/trash/{id}/restore", respec.Handler(contractHandler.RestoreContract).Summary("Restore a deleted contract").Unwrap())
+				r.With(can(auth.WriteContracts), recentMFA).Delete("/trash/{id}", respec.Handler(contractHandler.PurgeContract).Summary("Permanently delete a contract and its versions").Unwrap())
+				r.With(can(auth.ReadContracts)).Get("/{id}", respec.Handler(contractHandler.GetContract).Summary("Get a single contract").Unwrap())
+				r.With(can(auth.WriteContracts)).Put("/{id}", respec.Handler(contractHandler.UpdateContract).Summary("Update a contract").Unwrap())
+				r.With(can(auth.WriteContracts)).Patch("/{id}", respec.Handler(contractHandler.PatchContract).Summary("Partially update a contract").Unwrap())
+				r.With(can(auth.WriteContracts), recentMFA).Delete("/{id}", respec.Handler(contractHandler.DeleteContract).Summary("Delte a contract").Unwrap())
+
+				// Contract version routes
+				r.With(can(auth.WriteContracts), idempotent).Post("/versions", respec.Handler(contractHandler.CreateContractVersion).Summary("Create a new contract version").Unwrap())
+				r.With(can(auth.ReadContracts)).Get("/{id}/versions", respec.Handler(contractHandler.GetContractVersions).Summary("Get all versions for a contract").Unwrap())
+				r.With(can(auth.WriteContracts)).Patch("/{id}/versions/{versionId}", respec.Handler(contractHandler.PatchContractVersion).Summary("Partially update a contract version").Unwrap())
+
+				// Payment routes
+				r.With(can(auth.WriteContracts), idempotent).Post("/{id}/payments", respec.Handler(paymentHandler.CreatePayment).Summary("Record a payment towards a contract").Unwrap())
+				r.With(can(auth.ReadContracts)).Get("/{id}/payments", respec.Handler(paymentHandler.GetPayments).Summary("Get the payments of a contract").Unwrap())
+				r.With(can(auth.WriteContracts), recentMFA).Delete("/{id}/payments/{paymentId}", respec.Handler(paymentHandler.DeletePayment).Summary("Delete a payment").Unwrap())
+				r.With(can(auth.ReadContracts)).Get("/{id}/charges", respec.Handler(paymentHandler.GetCharges).Summary("Get the rent charges of a contract and what is owed").Unwrap())
+
+				// Contract document routes
+				r.With(can(auth.ReadDocuments)).Get("/{id}/document", respec.Handler(contractHandler.GetContractDocument).Summary("Get the document for a contract").Unwrap())
+			})
+
+			// Reference confirmation routes
+			r.Route("/confirmations", func(r chi.Router) {
+				respec.Meta(r).Tag("Contracts")
+				r.With(can(auth.ReadConfirmations)).Get("/", respec.Handler(confirmationHandler.GetConfirmationRequests).Summary("Get the requests to confirm a tenant sent to references").Unwrap())
+				r.With(can(auth.WriteConfirmations)).Put("/{contractId}", respec.Handler(confirmationHandler.RespondToConfirmation).Summary("Confirm or decline vouching for the tenant of a contract").Unwrap())
+			})
+
+			// Audit routes
+			r.Route("/audit", func(r chi.Router) {
+				respec.Meta(r).Tag("Audit")
+				r.Use(can(auth.ReadAudit))
+				r.Get("/", respec.Handler(auditHandler.GetAuditLogs).Summary("Search the audit log").Unwrap())
+				r.Get("/{entityType}/{id}", respec.Handler(auditHandler.GetEntityHistory).Summary("Get the change history of a record").Unwrap())
+			})
+
+			// Personal data rights routes
+			r.Route("/privacy", func(r chi.Router) {
+				respec.Meta(r).Tag("Privacy")
+				r.Use(can(auth.ManagePrivacy))
+				r.Post("/requests", respec.Handler(privacyHandler.CreateRequest).Summary("Record an ARCO request").Unwrap())
+				r.Get("/requests", respec.Handler(privacyHandler.GetRequests).Summary("Get the ARCO requests").Unwrap())
+				r.Post("/requests/{id}/resolve", respec.Handler(privacyHandler.ResolveRequest).Summary("Complete or reject an ARCO request").Unwrap())
+				r.With(recentMFA).Get("/users/{id}/export", respec.Handler(privacyHandler.ExportUserData).Summary("Export everything held about a user").Unwrap())
+				r.With(recentMFA).Post("/users/{id}/anonymize", respec.Handler(privacyHandler.AnonymizeUser).Summary("Anonymize a user after their retention period").Unwrap())
+			})
+
+			// Statistics routes
+			r.Route("/statistics", func(r chi.Router) {
+				respec.Meta(r).Tag("Statistics")
+				r.With(can(auth.ReadStatistics)).Get("/overall", respec.Handler(statisticsHandler.GetOverallStatistics).Summary("Get the overall statistics").Unwrap())
+				r.With(can(auth.ReadStatistics)).Get("/timeseries", respec.Handler(statisticsHandler.GetTimeSeries).Summary("Get statistics per month, quarter or year").Unwrap())
+				r.With(can(auth.ReadStatistics)).Get("/retention", respec.Handler(statisticsHandler.GetRetention).Summary("Get renewal, tenure, churn and vacancy statistics").Unwrap())
+			})
+
+			// Report routes
+			r.Route("/reports", func(r chi.Router) {
+				respec.Meta(r).Tag("Reports")
+				r.Use(can(auth.ReadReports))
+				// The names of every tenant and landlord are as sensitive as a
+				// personal data export, in whichever format
+				r.With(recentMFA).Get("/rent-roll", respec.Handler(reportHandler.GetRentRoll).Summary("Get the rent roll as JSON, CSV, XLSX or PDF").Unwrap())
+				r.Get("/lease-expirations", respec.Handler(reportHandler.GetLeaseExpirations).Summary("Get the contracts about to expire and their renewals").Unwrap())
+				r.Get("/cash-flow", respec.Handler(reportHandler.GetCashFlowForecast).Summary("Forecast the rent income of the coming months").Unwrap())
+				r.Get("/aging", respec.Handler(reportHandler.GetDelinquencyAging).Summary("Get the outstanding rent by days overdue").Unwrap())
+				r.Get("/rent-benchmarks", respec.Handler(reportHandler.GetRentBenchmarks).Summary("Compare rents against their neighborhood and city").Unwrap())
+				// So is the income of every landlord
+				r.With(recentMFA).Get("/tax-summary", respec.Handler(reportHandler.GetTaxSummary).Summary("Get the yearly income and taxes of each landlord as JSON, CSV or PDF").Unwrap())
+			})
+
+			// Expense routes
+			r.Route("/expenses", func(r chi.Router) {
+				respec.Meta(r).Tag("Accounting")
+				r.Use(can(auth.ManageAccounting))
+				r.With(idempotent).Post("/", respec.Handler(expenseHandler.CreateExpense).Summary("Record an expense paid on behalf of a landlord").Unwrap())
+				r.Get("/", respec.Handler(expenseHandler.GetExpenses).Summary("Get the expenses").Unwrap())
+				r.With(recentMFA).Delete("/{id}", respec.Handler(expenseHandler.DeleteExpense).Summary("Delete an expense").Unwrap())
+			})
 
-		// Statistics routes
-		r.Route("/statistics", func(r chi.Router) {
-			respec.Meta(r).Tag("Statistics")
-			r.Get("/overall", respec.Handler(statisticsHandler.GetOverallStatistics).Summary("Get the overall statistics").Unwrap())
+			// Owner statement routes
+			r.Route("/statements", func(r chi.Router) {
+				respec.Meta(r).Tag("Accounting")
+				r.Use(can(auth.ManageAccounting))
+				r.With(idempotent).Post("/", respec.Handler(statementHandler.CreateOwnerStatement).Summary("Generate and archive the monthly statement of a landlord").Unwrap())
+				r.Get("/", respec.Handler(statementHandler.GetOwnerStatements).Summary("Get the archived owner statements").Unwrap())
+				r.With(recentMFA).Get("/{id}", respec.Handler(statementHandler.DownloadOwnerStatement).Summary("Download an archived owner statement").Unwrap())
+			})
 		})
 	})
 
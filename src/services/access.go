@@ -0,0 +1,40 @@
+package services
+
+import (
+	"context"
+
+	"github.com/edfloreshz/rent-contracts/src/auth"
+	"gorm.io/gorm"
+)
+
+// contractAccessScope restricts contract queries to the contracts the caller may see.
+// Tenants only see the contracts they rent and callers without a principal see nothing.
+func contractAccessScope(ctx context.Context) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		principal, ok := auth.PrincipalFromContext(ctx)
+		switch {
+		case !ok:
+			return db.Where("1 = 0")
+		case principal.SeesEverything():
+			return db
+		default:
+			return db.Where("contracts.tenantid = ?", principal.UserID)
+		}
+	}
+}
+
+// userAccessScope restricts user queries to the users the caller may see.
+// Non-admin users only see themselves and callers without a principal see nothing.
+func userAccessScope(ctx context.Context) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		principal, ok := auth.PrincipalFromContext(ctx)
+		switch {
+		case !ok:
+			return db.Where("1 = 0")
+		case principal.SeesEverything():
+			return db
+		default:
+			return db.Where("users.id = ?", principal.UserID)
+		}
+	}
+}
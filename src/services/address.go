@@ -1,7 +1,9 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"time"
 
 	"github.com/edfloreshz/rent-contracts/src/dto"
 	"github.com/edfloreshz/rent-contracts/src/models"
@@ -20,13 +22,20 @@ func NewAddressService(db *gorm.DB) *AddressService {
 	}
 }
 
+// WithTx returns a copy of the service that runs its queries in the given transaction
+func (s *AddressService) WithTx(tx *gorm.DB) *AddressService {
+	return &AddressService{
+		tx,
+	}
+}
+
 type AddressServiceFilter struct {
 	Type      *models.AddressType
 	Available *bool
 	Limit     *int
 }
 
-func (s *AddressService) CreateAddress(req *dto.CreateAddressRequest) (*models.Address, error) {
+func (s *AddressService) CreateAddress(ctx context.Context, req *dto.CreateAddressRequest) (*models.Address, error) {
 	address := &models.Address{
 		Type:         models.AddressType(req.Type),
 		Street:       req.Street,
@@ -38,17 +47,37 @@ func (s *AddressService) CreateAddress(req *dto.CreateAddressRequest) (*models.A
 		Country:      req.Country,
 	}
 
-	if err := s.db.Create(address).Error; err != nil {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(address).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, address.OrganizationID, models.AuditCreate, AuditAddress, address.ID, nil, address)
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return address, nil
 }
 
-func (s *AddressService) GetAddressByID(id uuid.UUID) (*models.Address, error) {
+func (s *AddressService) GetAddressByID(ctx context.Context, id uuid.UUID) (*models.Address, error) {
+	var address models.Address
+
+	if err := s.db.WithContext(ctx).First(&address, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("address not found")
+		}
+		return nil, err
+	}
+
+	return &address, nil
+}
+
+// GetAddressAsOf returns an address as it was at asOf
+func (s *AddressService) GetAddressAsOf(ctx context.Context, id uuid.UUID, asOf time.Time) (*models.Address, error) {
 	var address models.Address
 
-	if err := s.db.First(&address, id).Error; err != nil {
+	if err := findAsOf(liveHistoryQuery(ctx, s.db, "addresses", asOf), id, &address); err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("address not found")
 		}
@@ -58,9 +87,9 @@ func (s *AddressService) GetAddressByID(id uuid.UUID) (*models.Address, error) {
 	return &address, nil
 }
 
-func (s *AddressService) GetAllAddresses(filter *AddressServiceFilter) ([]models.Address, error) {
+func (s *AddressService) GetAllAddresses(ctx context.Context, filter *AddressServiceFilter) ([]models.Address, error) {
 	var addresses []models.Address
-	query := s.db
+	query := s.db.WithContext(ctx)
 
 	if filter != nil {
 		if filter.Type != nil {
@@ -81,15 +110,20 @@ func (s *AddressService) GetAllAddresses(filter *AddressServiceFilter) ([]models
 	return addresses, nil
 }
 
-func (s *AddressService) UpdateAddress(id uuid.UUID, req *dto.UpdateAddressRequest) (*models.Address, error) {
+func (s *AddressService) UpdateAddress(ctx context.Context, id uuid.UUID, req *dto.UpdateAddressRequest, expectedVersions []int) (*models.Address, error) {
 	var address models.Address
-	if err := s.db.First(&address, id).Error; err != nil {
+	if err := s.db.WithContext(ctx).First(&address, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("address not found")
 		}
 		return nil, err
 	}
 
+	if err := checkVersion(address.Version, expectedVersions); err != nil {
+		return nil, err
+	}
+	before := address
+
 	// Update only provided fields
 	if req.Type != nil {
 		address.Type = models.AddressType(*req.Type)
@@ -116,16 +150,132 @@ func (s *AddressService) UpdateAddress(id uuid.UUID, req *dto.UpdateAddressReque
 		address.Country = *req.Country
 	}
 
-	if err := s.db.Save(&address).Error; err != nil {
+	if err := s.saveAddress(ctx, &before, &address); err != nil {
+		return nil, err
+	}
+
+	return &address, nil
+}
+
+// PatchAddress applies a JSON merge patch to an address and returns the names of the changed fields
+func (s *AddressService) PatchAddress(ctx context.Context, id uuid.UUID, patch []byte, expectedVersions []int) (*models.Address, []string, error) {
+	var address models.Address
+	if err := s.db.WithContext(ctx).First(&address, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errors.New("address not found")
+		}
+		return nil, nil, err
+	}
+
+	if err := checkVersion(address.Version, expectedVersions); err != nil {
+		return nil, nil, err
+	}
+	before := address
+
+	document := dto.AddressPatch{
+		Type:         string(address.Type),
+		Street:       address.Street,
+		Number:       address.Number,
+		Neighborhood: address.Neighborhood,
+		City:         address.City,
+		State:        address.State,
+		ZipCode:      address.ZipCode,
+		Country:      address.Country,
+	}
+
+	changed, err := applyMergePatch(&document, patch)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(changed) == 0 {
+		return &address, changed, nil
+	}
+
+	address.Type = models.AddressType(document.Type)
+	address.Street = document.Street
+	address.Number = document.Number
+	address.Neighborhood = document.Neighborhood
+	address.City = document.City
+	address.State = document.State
+	address.ZipCode = document.ZipCode
+	address.Country = document.Country
+
+	if err := s.saveAddress(ctx, &before, &address); err != nil {
+		return nil, nil, err
+	}
+
+	return &address, changed, nil
+}
+
+// DeleteAddress deletes an address. Unless cascade is set it fails with a
+// DependencyError while users or contracts still use the address.
+func (s *AddressService) DeleteAddress(ctx context.Context, id uuid.UUID, cascade bool) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var address models.Address
+		if err := tx.First(&address, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("address not found")
+			}
+			return err
+		}
+
+		return deleteAddress(tx, &address, cascade)
+	})
+}
+
+// GetDeletedAddresses returns the addresses in the trash, most recently deleted first
+func (s *AddressService) GetDeletedAddresses(ctx context.Context) ([]models.Address, error) {
+	var addresses []models.Address
+	if err := s.db.WithContext(ctx).Unscoped().Where("deletedat IS NOT NULL").Order("deletedat DESC").Find(&addresses).Error; err != nil {
 		return nil, err
 	}
+	return addresses, nil
+}
 
+// RestoreAddress takes an address out of the trash
+func (s *AddressService) RestoreAddress(ctx context.Context, id uuid.UUID) (*models.Address, error) {
+	var address models.Address
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := findDeleted(tx, &address, id); err != nil {
+			return err
+		}
+
+		before := address
+		if err := restoreRecord(tx, &address, id); err != nil {
+			return err
+		}
+		return recordAudit(tx, address.OrganizationID, models.AuditRestore, AuditAddress, address.ID, &before, &address)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("address not found in trash")
+	}
+	if err != nil {
+		return nil, err
+	}
 	return &address, nil
 }
 
-func (s *AddressService) DeleteAddress(id uuid.UUID) error {
-	if err := s.db.Delete(&models.Address{}, id).Error; err != nil {
-		return err
+// PurgeAddress permanently removes an address from the trash
+func (s *AddressService) PurgeAddress(ctx context.Context, id uuid.UUID) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var address models.Address
+		if err := findDeleted(tx, &address, id); err != nil {
+			return err
+		}
+		return purgeAddress(tx, &address)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.New("address not found in trash")
 	}
-	return nil
+	return err
+}
+
+// saveAddress stores the changes made to an address and records them in the audit log
+func (s *AddressService) saveAddress(ctx context.Context, before, address *models.Address) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := saveVersioned(tx, address, &address.Version); err != nil {
+			return err
+		}
+		return recordAudit(tx, address.OrganizationID, models.AuditUpdate, AuditAddress, address.ID, before, address)
+	})
 }
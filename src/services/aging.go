@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/edfloreshz/rent-contracts/src/models"
+	"github.com/google/uuid"
+)
+
+// Aging report groupings
+const (
+	AgingByTenant   = "tenant"
+	AgingByProperty = "property"
+	AgingByLandlord = "landlord"
+)
+
+// rescissionThresholdDays is how late rent can be before the RESCISION clause of
+// the contract, which allows rescinding it after one to two months of late rent,
+// applies
+const rescissionThresholdDays = 30
+
+// ErrInvalidAgingGroup is returned when an aging report is asked for an unknown grouping
+var ErrInvalidAgingGroup = errors.New("invalid aging grouping")
+
+// AgingBuckets split outstanding rent by how many days overdue it is
+type AgingBuckets struct {
+	Current    float64 `json:"current"`
+	Days1To30  float64 `json:"days1To30"`
+	Days31To60 float64 `json:"days31To60"`
+	Days61To90 float64 `json:"days61To90"`
+	Over90     float64 `json:"over90"`
+	Total      float64 `json:"total"`
+}
+
+// DelinquencyAging is the outstanding rent of every tenant, property or landlord
+// that owes any
+type DelinquencyAging struct {
+	GeneratedAt time.Time     `json:"generatedAt"`
+	GroupBy     string        `json:"groupBy"`
+	Totals      AgingBuckets  `json:"totals"`
+	Groups      []AgingGroup  `json:"groups"`
+	Rescindable []Rescindable `json:"rescindable"`
+}
+
+// AgingGroup is the outstanding rent of a tenant, property or landlord and the
+// charges it is made of
+type AgingGroup struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	AgingBuckets
+	Charges []Charge `json:"charges"`
+}
+
+// Rescindable is a contract still in force whose rent is late enough for the
+// RESCISION clause
+type Rescindable struct {
+	ContractID     uuid.UUID `json:"contractId"`
+	TenantID       uuid.UUID `json:"tenantId"`
+	Tenant         string    `json:"tenant"`
+	PropertyID     uuid.UUID `json:"propertyId"`
+	Property       string    `json:"property"`
+	OverdueCharges int       `json:"overdueCharges"`
+	Outstanding    float64   `json:"outstanding"`
+	OldestDueDate  time.Time `json:"oldestDueDate"`
+	DaysOverdue    int       `json:"daysOverdue"`
+}
+
+// add counts the outstanding amount of a charge in its bucket
+func (b *AgingBuckets) add(charge Charge) {
+	switch {
+	case charge.DaysOverdue == 0:
+		b.Current += charge.Outstanding
+	case charge.DaysOverdue <= 30:
+		b.Days1To30 += charge.Outstanding
+	case charge.DaysOverdue <= 60:
+		b.Days31To60 += charge.Outstanding
+	case charge.DaysOverdue <= 90:
+		b.Days61To90 += charge.Outstanding
+	default:
+		b.Over90 += charge.Outstanding
+	}
+	b.Total += charge.Outstanding
+}
+
+// GetDelinquencyAging buckets the outstanding charges of the contracts that are
+// not deleted per tenant, property or landlord, the largest debt first
+func (s *ReportService) GetDelinquencyAging(ctx context.Context, groupBy string) (*DelinquencyAging, error) {
+	if groupBy != AgingByTenant && groupBy != AgingByProperty && groupBy != AgingByLandlord {
+		return nil, fmt.Errorf("%w: invalid groupBy %q", ErrInvalidAgingGroup, groupBy)
+	}
+
+	db := s.db.WithContext(ctx)
+	var contracts []models.Contract
+	if err := db.Preload("Tenant").Preload("Landlord").Preload("Address").Preload("CurrentVersion").Find(&contracts).Error; err != nil {
+		return nil, err
+	}
+
+	contractIDs := make([]uuid.UUID, len(contracts))
+	for i, contract := range contracts {
+		contractIDs[i] = contract.ID
+	}
+	now := time.Now()
+	ledgers, err := contractLedgers(db, contractIDs, now)
+	if err != nil {
+		return nil, err
+	}
+
+	aging := &DelinquencyAging{GeneratedAt: now, GroupBy: groupBy, Groups: []AgingGroup{}, Rescindable: []Rescindable{}}
+	groups := map[uuid.UUID]*AgingGroup{}
+	for _, contract := range contracts {
+		var group *AgingGroup
+		var rescindable *Rescindable
+		for _, charge := range ledgers[contract.ID] {
+			if charge.Outstanding <= 0 {
+				continue
+			}
+
+			if group == nil {
+				group = agingGroup(groups, groupBy, contract)
+			}
+			group.add(charge)
+			group.Charges = append(group.Charges, charge)
+			aging.Totals.add(charge)
+
+			if charge.DaysOverdue == 0 {
+				continue
+			}
+			if rescindable == nil {
+				rescindable = &Rescindable{
+					ContractID:    contract.ID,
+					TenantID:      contract.TenantID,
+					Tenant:        contract.Tenant.FullName(),
+					PropertyID:    contract.AddressID,
+					Property:      contract.Address.FullAddress(),
+					OldestDueDate: charge.DueDate,
+					DaysOverdue:   charge.DaysOverdue,
+				}
+			}
+			rescindable.OverdueCharges++
+			rescindable.Outstanding += charge.Outstanding
+		}
+
+		// A terminated contract has nothing left to rescind, only a debt to collect
+		terminated := contract.CurrentVersion != nil && contract.CurrentVersion.Status == models.TerminatedContract
+		if rescindable != nil && rescindable.DaysOverdue > rescissionThresholdDays && !terminated {
+			aging.Rescindable = append(aging.Rescindable, *rescindable)
+		}
+	}
+
+	for _, group := range groups {
+		sort.Slice(group.Charges, func(i, j int) bool { return group.Charges[i].DueDate.Before(group.Charges[j].DueDate) })
+		aging.Groups = append(aging.Groups, *group)
+	}
+	sort.Slice(aging.Groups, func(i, j int) bool {
+		if aging.Groups[i].Total != aging.Groups[j].Total {
+			return aging.Groups[i].Total > aging.Groups[j].Total
+		}
+		return aging.Groups[i].Name < aging.Groups[j].Name
+	})
+	sort.Slice(aging.Rescindable, func(i, j int) bool { return aging.Rescindable[i].DaysOverdue > aging.Rescindable[j].DaysOverdue })
+
+	return aging, nil
+}
+
+// agingGroup returns the group a contract falls in, creating it on first use
+func agingGroup(groups map[uuid.UUID]*AgingGroup, groupBy string, contract models.Contract) *AgingGroup {
+	id, name := contract.TenantID, contract.Tenant.FullName()
+	switch groupBy {
+	case AgingByProperty:
+		id, name = contract.AddressID, contract.Address.FullAddress()
+	case AgingByLandlord:
+		id, name = contract.LandlordID, contract.Landlord.FullName()
+	}
+
+	if groups[id] == nil {
+		groups[id] = &AgingGroup{ID: id, Name: name}
+	}
+	return groups[id]
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/edfloreshz/rent-contracts/src/auth"
+	"github.com/edfloreshz/rent-contracts/src/dto"
+	"github.com/edfloreshz/rent-contracts/src/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// APIKeyPrefix starts every API key so it can be told apart from a JWT
+const APIKeyPrefix = "rk_"
+
+const (
+	// apiKeySecretBytes is the amount of randomness in a key
+	apiKeySecretBytes = 32
+
+	// apiKeyDisplayLength is how many characters of a key are kept to identify it in listings
+	apiKeyDisplayLength = len(APIKeyPrefix) + 8
+
+	// apiKeyLastUsedInterval limits how often the last use of a key is written
+	apiKeyLastUsedInterval = time.Minute
+
+	// readOnlyScope grants every read permission of the creator
+	readOnlyScope = "read"
+)
+
+// ErrAPIKeyIPNotAllowed is returned when a key is used from an address outside its allowlist
+var ErrAPIKeyIPNotAllowed = errors.New("API key is not allowed from this address")
+
+type APIKeyService struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyService(db *gorm.DB) *APIKeyService {
+	return &APIKeyService{
+		db: db,
+	}
+}
+
+// CreateAPIKey issues a new key on behalf of the caller and returns it with its
+// plaintext value, which is not stored and cannot be retrieved again
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, req *dto.CreateAPIKeyRequest) (*models.APIKey, string, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok || principal.System || principal.APIKeyID != nil {
+		return nil, "", errors.New("API keys can only be created by users")
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, "", errors.New("name is required")
+	}
+
+	scopes, err := resolveScopes(principal, req.Scopes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	allowedIPs := models.StringList{}
+	for _, entry := range req.AllowedIPs {
+		prefix, err := parseIPPrefix(entry)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid allowed IP %q", entry)
+		}
+		allowedIPs = append(allowedIPs, prefix.String())
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, "", errors.New("expiresAt must be in the future")
+	}
+
+	secret := make([]byte, apiKeySecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", err
+	}
+	key := APIKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	apiKey := &models.APIKey{
+		UserID:     principal.UserID,
+		Name:       name,
+		Prefix:     key[:apiKeyDisplayLength],
+		KeyHash:    hashAPIKey(key),
+		Scopes:     scopes,
+		AllowedIPs: allowedIPs,
+		ExpiresAt:  req.ExpiresAt,
+	}
+
+	if err := s.db.WithContext(ctx).Create(apiKey).Error; err != nil {
+		return nil, "", err
+	}
+
+	return apiKey, key, nil
+}
+
+func (s *APIKeyService) GetAllAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	var apiKeys []models.APIKey
+	if err := s.db.WithContext(ctx).Order("createdat DESC").Find(&apiKeys).Error; err != nil {
+		return nil, err
+	}
+	return apiKeys, nil
+}
+
+// RevokeAPIKey stops a key from authenticating any further requests
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, id uuid.UUID) error {
+	var apiKey models.APIKey
+	if err := s.db.WithContext(ctx).First(&apiKey, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("API key not found")
+		}
+		return err
+	}
+
+	if apiKey.RevokedAt != nil {
+		return nil
+	}
+
+	return s.db.WithContext(ctx).Model(&apiKey).Update("revokedat", time.Now()).Error
+}
+
+// Authenticate validates an API key presented from the given address and returns
+// the principal it acts as
+func (s *APIKeyService) Authenticate(key string, remoteIP netip.Addr) (*auth.Principal, error) {
+	// The key is looked up across organizations since the caller is not known yet
+	ctx := auth.WithPrincipal(context.Background(), auth.System)
+
+	var apiKey models.APIKey
+	if err := s.db.WithContext(ctx).Joins("User").
+		First(&apiKey, "apikeys.keyhash = ?", hashAPIKey(key)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if !apiKey.Active(now) || apiKey.User.ID == uuid.Nil {
+		return nil, ErrInvalidToken
+	}
+
+	if !ipAllowed(apiKey.AllowedIPs, remoteIP) {
+		return nil, ErrAPIKeyIPNotAllowed
+	}
+
+	if err := s.db.WithContext(ctx).Model(&models.APIKey{}).
+		Where("id = ? AND (lastusedat IS NULL OR lastusedat < ?)", apiKey.ID, now.Add(-apiKeyLastUsedInterval)).
+		Update("lastusedat", now).Error; err != nil {
+		return nil, err
+	}
+
+	scopes := make([]auth.Permission, len(apiKey.Scopes))
+	for i, scope := range apiKey.Scopes {
+		scopes[i] = auth.Permission(scope)
+	}
+
+	return &auth.Principal{
+		UserID:         apiKey.UserID,
+		OrganizationID: apiKey.OrganizationID,
+		Type:           apiKey.User.Type,
+		APIKeyID:       &apiKey.ID,
+		Scopes:         scopes,
+	}, nil
+}
+
+// resolveScopes validates the requested scopes against the permissions of the
+// creator, so a key can never do more than the user who created it
+func resolveScopes(principal *auth.Principal, requested []string) (models.StringList, error) {
+	if len(requested) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+
+	seen := map[auth.Permission]bool{}
+	scopes := models.StringList{}
+	add := func(permission auth.Permission) {
+		if !seen[permission] {
+			seen[permission] = true
+			scopes = append(scopes, string(permission))
+		}
+	}
+
+	for _, scope := range requested {
+		if scope == readOnlyScope {
+			for _, permission := range auth.Permissions(principal.Type) {
+				if permission.ReadOnly() {
+					add(permission)
+				}
+			}
+			continue
+		}
+
+		permission := auth.Permission(scope)
+		if permission == auth.ManageAPIKeys || !principal.Can(permission) {
+			return nil, fmt.Errorf("scope %q cannot be granted", scope)
+		}
+		add(permission)
+	}
+
+	return scopes, nil
+}
+
+// parseIPPrefix parses a single address or a CIDR range
+func parseIPPrefix(value string) (netip.Prefix, error) {
+	value = strings.TrimSpace(value)
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		return prefix.Masked(), err
+	}
+
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// ipAllowed reports whether the address is in the allowlist. An empty allowlist allows every address.
+func ipAllowed(allowedIPs []string, remoteIP netip.Addr) bool {
+	if len(allowedIPs) == 0 {
+		return true
+	}
+
+	remoteIP = remoteIP.Unmap()
+	for _, entry := range allowedIPs {
+		prefix, err := netip.ParsePrefix(entry)
+		if err == nil && prefix.Contains(remoteIP) {
+			return true
+		}
+	}
+	return false
+}
+
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,225 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/edfloreshz/rent-contracts/src/auth"
+	"github.com/edfloreshz/rent-contracts/src/models"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Entity types recorded in the audit log
+const (
+	AuditAddress         = "address"
+	AuditUser            = "user"
+	AuditContract        = "contract"
+	AuditContractVersion = "contractVersion"
+	AuditPayment         = "payment"
+	AuditExpense         = "expense"
+	AuditConfirmation    = "confirmation"
+)
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 500
+)
+
+// auditIgnoredFields change on every write and only add noise to the audit log
+var auditIgnoredFields = map[string]bool{
+	"version":   true,
+	"updatedAt": true,
+}
+
+type AuditFilter struct {
+	EntityType *string
+	EntityID   *uuid.UUID
+	ActorID    *uuid.UUID
+	Action     *models.AuditAction
+	From       *time.Time
+	To         *time.Time
+	Limit      *int
+	Offset     *int
+}
+
+type AuditService struct {
+	db *gorm.DB
+}
+
+func NewAuditService(db *gorm.DB) *AuditService {
+	return &AuditService{
+		db: db,
+	}
+}
+
+// GetAuditLogs returns the audit entries matching the filter, newest first
+func (s *AuditService) GetAuditLogs(ctx context.Context, filter *AuditFilter) ([]models.AuditLog, error) {
+	query := s.db.WithContext(ctx).Model(&models.AuditLog{})
+
+	if filter.EntityType != nil {
+		query = query.Where("entitytype = ?", *filter.EntityType)
+	}
+	if filter.EntityID != nil {
+		query = query.Where("entityid = ?", *filter.EntityID)
+	}
+	if filter.ActorID != nil {
+		query = query.Where("actorid = ?", *filter.ActorID)
+	}
+	if filter.Action != nil {
+		query = query.Where("action = ?", *filter.Action)
+	}
+	if filter.From != nil {
+		query = query.Where("createdat >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("createdat < ?", *filter.To)
+	}
+
+	limit := defaultAuditLimit
+	if filter.Limit != nil && *filter.Limit > 0 {
+		limit = min(*filter.Limit, maxAuditLimit)
+	}
+	query = query.Limit(limit)
+	if filter.Offset != nil && *filter.Offset > 0 {
+		query = query.Offset(*filter.Offset)
+	}
+
+	var logs []models.AuditLog
+	if err := query.Order("createdat DESC, id").Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// GetEntityHistory returns every audit entry of a single record, oldest first
+func (s *AuditService) GetEntityHistory(ctx context.Context, entityType string, entityID uuid.UUID) ([]models.AuditLog, error) {
+	var logs []models.AuditLog
+	if err := s.db.WithContext(ctx).
+		Where("entitytype = ? AND entityid = ?", entityType, entityID).
+		Order("createdat, id").
+		Find(&logs).Error; err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// recordAudit writes an audit entry for a change to a record. before is nil for
+// creations and after is nil for deletions. It must run in the same transaction
+// as the change so that both are committed together.
+func recordAudit(tx *gorm.DB, organizationID uuid.UUID, action models.AuditAction, entityType string, entityID uuid.UUID, before, after interface{}) error {
+	changes, err := auditDiff(before, after)
+	if err != nil {
+		return err
+	}
+	if action == models.AuditUpdate && len(changes) == 0 {
+		return nil
+	}
+
+	entry := &models.AuditLog{
+		OrganizationID: organizationID,
+		Action:         action,
+		EntityType:     entityType,
+		EntityID:       entityID,
+		Changes:        changes,
+	}
+
+	ctx := tx.Statement.Context
+	if principal, ok := auth.PrincipalFromContext(ctx); ok && !principal.System {
+		entry.ActorID = &principal.UserID
+		entry.APIKeyID = principal.APIKeyID
+	}
+	if requestID := middleware.GetReqID(ctx); requestID != "" {
+		entry.RequestID = &requestID
+	}
+
+	return tx.Create(entry).Error
+}
+
+// auditDiff compares the fields of two snapshots of a record
+func auditDiff(before, after interface{}) (models.AuditChanges, error) {
+	beforeFields, err := auditSnapshot(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := auditSnapshot(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := models.AuditChanges{}
+	for field, value := range beforeFields {
+		if !reflect.DeepEqual(value, afterFields[field]) {
+			changes[field] = models.AuditChange{Before: value, After: afterFields[field]}
+		}
+	}
+	for field, value := range afterFields {
+		if _, ok := beforeFields[field]; !ok && value != nil {
+			changes[field] = models.AuditChange{Before: nil, After: value}
+		}
+	}
+	return changes, nil
+}
+
+// auditSnapshot returns the fields of a record as they are serialized to JSON.
+// Relationships are left out since they are audited on their own, but lists of
+// plain values such as the reference IDs of a contract are kept.
+func auditSnapshot(record interface{}) (map[string]interface{}, error) {
+	if record == nil || reflect.ValueOf(record).IsNil() {
+		return map[string]interface{}{}, nil
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+
+	for field, value := range fields {
+		if auditIgnoredFields[field] || isRelationship(value) {
+			delete(fields, field)
+		}
+	}
+	return fields, nil
+}
+
+// isRelationship reports whether a JSON value is a nested record or a list of records.
+// Empty lists are kept, since clearing a list of plain values is a change to audit.
+func isRelationship(value interface{}) bool {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return true
+	case []interface{}:
+		for _, item := range v {
+			if _, ok := item.(map[string]interface{}); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ValidAuditEntity reports whether the entity type is recorded in the audit log
+func ValidAuditEntity(entityType string) bool {
+	switch entityType {
+	case AuditAddress, AuditUser, AuditContract, AuditContractVersion, AuditPayment, AuditExpense, AuditConfirmation:
+		return true
+	}
+	return false
+}
+
+// ValidAuditAction reports whether the action is recorded in the audit log
+func ValidAuditAction(action models.AuditAction) bool {
+	switch action {
+	case models.AuditCreate, models.AuditUpdate, models.AuditDelete, models.AuditRestore, models.AuditPurge, models.AuditAnonymize:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAuditDiff(t *testing.T) {
+	type record struct {
+		Name         string                 `json:"name"`
+		ReferenceIDs []string               `json:"referenceIds"`
+		Tenant       map[string]interface{} `json:"tenant"`
+		Versions     []map[string]string    `json:"versions"`
+	}
+
+	before := &record{
+		Name:         "Contrato",
+		ReferenceIDs: []string{"a", "b"},
+		Tenant:       map[string]interface{}{"name": "Ana"},
+		Versions:     []map[string]string{{"rent": "1000"}},
+	}
+	after := &record{
+		Name:         "Contrato",
+		ReferenceIDs: []string{},
+		Tenant:       map[string]interface{}{"name": "Luis"},
+		Versions:     []map[string]string{{"rent": "2000"}},
+	}
+
+	changes, err := auditDiff(before, after)
+	if err != nil {
+		t.Fatalf("diff: %v", err)
+	}
+
+	if len(changes) != 1 {
+		t.Fatalf("got changes %v, want only referenceIds", changes)
+	}
+	change, ok := changes["referenceIds"]
+	if !ok {
+		t.Fatalf("got changes %v, want referenceIds", changes)
+	}
+	if !reflect.DeepEqual(change.Before, []interface{}{"a", "b"}) || !reflect.DeepEqual(change.After, []interface{}{}) {
+		t.Errorf("got referenceIds %v -> %v, want [a b] -> []", change.Before, change.After)
+	}
+}
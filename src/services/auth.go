@@ -0,0 +1,320 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/edfloreshz/rent-contracts/src/auth"
+	"github.com/edfloreshz/rent-contracts/src/dto"
+	"github.com/edfloreshz/rent-contracts/src/models"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Token types carried in the token_type claim
+const (
+	accessTokenType  = "access"
+	refreshTokenType = "refresh"
+	mfaTokenType     = "mfa"
+)
+
+// minPasswordLength is the shortest password accepted for an account
+const minPasswordLength = 8
+
+var (
+	// ErrInvalidCredentials is returned when the email or password is wrong
+	ErrInvalidCredentials = errors.New("invalid email or password")
+
+	// ErrInvalidToken is returned when a token is malformed, expired or revoked
+	ErrInvalidToken = errors.New("invalid or expired token")
+)
+
+// dummyPasswordHash is compared against when the user does not exist so that
+// failed logins take the same time whether or not the email is registered
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	SessionID string `json:"sid"`
+	TokenType string `json:"token_type"`
+}
+
+type AuthService struct {
+	db              *gorm.DB
+	secret          []byte
+	accessTokenTTL  time.Duration
+	refreshTokenTTL time.Duration
+}
+
+func NewAuthService(db *gorm.DB, secret []byte, accessTokenTTL, refreshTokenTTL time.Duration) *AuthService {
+	return &AuthService{
+		db:              db,
+		secret:          secret,
+		accessTokenTTL:  accessTokenTTL,
+		refreshTokenTTL: refreshTokenTTL,
+	}
+}
+
+// SetPassword stores the password for a user, replacing any previous one
+func (s *AuthService) SetPassword(ctx context.Context, userID uuid.UUID, password string) error {
+	if len(password) < minPasswordLength {
+		return fmt.Errorf("password must be at least %d characters long", minPasswordLength)
+	}
+
+	var user models.User
+	if err := s.db.WithContext(ctx).First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("user not found")
+		}
+		return err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	credential := &models.UserCredential{
+		UserID:       user.ID,
+		PasswordHash: string(hash),
+	}
+
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "userid"}},
+		DoUpdates: clause.AssignmentColumns([]string{"passwordhash"}),
+	}).Create(credential).Error
+}
+
+// BootstrapAdmin sets the password of the admin with the given email when it has no credentials yet
+func (s *AuthService) BootstrapAdmin(email, password string) error {
+	// The admin is looked up across organizations since there is no caller yet
+	ctx := auth.WithPrincipal(context.Background(), auth.System)
+
+	var user models.User
+	if err := s.db.WithContext(ctx).Where("email = ? AND type = ?", email, models.AdminUser).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("admin user %s not found", email)
+		}
+		return err
+	}
+
+	var count int64
+	if err := s.db.Model(&models.UserCredential{}).Where("userid = ?", user.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	return s.SetPassword(ctx, user.ID, password)
+}
+
+// Login verifies the credentials of a user and starts a new session. Users who
+// enrolled in two-factor authentication get a challenge instead, which is
+// exchanged for tokens with VerifyLogin.
+func (s *AuthService) Login(req *dto.LoginRequest) (*dto.TokenResponse, *dto.MFAChallengeResponse, error) {
+	var credential models.UserCredential
+	err := s.db.
+		Joins("User").
+		Where(`"User".email = ?`, strings.TrimSpace(req.Email)).
+		First(&credential).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, err
+	}
+
+	hash := dummyPasswordHash
+	if err == nil {
+		hash = []byte(credential.PasswordHash)
+	}
+
+	if bcrypt.CompareHashAndPassword(hash, []byte(req.Password)) != nil || err != nil {
+		return nil, nil, ErrInvalidCredentials
+	}
+
+	if credential.TOTPEnabled() {
+		challenge, err := s.issueMFAChallenge(credential.UserID)
+		return nil, challenge, err
+	}
+
+	tokens, err := s.startSession(s.db, credential.UserID, nil, nil)
+	return tokens, nil, err
+}
+
+// startSession creates a session for the user and issues its first token pair. A
+// session started by answering a login challenge records it, so the challenge
+// cannot be answered again.
+func (s *AuthService) startSession(db *gorm.DB, userID uuid.UUID, mfaVerifiedAt *time.Time, mfaChallengeID *uuid.UUID) (*dto.TokenResponse, error) {
+	session := &models.Session{
+		UserID:         userID,
+		RefreshTokenID: uuid.New(),
+		ExpiresAt:      time.Now().Add(s.refreshTokenTTL),
+		MFAVerifiedAt:  mfaVerifiedAt,
+		MFAChallengeID: mfaChallengeID,
+	}
+	if err := db.Create(session).Error; err != nil {
+		return nil, err
+	}
+
+	return s.issueTokens(session)
+}
+
+// Refresh exchanges a refresh token for a new token pair. Refresh tokens are
+// single use: presenting one that was already exchanged revokes the session.
+func (s *AuthService) Refresh(refreshToken string) (*dto.TokenResponse, error) {
+	claims, err := s.parseToken(refreshToken, refreshTokenType)
+	if err != nil {
+		return nil, err
+	}
+
+	var response *dto.TokenResponse
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var session models.Session
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&session, "id = ?", claims.SessionID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidToken
+			}
+			return err
+		}
+
+		if !session.Active(time.Now()) {
+			return ErrInvalidToken
+		}
+
+		if session.RefreshTokenID.String() != claims.ID {
+			// The token was already used, so it may have been stolen
+			now := time.Now()
+			if err := tx.Model(&session).Update("revokedat", now).Error; err != nil {
+				return err
+			}
+			return nil
+		}
+
+		session.RefreshTokenID = uuid.New()
+		if err := tx.Model(&session).Update("refreshtokenid", session.RefreshTokenID).Error; err != nil {
+			return err
+		}
+
+		response, err = s.issueTokens(&session)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if response == nil {
+		return nil, ErrInvalidToken
+	}
+
+	return response, nil
+}
+
+// Logout revokes the session so its access and refresh tokens stop working
+func (s *AuthService) Logout(sessionID uuid.UUID) error {
+	return s.db.Model(&models.Session{}).
+		Where("id = ? AND revokedat IS NULL", sessionID).
+		Update("revokedat", time.Now()).Error
+}
+
+// Authenticate validates an access token and returns the principal it belongs to
+func (s *AuthService) Authenticate(accessToken string) (*auth.Principal, error) {
+	claims, err := s.parseToken(accessToken, accessTokenType)
+	if err != nil {
+		return nil, err
+	}
+
+	var session models.Session
+	if err := s.db.Joins("User").First(&session, "sessions.id = ?", claims.SessionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	if !session.Active(time.Now()) || session.User.ID == uuid.Nil {
+		return nil, ErrInvalidToken
+	}
+
+	enrolled, err := exists(s.db.Model(&models.UserCredential{}).Where("userid = ? AND totpenabledat IS NOT NULL", session.UserID))
+	if err != nil {
+		return nil, err
+	}
+
+	return &auth.Principal{
+		UserID:         session.UserID,
+		SessionID:      session.ID,
+		OrganizationID: session.User.OrganizationID,
+		Type:           session.User.Type,
+		MFAVerifiedAt:  session.MFAVerifiedAt,
+		MFAEnrolled:    enrolled,
+	}, nil
+}
+
+// issueTokens signs a new access and refresh token pair for the session
+func (s *AuthService) issueTokens(session *models.Session) (*dto.TokenResponse, error) {
+	now := time.Now()
+
+	accessToken, err := s.signToken(&tokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Subject:   session.UserID.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(s.accessTokenTTL)),
+		},
+		SessionID: session.ID.String(),
+		TokenType: accessTokenType,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := s.signToken(&tokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        session.RefreshTokenID.String(),
+			Subject:   session.UserID.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(session.ExpiresAt),
+		},
+		SessionID: session.ID.String(),
+		TokenType: refreshTokenType,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.accessTokenTTL.Seconds()),
+	}, nil
+}
+
+func (s *AuthService) signToken(claims *tokenClaims) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+}
+
+// parseToken verifies the signature and expiry of a token of the expected type
+func (s *AuthService) parseToken(token string, tokenType string) (*tokenClaims, error) {
+	claims := &tokenClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		return s.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil || claims.TokenType != tokenType {
+		return nil, ErrInvalidToken
+	}
+
+	// MFA tokens are issued before a session exists
+	if tokenType != mfaTokenType {
+		if _, err := uuid.Parse(claims.SessionID); err != nil {
+			return nil, ErrInvalidToken
+		}
+	}
+
+	return claims, nil
+}
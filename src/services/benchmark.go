@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/edfloreshz/rent-contracts/src/models"
+	"github.com/google/uuid"
+)
+
+// minBenchmarkSample is how many contracts a neighborhood needs for its median to
+// be compared against. Contracts in smaller neighborhoods are compared against
+// their city.
+const minBenchmarkSample = 3
+
+// ErrInvalidBenchmarkThreshold is returned when the threshold of a benchmark is not a percentage
+var ErrInvalidBenchmarkThreshold = errors.New("threshold must be between 0 and 100")
+
+// Areas a contract can be benchmarked against
+const (
+	BenchmarkNeighborhood = "neighborhood"
+	BenchmarkCity         = "city"
+)
+
+// RentBenchmarks are the rent distributions of the active contracts per
+// neighborhood and city, and the contracts rented well below them
+type RentBenchmarks struct {
+	GeneratedAt   time.Time          `json:"generatedAt"`
+	Threshold     float64            `json:"threshold"`
+	Neighborhoods []RentDistribution `json:"neighborhoods"`
+	Cities        []RentDistribution `json:"cities"`
+	UnderRented   []UnderRented      `json:"underRented"`
+}
+
+// RentDistribution summarizes the rents of an area. Neighborhood is empty for
+// the distribution of a whole city.
+type RentDistribution struct {
+	City         string `json:"city"`
+	Neighborhood string `json:"neighborhood,omitempty"`
+	RentStatistics
+	Businesses []BusinessRent `json:"businesses"`
+}
+
+// RentStatistics describe a set of rents
+type RentStatistics struct {
+	Contracts    int     `json:"contracts"`
+	Mean         float64 `json:"mean"`
+	Min          float64 `json:"min"`
+	Percentile25 float64 `json:"p25"`
+	Median       float64 `json:"median"`
+	Percentile75 float64 `json:"p75"`
+	Percentile90 float64 `json:"p90"`
+	Max          float64 `json:"max"`
+}
+
+// BusinessRent summarizes the rents of one business category within an area
+type BusinessRent struct {
+	Business string `json:"business"`
+	RentStatistics
+}
+
+// UnderRented is a contract whose rent is more than the threshold below the
+// median of its neighborhood, or of its city when the neighborhood is too small
+type UnderRented struct {
+	ContractID   uuid.UUID `json:"contractId"`
+	PropertyID   uuid.UUID `json:"propertyId"`
+	Property     string    `json:"property"`
+	City         string    `json:"city"`
+	Neighborhood string    `json:"neighborhood"`
+	Business     string    `json:"business"`
+	Rent         float64   `json:"rent"`
+	Benchmark    string    `json:"benchmark"`
+	Median       float64   `json:"median"`
+	BelowMedian  float64   `json:"belowMedian"`
+}
+
+// benchmarkedRent is the current rent of an active contract and where it is
+type benchmarkedRent struct {
+	ContractID   uuid.UUID `gorm:"column:contractid"`
+	PropertyID   uuid.UUID `gorm:"column:propertyid"`
+	Street       string    `gorm:"column:street"`
+	Number       string    `gorm:"column:number"`
+	City         string    `gorm:"column:city"`
+	Neighborhood string    `gorm:"column:neighborhood"`
+	Business     string    `gorm:"column:business"`
+	Rent         float64   `gorm:"column:rent"`
+}
+
+// GetRentBenchmarks computes the rent distributions of the current versions of
+// the active contracts and flags those more than threshold percent below their
+// local median. Business categories are compared ignoring case and spacing.
+func (s *ReportService) GetRentBenchmarks(ctx context.Context, threshold float64) (*RentBenchmarks, error) {
+	if threshold < 0 || threshold > 100 {
+		return nil, ErrInvalidBenchmarkThreshold
+	}
+
+	var rents []benchmarkedRent
+	if err := s.db.WithContext(ctx).Model(&models.Contract{}).
+		Joins("JOIN contractversions ON contracts.currentversionid = contractversions.id").
+		Joins("JOIN addresses ON addresses.id = contracts.addressid").
+		Where("contracts.deletedat IS NULL AND contractversions.status = ?", models.ActiveContract).
+		Select("contracts.id AS contractid, addresses.id AS propertyid, addresses.street, addresses.number, " +
+			"addresses.city, addresses.neighborhood, contractversions.business, contractversions.rent").
+		Order("addresses.city, addresses.neighborhood, contractversions.rent").
+		Scan(&rents).Error; err != nil {
+		return nil, err
+	}
+
+	type area struct{ city, neighborhood string }
+	neighborhoods := map[area][]benchmarkedRent{}
+	cities := map[area][]benchmarkedRent{}
+	for _, rent := range rents {
+		neighborhood := area{rent.City, rent.Neighborhood}
+		neighborhoods[neighborhood] = append(neighborhoods[neighborhood], rent)
+		city := area{city: rent.City}
+		cities[city] = append(cities[city], rent)
+	}
+
+	benchmarks := &RentBenchmarks{
+		GeneratedAt:   time.Now(),
+		Threshold:     threshold,
+		Neighborhoods: []RentDistribution{},
+		Cities:        []RentDistribution{},
+		UnderRented:   []UnderRented{},
+	}
+	medians := map[area]float64{}
+	for key, group := range neighborhoods {
+		distribution := rentDistribution(key.city, key.neighborhood, group)
+		benchmarks.Neighborhoods = append(benchmarks.Neighborhoods, distribution)
+		medians[key] = distribution.Median
+	}
+	for key, group := range cities {
+		distribution := rentDistribution(key.city, "", group)
+		benchmarks.Cities = append(benchmarks.Cities, distribution)
+		medians[key] = distribution.Median
+	}
+
+	for _, rent := range rents {
+		benchmark, key := BenchmarkNeighborhood, area{rent.City, rent.Neighborhood}
+		if len(neighborhoods[key]) < minBenchmarkSample {
+			benchmark, key = BenchmarkCity, area{city: rent.City}
+		}
+
+		median := medians[key]
+		if median <= 0 {
+			continue
+		}
+		below := (median - rent.Rent) / median * 100
+		if below <= threshold {
+			continue
+		}
+
+		benchmarks.UnderRented = append(benchmarks.UnderRented, UnderRented{
+			ContractID:   rent.ContractID,
+			PropertyID:   rent.PropertyID,
+			Property:     rent.Street + " " + rent.Number + ", " + rent.Neighborhood,
+			City:         rent.City,
+			Neighborhood: rent.Neighborhood,
+			Business:     rent.Business,
+			Rent:         rent.Rent,
+			Benchmark:    benchmark,
+			Median:       median,
+			BelowMedian:  roundCents(below),
+		})
+	}
+
+	sortDistributions := func(distributions []RentDistribution) {
+		sort.Slice(distributions, func(i, j int) bool {
+			if distributions[i].City != distributions[j].City {
+				return distributions[i].City < distributions[j].City
+			}
+			return distributions[i].Neighborhood < distributions[j].Neighborhood
+		})
+	}
+	sortDistributions(benchmarks.Neighborhoods)
+	sortDistributions(benchmarks.Cities)
+	sort.Slice(benchmarks.UnderRented, func(i, j int) bool {
+		return benchmarks.UnderRented[i].BelowMedian > benchmarks.UnderRented[j].BelowMedian
+	})
+
+	return benchmarks, nil
+}
+
+// rentDistribution summarizes the rents of an area overall and per business
+func rentDistribution(city, neighborhood string, rents []benchmarkedRent) RentDistribution {
+	all := make([]float64, len(rents))
+	byBusiness := map[string][]float64{}
+	labels := map[string]string{}
+	for i, rent := range rents {
+		all[i] = rent.Rent
+
+		key := strings.ToLower(strings.Join(strings.Fields(rent.Business), " "))
+		if _, ok := labels[key]; !ok {
+			labels[key] = strings.TrimSpace(rent.Business)
+		}
+		byBusiness[key] = append(byBusiness[key], rent.Rent)
+	}
+
+	distribution := RentDistribution{
+		City:           city,
+		Neighborhood:   neighborhood,
+		RentStatistics: rentStatistics(all),
+		Businesses:     make([]BusinessRent, 0, len(byBusiness)),
+	}
+	for key, businessRents := range byBusiness {
+		distribution.Businesses = append(distribution.Businesses, BusinessRent{
+			Business:       labels[key],
+			RentStatistics: rentStatistics(businessRents),
+		})
+	}
+	sort.Slice(distribution.Businesses, func(i, j int) bool {
+		return distribution.Businesses[i].Business < distribution.Businesses[j].Business
+	})
+
+	return distribution
+}
+
+// rentStatistics describes a non-empty set of rents
+func rentStatistics(rents []float64) RentStatistics {
+	sorted := append([]float64(nil), rents...)
+	sort.Float64s(sorted)
+
+	var sum float64
+	for _, rent := range sorted {
+		sum += rent
+	}
+
+	return RentStatistics{
+		Contracts:    len(sorted),
+		Mean:         roundCents(sum / float64(len(sorted))),
+		Min:          sorted[0],
+		Percentile25: percentile(sorted, 25),
+		Median:       percentile(sorted, 50),
+		Percentile75: percentile(sorted, 75),
+		Percentile90: percentile(sorted, 90),
+		Max:          sorted[len(sorted)-1],
+	}
+}
+
+// percentile interpolates the p-th percentile of sorted values
+func percentile(sorted []float64, p float64) float64 {
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return roundCents(sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower)))
+}
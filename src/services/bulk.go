@@ -0,0 +1,273 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/edfloreshz/rent-contracts/src/dto"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MaxBulkOperations limits how many operations a single bulk request can contain
+const MaxBulkOperations = 500
+
+// Bulk modes
+const (
+	// AtomicBulkMode commits all operations or none of them
+	AtomicBulkMode = "atomic"
+
+	// BestEffortBulkMode commits every operation that succeeds
+	BestEffortBulkMode = "bestEffort"
+)
+
+// Bulk item statuses
+const (
+	BulkItemSucceeded  = "succeeded"
+	BulkItemFailed     = "failed"
+	BulkItemRolledBack = "rolledBack"
+	BulkItemSkipped    = "skipped"
+)
+
+// errBulkAborted rolls back an atomic bulk request after one of its operations failed
+var errBulkAborted = errors.New("bulk operation aborted")
+
+// bulkOperationFunc runs a single bulk operation and returns the ID of the affected record
+type bulkOperationFunc func(tx *gorm.DB, op dto.BulkOperation) (*uuid.UUID, error)
+
+type BulkService struct {
+	uow             *UnitOfWork
+	addressService  *AddressService
+	userService     *UserService
+	contractService *ContractService
+}
+
+func NewBulkService(db *gorm.DB, addressService *AddressService, userService *UserService, contractService *ContractService) *BulkService {
+	return &BulkService{
+		uow:             NewUnitOfWork(db),
+		addressService:  addressService,
+		userService:     userService,
+		contractService: contractService,
+	}
+}
+
+func (s *BulkService) Addresses(ctx context.Context, req *dto.BulkRequest) (*dto.BulkResponse, error) {
+	return s.run(ctx, req, func(tx *gorm.DB, op dto.BulkOperation) (*uuid.UUID, error) {
+		addressService := s.addressService.WithTx(tx)
+
+		switch op.Op {
+		case "create":
+			var data dto.CreateAddressRequest
+			if err := json.Unmarshal(op.Data, &data); err != nil {
+				return nil, err
+			}
+			address, err := addressService.CreateAddress(ctx, &data)
+			if err != nil {
+				return nil, err
+			}
+			return &address.ID, nil
+		case "update":
+			var data dto.UpdateAddressRequest
+			if err := json.Unmarshal(op.Data, &data); err != nil {
+				return nil, err
+			}
+			address, err := addressService.UpdateAddress(ctx, *op.ID, &data, expectedVersions(op.Version))
+			if err != nil {
+				return nil, err
+			}
+			return &address.ID, nil
+		default:
+			return op.ID, addressService.DeleteAddress(ctx, *op.ID, op.Cascade)
+		}
+	})
+}
+
+func (s *BulkService) Users(ctx context.Context, req *dto.BulkRequest) (*dto.BulkResponse, error) {
+	return s.run(ctx, req, func(tx *gorm.DB, op dto.BulkOperation) (*uuid.UUID, error) {
+		userService := s.userService.WithTx(tx)
+
+		switch op.Op {
+		case "create":
+			var data dto.CreateUserRequest
+			if err := json.Unmarshal(op.Data, &data); err != nil {
+				return nil, err
+			}
+			user, err := userService.CreateUser(ctx, &data)
+			if err != nil {
+				return nil, err
+			}
+			return &user.ID, nil
+		case "update":
+			var data dto.UpdateUserRequest
+			if err := json.Unmarshal(op.Data, &data); err != nil {
+				return nil, err
+			}
+			user, err := userService.UpdateUser(ctx, *op.ID, &data, expectedVersions(op.Version))
+			if err != nil {
+				return nil, err
+			}
+			return &user.ID, nil
+		default:
+			return op.ID, userService.DeleteUser(ctx, *op.ID, op.Cascade)
+		}
+	})
+}
+
+func (s *BulkService) Contracts(ctx context.Context, req *dto.BulkRequest) (*dto.BulkResponse, error) {
+	return s.run(ctx, req, func(tx *gorm.DB, op dto.BulkOperation) (*uuid.UUID, error) {
+		contractService := s.contractService.WithTx(tx)
+
+		switch op.Op {
+		case "create":
+			var data dto.CreateContractRequest
+			if err := json.Unmarshal(op.Data, &data); err != nil {
+				return nil, err
+			}
+			contract, err := contractService.CreateContract(ctx, &data)
+			if err != nil {
+				return nil, err
+			}
+			return &contract.ID, nil
+		case "update":
+			var data dto.UpdateContractRequest
+			if err := json.Unmarshal(op.Data, &data); err != nil {
+				return nil, err
+			}
+			contract, err := contractService.UpdateContract(ctx, *op.ID, &data, expectedVersions(op.Version))
+			if err != nil {
+				return nil, err
+			}
+			return &contract.ID, nil
+		default:
+			return op.ID, contractService.DeleteContract(ctx, *op.ID)
+		}
+	})
+}
+
+// run executes the operations of a bulk request inside one transaction. In atomic
+// mode the first failure rolls everything back; in best-effort mode every operation
+// runs in its own savepoint so failures only undo that operation.
+func (s *BulkService) run(ctx context.Context, req *dto.BulkRequest, fn bulkOperationFunc) (*dto.BulkResponse, error) {
+	mode := req.Mode
+	if mode == "" {
+		mode = AtomicBulkMode
+	}
+	if mode != AtomicBulkMode && mode != BestEffortBulkMode {
+		return nil, fmt.Errorf("invalid bulk mode %q", req.Mode)
+	}
+	if len(req.Operations) == 0 {
+		return nil, errors.New("no operations provided")
+	}
+	if len(req.Operations) > MaxBulkOperations {
+		return nil, fmt.Errorf("a bulk request cannot contain more than %d operations", MaxBulkOperations)
+	}
+
+	var response *dto.BulkResponse
+
+	err := s.uow.Do(ctx, func(tx *gorm.DB) error {
+		response = &dto.BulkResponse{
+			Mode:    mode,
+			Results: make([]dto.BulkItemResult, len(req.Operations)),
+		}
+
+		for i, op := range req.Operations {
+			result := &response.Results[i]
+			result.Index = i
+			result.Op = op.Op
+
+			if err := validateBulkOperation(op); err != nil {
+				result.Status = BulkItemFailed
+				result.Error = err.Error()
+			} else if mode == AtomicBulkMode {
+				id, err := fn(tx, op)
+				result.ID = id
+				if err != nil {
+					result.Status = BulkItemFailed
+					result.Error = err.Error()
+				} else {
+					result.Status = BulkItemSucceeded
+				}
+			} else {
+				var id *uuid.UUID
+				err := tx.Transaction(func(tx *gorm.DB) error {
+					var err error
+					id, err = fn(tx, op)
+					return err
+				})
+				result.ID = id
+				if err != nil {
+					result.Status = BulkItemFailed
+					result.Error = err.Error()
+				} else {
+					result.Status = BulkItemSucceeded
+				}
+			}
+
+			if result.Status == BulkItemFailed && mode == AtomicBulkMode {
+				for j := range response.Results {
+					switch {
+					case j < i:
+						response.Results[j].Status = BulkItemRolledBack
+					case j > i:
+						response.Results[j].Index = j
+						response.Results[j].Op = req.Operations[j].Op
+						response.Results[j].Status = BulkItemSkipped
+					}
+				}
+				return errBulkAborted
+			}
+		}
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, errBulkAborted) {
+		return nil, err
+	}
+
+	response.Committed = err == nil
+	for _, result := range response.Results {
+		switch result.Status {
+		case BulkItemSucceeded:
+			response.Succeeded++
+		case BulkItemFailed:
+			response.Failed++
+		}
+	}
+
+	return response, nil
+}
+
+// validateBulkOperation checks that an operation carries the fields its type needs
+func validateBulkOperation(op dto.BulkOperation) error {
+	switch op.Op {
+	case "create":
+		if len(op.Data) == 0 {
+			return errors.New("create operations require data")
+		}
+	case "update":
+		if op.ID == nil {
+			return errors.New("update operations require an id")
+		}
+		if len(op.Data) == 0 {
+			return errors.New("update operations require data")
+		}
+	case "delete":
+		if op.ID == nil {
+			return errors.New("delete operations require an id")
+		}
+	default:
+		return fmt.Errorf("unsupported operation %q", op.Op)
+	}
+	return nil
+}
+
+// expectedVersions turns the optional version of an operation into the versions
+// the update expects
+func expectedVersions(version *int) []int {
+	if version == nil {
+		return nil
+	}
+	return []int{*version}
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/edfloreshz/rent-contracts/src/auth"
+	"github.com/edfloreshz/rent-contracts/src/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ConfirmationService struct {
+	db  *gorm.DB
+	uow *UnitOfWork
+}
+
+func NewConfirmationService(db *gorm.DB) *ConfirmationService {
+	return &ConfirmationService{
+		db:  db,
+		uow: NewUnitOfWork(db),
+	}
+}
+
+// GetConfirmationRequests returns the confirmation requests of the contracts that
+// are not deleted, pending ones first. References only see those addressed to them.
+func (s *ConfirmationService) GetConfirmationRequests(ctx context.Context) ([]models.ContractReference, error) {
+	db := s.db.WithContext(ctx)
+
+	var requests []models.ContractReference
+	if err := db.Scopes(confirmationAccessScope(ctx)).
+		Preload("Contract.Tenant").Preload("Contract.Landlord").Preload("Contract.Address").Preload("Contract.CurrentVersion").
+		Where("contractid IN (?)", db.Model(&models.Contract{}).Select("id")).
+		Order("respondedat DESC NULLS FIRST, contractid").
+		Find(&requests).Error; err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+// RespondToConfirmation records the answer of the calling reference to the request
+// of a contract. It can be answered again to change the answer.
+func (s *ConfirmationService) RespondToConfirmation(ctx context.Context, contractID uuid.UUID, status models.ConfirmationStatus) (*models.ContractReference, error) {
+	if status != models.ConfirmedConfirmation && status != models.DeclinedConfirmation {
+		return nil, errors.New("status must be confirmed or declined")
+	}
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return nil, errors.New("confirmation request not found")
+	}
+
+	var request models.ContractReference
+	err := s.uow.Do(ctx, func(tx *gorm.DB) error {
+		var contract models.Contract
+		if err := tx.Preload("Tenant").Preload("Landlord").Preload("Address").Preload("CurrentVersion").
+			First(&contract, contractID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("confirmation request not found")
+			}
+			return err
+		}
+		if err := tx.Where("contractid = ? AND referenceid = ?", contract.ID, principal.UserID).First(&request).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("confirmation request not found")
+			}
+			return err
+		}
+
+		before := request
+		now := time.Now()
+		if err := tx.Model(&request).
+			Where("contractid = ? AND referenceid = ?", request.ContractID, request.ReferenceID).
+			Updates(map[string]interface{}{"status": status, "respondedat": now}).Error; err != nil {
+			return err
+		}
+		request.Status = status
+		request.RespondedAt = &now
+		request.Contract = contract
+		return recordAudit(tx, contract.OrganizationID, models.AuditUpdate, AuditConfirmation, contract.ID, &before, &request)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &request, nil
+}
+
+// confirmationAccessScope restricts confirmation requests to those the caller may
+// see. References only see the requests addressed to them and callers without a
+// principal see nothing.
+func confirmationAccessScope(ctx context.Context) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		principal, ok := auth.PrincipalFromContext(ctx)
+		switch {
+		case !ok:
+			return db.Where("1 = 0")
+		case principal.SeesEverything():
+			return db
+		default:
+			return db.Where("contractreferences.referenceid = ?", principal.UserID)
+		}
+	}
+}
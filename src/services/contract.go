@@ -1,9 +1,13 @@
 package services
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"sort"
+	"time"
 
+	"github.com/edfloreshz/rent-contracts/src/auth"
 	"github.com/edfloreshz/rent-contracts/src/dto"
 	"github.com/edfloreshz/rent-contracts/src/models"
 	"github.com/google/uuid"
@@ -17,59 +21,61 @@ import (
 	"github.com/johnfercher/maroto/v2/pkg/core"
 	"github.com/johnfercher/maroto/v2/pkg/props"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type ContractService struct {
-	db *gorm.DB
+	db  *gorm.DB
+	uow *UnitOfWork
 }
 
 func NewContractService(db *gorm.DB) *ContractService {
 	return &ContractService{
-		db,
+		db:  db,
+		uow: NewUnitOfWork(db),
 	}
 }
 
-func (s *ContractService) CreateContract(req *dto.CreateContractRequest) (*models.Contract, error) {
-	contract := &models.Contract{
-		LandlordID: req.LandlordID,
-		TenantID:   req.TenantID,
-		AddressID:  req.AddressID,
+// WithTx returns a copy of the service that runs its queries in the given transaction
+func (s *ContractService) WithTx(tx *gorm.DB) *ContractService {
+	return &ContractService{
+		db:  tx,
+		uow: s.uow.WithTx(tx),
 	}
+}
 
-	// Start transaction
-	tx := s.db.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+func (s *ContractService) CreateContract(ctx context.Context, req *dto.CreateContractRequest) (*models.Contract, error) {
+	var contract *models.Contract
+
+	err := s.uow.Do(ctx, func(tx *gorm.DB) error {
+		contract = &models.Contract{
+			LandlordID: req.LandlordID,
+			TenantID:   req.TenantID,
+			AddressID:  req.AddressID,
 		}
-	}()
 
-	if err := tx.Create(contract).Error; err != nil {
-		tx.Rollback()
-		return nil, err
-	}
+		if err := tx.Create(contract).Error; err != nil {
+			return err
+		}
 
-	// Add references if provided
-	if len(req.ReferenceIDs) > 0 {
-		for _, refID := range req.ReferenceIDs {
-			contractRef := &models.ContractReference{
-				ContractID:  contract.ID,
-				ReferenceID: refID,
-			}
-			if err := tx.Create(contractRef).Error; err != nil {
-				tx.Rollback()
-				return nil, err
-			}
+		// Add references if provided
+		if err := replaceContractReferences(tx, contract.ID, req.ReferenceIDs); err != nil {
+			return err
 		}
+
+		return auditContract(tx, models.AuditCreate, nil, newContractAuditRecord(*contract, req.ReferenceIDs))
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	tx.Commit()
 	return contract, nil
 }
 
-func (s *ContractService) GetContractByID(id uuid.UUID) (*models.Contract, error) {
+func (s *ContractService) GetContractByID(ctx context.Context, id uuid.UUID) (*models.Contract, error) {
 	var contract models.Contract
-	if err := s.db.
+	if err := s.db.WithContext(ctx).
+		Scopes(contractAccessScope(ctx)).
 		Preload("CurrentVersion").
 		Preload("Landlord").
 		Preload("Tenant").
@@ -87,9 +93,71 @@ func (s *ContractService) GetContractByID(id uuid.UUID) (*models.Contract, error
 	return &contract, nil
 }
 
-func (s *ContractService) GetAllContracts() ([]models.Contract, error) {
+// GetContractAsOf returns a contract, its parties, its references and its versions
+// as they were at asOf
+func (s *ContractService) GetContractAsOf(ctx context.Context, id uuid.UUID, asOf time.Time) (*models.Contract, error) {
+	contract, err := s.contractAsOf(ctx, id, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	landlord, err := userAsOf(ctx, s.db, contract.LandlordID, asOf, false)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if landlord != nil {
+		contract.Landlord = *landlord
+	}
+
+	tenant, err := userAsOf(ctx, s.db, contract.TenantID, asOf, false)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if tenant != nil {
+		contract.Tenant = *tenant
+	}
+
+	if err := findAsOf(historyQuery(ctx, s.db, "addresses", asOf), contract.AddressID, &contract.Address); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var references []struct {
+		ReferenceID uuid.UUID `gorm:"column:referenceid"`
+	}
+	if err := historyQuery(ctx, s.db, "contractreferences", asOf).Where("rowid = ?", id).Scan(&references).Error; err != nil {
+		return nil, err
+	}
+	for _, reference := range references {
+		user, err := userAsOf(ctx, s.db, reference.ReferenceID, asOf, false)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		contract.References = append(contract.References, *user)
+	}
+
+	contract.Versions, err = s.contractVersionsAsOf(ctx, id, asOf)
+	if err != nil {
+		return nil, err
+	}
+	if contract.CurrentVersionID != nil {
+		for i := range contract.Versions {
+			if contract.Versions[i].ID == *contract.CurrentVersionID {
+				contract.CurrentVersion = &contract.Versions[i]
+				break
+			}
+		}
+	}
+
+	return contract, nil
+}
+
+func (s *ContractService) GetAllContracts(ctx context.Context) ([]models.Contract, error) {
 	var contracts []models.Contract
-	if err := s.db.
+	if err := s.db.WithContext(ctx).
+		Scopes(contractAccessScope(ctx)).
 		Preload("CurrentVersion").
 		Preload("Landlord").
 		Preload("Tenant").
@@ -102,9 +170,10 @@ func (s *ContractService) GetAllContracts() ([]models.Contract, error) {
 	return contracts, nil
 }
 
-func (s *ContractService) GetContractsByTenant(tenantID uuid.UUID) ([]models.Contract, error) {
+func (s *ContractService) GetContractsByTenant(ctx context.Context, tenantID uuid.UUID) ([]models.Contract, error) {
 	var contracts []models.Contract
-	if err := s.db.
+	if err := s.db.WithContext(ctx).
+		Scopes(contractAccessScope(ctx)).
 		Preload("CurrentVersion").
 		Preload("Landlord").
 		Preload("Tenant").
@@ -118,90 +187,265 @@ func (s *ContractService) GetContractsByTenant(tenantID uuid.UUID) ([]models.Con
 	return contracts, nil
 }
 
-func (s *ContractService) UpdateContract(id uuid.UUID, req *dto.UpdateContractRequest) (*models.Contract, error) {
+func (s *ContractService) UpdateContract(ctx context.Context, id uuid.UUID, req *dto.UpdateContractRequest, expectedVersions []int) (*models.Contract, error) {
 	var contract models.Contract
-	if err := s.db.First(&contract, id).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("contract not found")
+
+	err := s.uow.Do(ctx, func(tx *gorm.DB) error {
+		contract = models.Contract{}
+		if err := tx.First(&contract, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("contract not found")
+			}
+			return err
+		}
+
+		if err := checkVersion(contract.Version, expectedVersions); err != nil {
+			return err
 		}
+
+		referenceIDs, err := contractReferenceIDs(tx, contract.ID)
+		if err != nil {
+			return err
+		}
+		before := newContractAuditRecord(contract, referenceIDs)
+
+		// Update only provided fields
+		if req.LandlordID != nil {
+			contract.LandlordID = *req.LandlordID
+		}
+		if req.TenantID != nil {
+			contract.TenantID = *req.TenantID
+		}
+		if req.AddressID != nil {
+			contract.AddressID = *req.AddressID
+		}
+		if req.Deposit != nil {
+			contract.Deposit = *req.Deposit
+		}
+
+		if err := saveVersioned(tx, &contract, &contract.Version); err != nil {
+			return err
+		}
+
+		// Handle references update
+		if req.ReferenceIDs != nil {
+			if err := replaceContractReferences(tx, contract.ID, req.ReferenceIDs); err != nil {
+				return err
+			}
+			referenceIDs = req.ReferenceIDs
+		}
+
+		return auditContract(tx, models.AuditUpdate, before, newContractAuditRecord(contract, referenceIDs))
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	// Update only provided fields
-	if req.LandlordID != nil {
-		contract.LandlordID = *req.LandlordID
-	}
-	if req.TenantID != nil {
-		contract.TenantID = *req.TenantID
-	}
-	if req.AddressID != nil {
-		contract.AddressID = *req.AddressID
+	return &contract, nil
+}
+
+// PatchContract applies a JSON merge patch to a contract and returns the names of the changed fields
+func (s *ContractService) PatchContract(ctx context.Context, id uuid.UUID, patch []byte, expectedVersions []int) (*models.Contract, []string, error) {
+	var contract models.Contract
+	var changed []string
+
+	err := s.uow.Do(ctx, func(tx *gorm.DB) error {
+		contract = models.Contract{}
+		if err := tx.Preload("References").First(&contract, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("contract not found")
+			}
+			return err
+		}
+
+		if err := checkVersion(contract.Version, expectedVersions); err != nil {
+			return err
+		}
+
+		document := dto.ContractPatch{
+			LandlordID: contract.LandlordID,
+			TenantID:   contract.TenantID,
+			AddressID:  contract.AddressID,
+			Deposit:    contract.Deposit,
+		}
+		for _, reference := range contract.References {
+			document.ReferenceIDs = append(document.ReferenceIDs, reference.ID)
+		}
+		contract.References = nil
+		before := newContractAuditRecord(contract, document.ReferenceIDs)
+
+		var err error
+		changed, err = applyMergePatch(&document, patch)
+		if err != nil {
+			return err
+		}
+		if len(changed) == 0 {
+			return nil
+		}
+
+		contract.LandlordID = document.LandlordID
+		contract.TenantID = document.TenantID
+		contract.AddressID = document.AddressID
+		contract.Deposit = document.Deposit
+
+		if err := saveVersioned(tx, &contract, &contract.Version); err != nil {
+			return err
+		}
+
+		for _, field := range changed {
+			if field == "referenceIds" {
+				if err := replaceContractReferences(tx, contract.ID, document.ReferenceIDs); err != nil {
+					return err
+				}
+			}
+		}
+
+		return auditContract(tx, models.AuditUpdate, before, newContractAuditRecord(contract, document.ReferenceIDs))
+	})
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if err := s.db.Save(&contract).Error; err != nil {
+	return &contract, changed, nil
+}
+
+func (s *ContractService) DeleteContract(ctx context.Context, id uuid.UUID) error {
+	return s.uow.Do(ctx, func(tx *gorm.DB) error {
+		return deleteContractByID(tx, id)
+	})
+}
+
+// GetDeletedContracts returns the contracts in the trash, most recently deleted first
+func (s *ContractService) GetDeletedContracts(ctx context.Context) ([]models.Contract, error) {
+	var contracts []models.Contract
+	if err := s.db.WithContext(ctx).
+		Unscoped().
+		Preload("CurrentVersion").
+		Where("deletedat IS NOT NULL").
+		Order("deletedat DESC").
+		Find(&contracts).Error; err != nil {
 		return nil, err
 	}
+	return contracts, nil
+}
 
-	// Handle references update
-	if req.ReferenceIDs != nil {
-		// Remove existing references
-		if err := s.db.Where("contractid = ?", contract.ID).Delete(&models.ContractReference{}).Error; err != nil {
-			return nil, err
+// RestoreContract takes a contract out of the trash. Its landlord, tenant, address
+// and references must not be deleted.
+func (s *ContractService) RestoreContract(ctx context.Context, id uuid.UUID) (*models.Contract, error) {
+	err := s.uow.Do(ctx, func(tx *gorm.DB) error {
+		var contract models.Contract
+		if err := findDeleted(tx, &contract, id); err != nil {
+			return err
 		}
 
-		// Add new references
-		for _, refID := range req.ReferenceIDs {
-			contractRef := &models.ContractReference{
-				ContractID:  contract.ID,
-				ReferenceID: refID,
-			}
-			if err := s.db.Create(contractRef).Error; err != nil {
-				return nil, err
+		referenceIDs, err := contractReferenceIDs(tx, contract.ID)
+		if err != nil {
+			return err
+		}
+
+		userIDs := append([]uuid.UUID{contract.LandlordID, contract.TenantID}, referenceIDs...)
+		var deletedUsers []uuid.UUID
+		if err := tx.Unscoped().Model(&models.User{}).Where("id IN ? AND deletedat IS NOT NULL", userIDs).Pluck("id", &deletedUsers).Error; err != nil {
+			return err
+		}
+		for _, userID := range deletedUsers {
+			switch userID {
+			case contract.TenantID:
+				return fmt.Errorf("%w: tenant %s is deleted", ErrRestoreConflict, userID)
+			case contract.LandlordID:
+				return fmt.Errorf("%w: landlord %s is deleted", ErrRestoreConflict, userID)
+			default:
+				return fmt.Errorf("%w: reference %s is deleted", ErrRestoreConflict, userID)
 			}
 		}
-	}
 
-	return &contract, nil
+		addressExists, err := exists(tx.Model(&models.Address{}).Where("id = ?", contract.AddressID))
+		if err != nil {
+			return err
+		}
+		if !addressExists {
+			return fmt.Errorf("%w: address %s is deleted", ErrRestoreConflict, contract.AddressID)
+		}
+
+		before := contract
+		if err := restoreRecord(tx, &contract, id); err != nil {
+			return err
+		}
+		return auditContract(tx, models.AuditRestore, newContractAuditRecord(before, referenceIDs), newContractAuditRecord(contract, referenceIDs))
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("contract not found in trash")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.GetContractByID(ctx, id)
 }
 
-func (s *ContractService) DeleteContract(id uuid.UUID) error {
-	if err := s.db.Delete(&models.Contract{}, id).Error; err != nil {
-		return err
+// PurgeContract permanently removes a contract, its versions and its references
+// from the trash
+func (s *ContractService) PurgeContract(ctx context.Context, id uuid.UUID) error {
+	err := s.uow.Do(ctx, func(tx *gorm.DB) error {
+		var contract models.Contract
+		if err := findDeleted(tx, &contract, id); err != nil {
+			return err
+		}
+		return purgeContract(tx, &contract)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.New("contract not found in trash")
 	}
-	return nil
+	return err
 }
 
-func (s *ContractService) CreateContractVersion(req *dto.CreateContractVersionRequest) (*models.ContractVersion, error) {
-	var maxVersion int
-	s.db.Model(&models.ContractVersion{}).
-		Where("contractid = ?", req.ContractID).
-		Select("COALESCE(MAX(versionnumber), 0)").
-		Scan(&maxVersion)
-
-	version := &models.ContractVersion{
-		ContractID:             req.ContractID,
-		VersionNumber:          maxVersion + 1,
-		Rent:                   req.Rent,
-		RentIncreasePercentage: req.RentIncreasePercentage,
-		Business:               req.Business,
-		Status:                 models.ContractStatus(req.Status),
-		Type:                   models.ContractType(req.Type),
-		StartDate:              req.StartDate,
-		EndDate:                req.EndDate,
-		RenewalDate:            req.RenewalDate,
-		SpecialTerms:           req.SpecialTerms,
-	}
-
-	if err := s.db.Create(version).Error; err != nil {
+func (s *ContractService) CreateContractVersion(ctx context.Context, req *dto.CreateContractVersionRequest) (*models.ContractVersion, error) {
+	var version *models.ContractVersion
+
+	err := s.uow.Do(ctx, func(tx *gorm.DB) error {
+		// Lock the contract so concurrent versions are numbered one after another
+		contract, err := lockContract(tx, req.ContractID)
+		if err != nil {
+			return err
+		}
+
+		var maxVersion int
+		if err := tx.Model(&models.ContractVersion{}).
+			Where("contractid = ?", req.ContractID).
+			Select("COALESCE(MAX(versionnumber), 0)").
+			Scan(&maxVersion).Error; err != nil {
+			return err
+		}
+
+		version = &models.ContractVersion{
+			ContractID:             req.ContractID,
+			VersionNumber:          maxVersion + 1,
+			Rent:                   req.Rent,
+			RentIncreasePercentage: req.RentIncreasePercentage,
+			Business:               req.Business,
+			Status:                 models.ContractStatus(req.Status),
+			Type:                   models.ContractType(req.Type),
+			StartDate:              req.StartDate,
+			EndDate:                req.EndDate,
+			RenewalDate:            req.RenewalDate,
+			SpecialTerms:           req.SpecialTerms,
+		}
+
+		if err := tx.Create(version).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, contract.OrganizationID, models.AuditCreate, AuditContractVersion, version.ID, nil, version)
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return version, nil
 }
 
-func (s *ContractService) GetContractVersionByID(id uuid.UUID) (*models.ContractVersion, error) {
+func (s *ContractService) GetContractVersionByID(ctx context.Context, id uuid.UUID) (*models.ContractVersion, error) {
 	var version models.ContractVersion
-	if err := s.db.First(&version, id).Error; err != nil {
+	visible := s.db.WithContext(ctx).Model(&models.Contract{}).Scopes(contractAccessScope(ctx)).Select("id")
+	if err := s.db.WithContext(ctx).Where("contractid IN (?)", visible).First(&version, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("contract version not found")
 		}
@@ -210,16 +454,98 @@ func (s *ContractService) GetContractVersionByID(id uuid.UUID) (*models.Contract
 	return &version, nil
 }
 
-func (s *ContractService) GetContractVersionsByContractID(contractID uuid.UUID) ([]models.ContractVersion, error) {
+// PatchContractVersion applies a JSON merge patch to a contract version and returns the names of the changed fields
+func (s *ContractService) PatchContractVersion(ctx context.Context, contractID uuid.UUID, id uuid.UUID, patch []byte) (*models.ContractVersion, []string, error) {
+	var version models.ContractVersion
+	var changed []string
+
+	err := s.uow.Do(ctx, func(tx *gorm.DB) error {
+		contract, err := lockContract(tx, contractID)
+		if err != nil {
+			return err
+		}
+
+		version = models.ContractVersion{}
+		if err := tx.Where("contractid = ?", contractID).First(&version, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("contract version not found")
+			}
+			return err
+		}
+		before := version
+
+		document := dto.ContractVersionPatch{
+			Rent:                   version.Rent,
+			RentIncreasePercentage: version.RentIncreasePercentage,
+			Business:               version.Business,
+			Status:                 string(version.Status),
+			Type:                   string(version.Type),
+			StartDate:              version.StartDate,
+			EndDate:                version.EndDate,
+			RenewalDate:            version.RenewalDate,
+			SpecialTerms:           version.SpecialTerms,
+		}
+
+		changed, err = applyMergePatch(&document, patch)
+		if err != nil {
+			return err
+		}
+		if len(changed) == 0 {
+			return nil
+		}
+
+		version.Rent = document.Rent
+		version.RentIncreasePercentage = document.RentIncreasePercentage
+		version.Business = document.Business
+		version.Status = models.ContractStatus(document.Status)
+		version.Type = models.ContractType(document.Type)
+		version.StartDate = document.StartDate
+		version.EndDate = document.EndDate
+		version.RenewalDate = document.RenewalDate
+		version.SpecialTerms = document.SpecialTerms
+
+		if err := tx.Model(&version).Select("*").Omit(clause.Associations).Updates(&version).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, contract.OrganizationID, models.AuditUpdate, AuditContractVersion, version.ID, &before, &version)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &version, changed, nil
+}
+
+func (s *ContractService) GetContractVersionsByContractID(ctx context.Context, contractID uuid.UUID) ([]models.ContractVersion, error) {
 	var versions []models.ContractVersion
-	if err := s.db.Where("contractid = ?", contractID).Order("versionnumber DESC").Find(&versions).Error; err != nil {
+	visible := s.db.WithContext(ctx).Model(&models.Contract{}).Scopes(contractAccessScope(ctx)).Select("id")
+	if err := s.db.WithContext(ctx).
+		Where("contractid = ? AND contractid IN (?)", contractID, visible).
+		Order("versionnumber DESC").
+		Find(&versions).Error; err != nil {
 		return nil, err
 	}
 	return versions, nil
 }
 
-func (s *ContractService) GetContractDocument(id uuid.UUID, versionID *uuid.UUID) ([]byte, error) {
-	contract, err := s.GetContractByID(id)
+// GetContractVersionsAsOf returns the versions a contract had at asOf
+func (s *ContractService) GetContractVersionsAsOf(ctx context.Context, contractID uuid.UUID, asOf time.Time) ([]models.ContractVersion, error) {
+	if _, err := s.contractAsOf(ctx, contractID, asOf); err != nil {
+		return nil, err
+	}
+	return s.contractVersionsAsOf(ctx, contractID, asOf)
+}
+
+// GetContractDocument renders a contract as a PDF. When asOf is set the contract is
+// rendered as it was at that moment.
+func (s *ContractService) GetContractDocument(ctx context.Context, id uuid.UUID, versionID *uuid.UUID, asOf *time.Time) ([]byte, error) {
+	var contract *models.Contract
+	var err error
+	if asOf != nil {
+		contract, err = s.GetContractAsOf(ctx, id, *asOf)
+	} else {
+		contract, err = s.GetContractByID(ctx, id)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -575,6 +901,120 @@ func (s *ContractService) GetContractDocument(id uuid.UUID, versionID *uuid.UUID
 	return document.GetBytes(), nil
 }
 
+// contractAsOf reads a contract as it was at asOf, without its relationships, if the
+// caller may see it
+func (s *ContractService) contractAsOf(ctx context.Context, id uuid.UUID, asOf time.Time) (*models.Contract, error) {
+	var contract models.Contract
+	if err := findAsOf(liveHistoryQuery(ctx, s.db, "contracts", asOf), id, &contract); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("contract not found")
+		}
+		return nil, err
+	}
+
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok || (!principal.SeesEverything() && principal.UserID != contract.TenantID) {
+		return nil, errors.New("contract not found")
+	}
+	return &contract, nil
+}
+
+// contractVersionsAsOf reads the versions a contract had at asOf, newest first
+func (s *ContractService) contractVersionsAsOf(ctx context.Context, contractID uuid.UUID, asOf time.Time) ([]models.ContractVersion, error) {
+	var versions []models.ContractVersion
+	if err := historyQuery(ctx, s.db, "contractversions", asOf).
+		Where("data->>'contractid' = ?", contractID.String()).
+		Order("(data->>'versionnumber')::int DESC").
+		Scan(&versions).Error; err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// lockContract takes a row lock on the contract for the rest of the transaction
+func lockContract(tx *gorm.DB, id uuid.UUID) (*models.Contract, error) {
+	var contract models.Contract
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "organizationid").First(&contract, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("contract not found")
+		}
+		return nil, err
+	}
+	return &contract, nil
+}
+
+// contractAuditRecord is the audited state of a contract, including its references
+type contractAuditRecord struct {
+	models.Contract
+	ReferenceIDs []string `json:"referenceIds"`
+}
+
+func newContractAuditRecord(contract models.Contract, referenceIDs []uuid.UUID) *contractAuditRecord {
+	record := &contractAuditRecord{Contract: contract}
+	for _, id := range referenceIDs {
+		record.ReferenceIDs = append(record.ReferenceIDs, id.String())
+	}
+	sort.Strings(record.ReferenceIDs)
+	return record
+}
+
+// auditContract records a change to a contract in the audit log
+func auditContract(tx *gorm.DB, action models.AuditAction, before, after *contractAuditRecord) error {
+	record := after
+	if record == nil {
+		record = before
+	}
+	return recordAudit(tx, record.OrganizationID, action, AuditContract, record.ID, before, after)
+}
+
+// contractReferenceIDs returns the IDs of the references linked to a contract
+func contractReferenceIDs(tx *gorm.DB, contractID uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	if err := tx.Model(&models.ContractReference{}).Where("contractid = ?", contractID).Pluck("referenceid", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// replaceContractReferences makes the given users the references of the contract.
+// References that stay keep their answer to the confirmation request, new ones
+// start pending.
+func replaceContractReferences(tx *gorm.DB, contractID uuid.UUID, referenceIDs []uuid.UUID) error {
+	// References have no organization of their own, so make sure they belong to the caller's
+	unique := make(map[uuid.UUID]struct{}, len(referenceIDs))
+	for _, refID := range referenceIDs {
+		unique[refID] = struct{}{}
+	}
+	if len(unique) > 0 {
+		var count int64
+		if err := tx.Model(&models.User{}).Where("id IN ?", referenceIDs).Count(&count).Error; err != nil {
+			return err
+		}
+		if int(count) != len(unique) {
+			return errors.New("reference not found")
+		}
+	}
+
+	removed := tx.Where("contractid = ?", contractID)
+	if len(unique) > 0 {
+		removed = removed.Where("referenceid NOT IN ?", referenceIDs)
+	}
+	if err := removed.Delete(&models.ContractReference{}).Error; err != nil {
+		return err
+	}
+
+	for refID := range unique {
+		contractRef := &models.ContractReference{
+			ContractID:  contractID,
+			ReferenceID: refID,
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(contractRef).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func darkGrayColor() *props.Color {
 	return &props.Color{
 		Red:   55,
@@ -0,0 +1,221 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/edfloreshz/rent-contracts/src/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrHasDependents is returned when deleting a record other records still depend on
+var ErrHasDependents = errors.New("record is still in use")
+
+// Relations a dependent record has with the record being deleted
+const (
+	DependentResident  = "resident"
+	DependentProperty  = "property"
+	DependentTenant    = "tenant"
+	DependentLandlord  = "landlord"
+	DependentReference = "reference"
+)
+
+// Dependent is a record that stops another one from being deleted
+type Dependent struct {
+	Type     string
+	ID       uuid.UUID
+	Relation string
+}
+
+// DependencyError lists the records that stop a record from being deleted
+type DependencyError struct {
+	Dependents []Dependent
+}
+
+func (e *DependencyError) Error() string {
+	dependents := make([]string, len(e.Dependents))
+	for i, dependent := range e.Dependents {
+		dependents[i] = fmt.Sprintf("%s %s (%s)", dependent.Type, dependent.ID, dependent.Relation)
+	}
+	return fmt.Sprintf("%s by %s", ErrHasDependents.Error(), strings.Join(dependents, ", "))
+}
+
+func (e *DependencyError) Unwrap() error {
+	return ErrHasDependents
+}
+
+// addressDependents returns the users living at an address and the contracts
+// renting it that are not deleted
+func addressDependents(tx *gorm.DB, addressID uuid.UUID) ([]Dependent, error) {
+	var dependents []Dependent
+
+	var userIDs []uuid.UUID
+	if err := tx.Model(&models.User{}).Where("addressid = ?", addressID).Pluck("id", &userIDs).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range userIDs {
+		dependents = append(dependents, Dependent{Type: AuditUser, ID: id, Relation: DependentResident})
+	}
+
+	var contractIDs []uuid.UUID
+	if err := tx.Model(&models.Contract{}).Where("addressid = ?", addressID).Pluck("id", &contractIDs).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range contractIDs {
+		dependents = append(dependents, Dependent{Type: AuditContract, ID: id, Relation: DependentProperty})
+	}
+
+	return dependents, nil
+}
+
+// userDependents returns the contracts that are not deleted and name a user as
+// their tenant, landlord or reference
+func userDependents(tx *gorm.DB, userID uuid.UUID) ([]Dependent, error) {
+	var contracts []models.Contract
+	if err := tx.Select("id", "tenantid", "landlordid").Where("tenantid = ? OR landlordid = ?", userID, userID).Find(&contracts).Error; err != nil {
+		return nil, err
+	}
+
+	var dependents []Dependent
+	for _, contract := range contracts {
+		relation := DependentTenant
+		if contract.LandlordID == userID {
+			relation = DependentLandlord
+		}
+		dependents = append(dependents, Dependent{Type: AuditContract, ID: contract.ID, Relation: relation})
+	}
+
+	var referencedBy []uuid.UUID
+	if err := tx.Model(&models.Contract{}).
+		Joins("JOIN contractreferences ON contractreferences.contractid = contracts.id").
+		Where("contractreferences.referenceid = ?", userID).
+		Pluck("contracts.id", &referencedBy).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range referencedBy {
+		dependents = append(dependents, Dependent{Type: AuditContract, ID: id, Relation: DependentReference})
+	}
+
+	return dependents, nil
+}
+
+// deleteAddress soft deletes an address. Without cascade it fails with a
+// DependencyError while users or contracts depend on it; with cascade those are
+// deleted first.
+func deleteAddress(tx *gorm.DB, address *models.Address, cascade bool) error {
+	dependents, err := addressDependents(tx, address.ID)
+	if err != nil {
+		return err
+	}
+	if len(dependents) > 0 && !cascade {
+		return &DependencyError{Dependents: dependents}
+	}
+
+	// Contracts go first since deleting the residents may already remove some of them
+	for _, dependent := range dependents {
+		if dependent.Type == AuditContract {
+			if err := deleteContractByID(tx, dependent.ID); err != nil {
+				return err
+			}
+		}
+	}
+	for _, dependent := range dependents {
+		if dependent.Type == AuditUser {
+			var user models.User
+			if err := tx.First(&user, dependent.ID).Error; err != nil {
+				return err
+			}
+			if err := deleteUser(tx, &user, true); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tx.Delete(address).Error; err != nil {
+		return err
+	}
+	return recordAudit(tx, address.OrganizationID, models.AuditDelete, AuditAddress, address.ID, address, nil)
+}
+
+// deleteUser soft deletes a user. Without cascade it fails with a DependencyError
+// while contracts name them; with cascade the contracts they are a party to are
+// deleted and they are dropped from the references of the others.
+func deleteUser(tx *gorm.DB, user *models.User, cascade bool) error {
+	dependents, err := userDependents(tx, user.ID)
+	if err != nil {
+		return err
+	}
+	if len(dependents) > 0 && !cascade {
+		return &DependencyError{Dependents: dependents}
+	}
+
+	deleted := map[uuid.UUID]bool{}
+	for _, dependent := range dependents {
+		if dependent.Relation != DependentReference {
+			if err := deleteContractByID(tx, dependent.ID); err != nil {
+				return err
+			}
+			deleted[dependent.ID] = true
+		}
+	}
+	for _, dependent := range dependents {
+		if dependent.Relation == DependentReference && !deleted[dependent.ID] {
+			if err := removeContractReference(tx, dependent.ID, user.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tx.Delete(user).Error; err != nil {
+		return err
+	}
+	return recordAudit(tx, user.OrganizationID, models.AuditDelete, AuditUser, user.ID, user, nil)
+}
+
+// deleteContractByID soft deletes a contract
+func deleteContractByID(tx *gorm.DB, id uuid.UUID) error {
+	var contract models.Contract
+	if err := tx.First(&contract, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("contract not found")
+		}
+		return err
+	}
+
+	referenceIDs, err := contractReferenceIDs(tx, contract.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Delete(&contract).Error; err != nil {
+		return err
+	}
+	return auditContract(tx, models.AuditDelete, newContractAuditRecord(contract, referenceIDs), nil)
+}
+
+// removeContractReference drops a user from the references of a contract
+func removeContractReference(tx *gorm.DB, contractID, referenceID uuid.UUID) error {
+	contract, err := lockContract(tx, contractID)
+	if err != nil {
+		return err
+	}
+
+	referenceIDs, err := contractReferenceIDs(tx, contractID)
+	if err != nil {
+		return err
+	}
+
+	var remaining []uuid.UUID
+	for _, id := range referenceIDs {
+		if id != referenceID {
+			remaining = append(remaining, id)
+		}
+	}
+
+	if err := tx.Where("contractid = ? AND referenceid = ?", contractID, referenceID).Delete(&models.ContractReference{}).Error; err != nil {
+		return err
+	}
+	return auditContract(tx, models.AuditUpdate, newContractAuditRecord(*contract, referenceIDs), newContractAuditRecord(*contract, remaining))
+}
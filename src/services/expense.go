@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/edfloreshz/rent-contracts/src/dto"
+	"github.com/edfloreshz/rent-contracts/src/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ExpenseService struct {
+	db  *gorm.DB
+	uow *UnitOfWork
+}
+
+func NewExpenseService(db *gorm.DB) *ExpenseService {
+	return &ExpenseService{
+		db:  db,
+		uow: NewUnitOfWork(db),
+	}
+}
+
+func (s *ExpenseService) CreateExpense(ctx context.Context, req *dto.CreateExpenseRequest) (*models.Expense, error) {
+	description := strings.TrimSpace(req.Description)
+	if req.Amount <= 0 || description == "" {
+		return nil, errors.New("a positive amount and a description are required")
+	}
+
+	var expense *models.Expense
+	err := s.uow.Do(ctx, func(tx *gorm.DB) error {
+		var landlord models.User
+		if err := tx.Select("id", "organizationid").First(&landlord, req.LandlordID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("landlord not found")
+			}
+			return err
+		}
+		if req.AddressID != nil {
+			found, err := exists(tx.Model(&models.Address{}).Where("id = ?", *req.AddressID))
+			if err != nil {
+				return err
+			}
+			if !found {
+				return errors.New("address not found")
+			}
+		}
+
+		expense = &models.Expense{
+			LandlordID:  landlord.ID,
+			AddressID:   req.AddressID,
+			Amount:      req.Amount,
+			PaidAt:      req.PaidAt,
+			Description: description,
+		}
+		if err := tx.Create(expense).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, landlord.OrganizationID, models.AuditCreate, AuditExpense, expense.ID, nil, expense)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return expense, nil
+}
+
+// GetExpenses returns the expenses, optionally of one landlord, the latest first
+func (s *ExpenseService) GetExpenses(ctx context.Context, landlordID *uuid.UUID) ([]models.Expense, error) {
+	query := s.db.WithContext(ctx).Model(&models.Expense{})
+	if landlordID != nil {
+		query = query.Where("landlordid = ?", *landlordID)
+	}
+
+	var expenses []models.Expense
+	if err := query.Order("paidat DESC, createdat DESC").Find(&expenses).Error; err != nil {
+		return nil, err
+	}
+	return expenses, nil
+}
+
+func (s *ExpenseService) DeleteExpense(ctx context.Context, id uuid.UUID) error {
+	return s.uow.Do(ctx, func(tx *gorm.DB) error {
+		var expense models.Expense
+		if err := tx.First(&expense, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("expense not found")
+			}
+			return err
+		}
+
+		if err := tx.Delete(&expense).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, expense.OrganizationID, models.AuditDelete, AuditExpense, expense.ID, &expense, nil)
+	})
+}
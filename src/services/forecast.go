@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/edfloreshz/rent-contracts/src/models"
+	"github.com/google/uuid"
+)
+
+// maxForecastMonths limits how far ahead a cash flow forecast can look
+const maxForecastMonths = 60
+
+// ErrInvalidForecast is returned when the horizon or assumptions of a forecast are out of range
+var ErrInvalidForecast = errors.New("invalid forecast")
+
+// ForecastAssumptions describe what happens to a contract once its last version ends
+type ForecastAssumptions struct {
+	// RenewalProbability is the chance, between 0 and 1, that a tenant renews
+	RenewalProbability float64 `json:"renewalProbability"`
+	// VacancyMonths is how long a property stays empty before it is let again
+	// when its tenant does not renew
+	VacancyMonths int `json:"vacancyMonths"`
+}
+
+// CashFlowForecast is the expected rent income of the coming months
+type CashFlowForecast struct {
+	GeneratedAt   time.Time           `json:"generatedAt"`
+	Assumptions   ForecastAssumptions `json:"assumptions"`
+	Months        []CashFlowMonth     `json:"months"`
+	TotalExpected float64             `json:"totalExpected"`
+}
+
+// CashFlowMonth splits the expected rent of a month into the rent contracted by
+// the versions in force and the expected rent of renewals and new tenants.
+// ExpiringContracts counts the contracts whose last version ends in the month.
+type CashFlowMonth struct {
+	Month             string  `json:"month"`
+	Contracted        float64 `json:"contracted"`
+	Renewals          float64 `json:"renewals"`
+	Relets            float64 `json:"relets"`
+	Expected          float64 `json:"expected"`
+	ExpiringContracts int     `json:"expiringContracts"`
+}
+
+// Validate checks the assumptions are within range
+func (a ForecastAssumptions) Validate() error {
+	if a.RenewalProbability < 0 || a.RenewalProbability > 1 {
+		return fmt.Errorf("%w: renewalProbability must be between 0 and 1", ErrInvalidForecast)
+	}
+	if a.VacancyMonths < 0 {
+		return fmt.Errorf("%w: vacancyMonths must not be negative", ErrInvalidForecast)
+	}
+	return nil
+}
+
+// GetCashFlowForecast projects the rent income of the contracts that are not
+// deleted over the next months, starting with the current one. Each month gets
+// the charges due in it of the version in force then, increased by its percentage
+// on each anniversary, so a signed renewal only bills from its start. Once the
+// last version of a contract ends, unless it was terminated or has expired, the
+// contract is renewed with the RenewalProbability at its last rent plus one more
+// increase, and otherwise let again at that rent after VacancyMonths. Both keep
+// increasing yearly.
+func (s *ReportService) GetCashFlowForecast(ctx context.Context, months int, assumptions ForecastAssumptions) (*CashFlowForecast, error) {
+	if months < 1 || months > maxForecastMonths {
+		return nil, fmt.Errorf("%w: months must be between 1 and %d", ErrInvalidForecast, maxForecastMonths)
+	}
+	if err := assumptions.Validate(); err != nil {
+		return nil, err
+	}
+
+	db := s.db.WithContext(ctx)
+	var versions []models.ContractVersion
+	if err := db.
+		Joins("JOIN contracts ON contracts.id = contractversions.contractid").
+		Where("contracts.deletedat IS NULL").
+		Order("contractversions.contractid, contractversions.startdate, contractversions.versionnumber").
+		Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	var contractIDs []uuid.UUID
+	byContract := map[uuid.UUID][]models.ContractVersion{}
+	for _, version := range versions {
+		if _, ok := byContract[version.ContractID]; !ok {
+			contractIDs = append(contractIDs, version.ContractID)
+		}
+		byContract[version.ContractID] = append(byContract[version.ContractID], version)
+	}
+	terminatedAt, err := versionTerminations(db, contractIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	first := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	horizon := first.AddDate(0, months, 0)
+	forecast := &CashFlowForecast{
+		GeneratedAt: now,
+		Assumptions: assumptions,
+		Months:      make([]CashFlowMonth, months),
+	}
+	for i := range forecast.Months {
+		forecast.Months[i].Month = first.AddDate(0, i, 0).Format("2006-01")
+	}
+	// monthOf returns the forecast month t falls in, or nil when it is outside it
+	monthOf := func(t time.Time) *CashFlowMonth {
+		if t.Before(first) || !t.Before(horizon) {
+			return nil
+		}
+		return &forecast.Months[monthsBetween(first, t)]
+	}
+
+	for _, contractID := range contractIDs {
+		contractVersions := byContract[contractID]
+		for _, charge := range rentCharges(contractVersions, terminatedAt, horizon) {
+			if cashFlow := monthOf(charge.DueDate); cashFlow != nil {
+				cashFlow.Contracted += charge.Amount
+			}
+		}
+
+		last := contractVersions[len(contractVersions)-1]
+		if last.Status != models.ActiveContract {
+			continue
+		}
+		if cashFlow := monthOf(last.EndDate.AddDate(0, 0, -1)); cashFlow != nil {
+			cashFlow.ExpiringContracts++
+		}
+
+		// The renewal or the next tenant is charged from the day the last version ends
+		increase := 1 + last.RentIncreasePercentage/100
+		lastRent := last.Rent * math.Pow(increase, float64(anniversaries(last.StartDate, last.EndDate.AddDate(0, 0, -1))))
+		for elapsed := 0; ; elapsed++ {
+			dueDate := last.EndDate.AddDate(0, elapsed, 0)
+			if !dueDate.Before(horizon) {
+				break
+			}
+			cashFlow := monthOf(dueDate)
+			if cashFlow == nil {
+				continue
+			}
+			rent := lastRent * math.Pow(increase, float64(1+anniversaries(last.EndDate, dueDate)))
+			cashFlow.Renewals += assumptions.RenewalProbability * rent
+			if elapsed >= assumptions.VacancyMonths {
+				cashFlow.Relets += (1 - assumptions.RenewalProbability) * rent
+			}
+		}
+	}
+
+	for i := range forecast.Months {
+		cashFlow := &forecast.Months[i]
+		cashFlow.Expected = cashFlow.Contracted + cashFlow.Renewals + cashFlow.Relets
+		forecast.TotalExpected += cashFlow.Expected
+	}
+
+	return forecast, nil
+}
+
+// anniversaries counts the anniversaries of start up to and including t
+func anniversaries(start, t time.Time) int {
+	years := 0
+	for !start.AddDate(years+1, 0, 0).After(t) {
+		years++
+	}
+	return years
+}
+
+// monthsBetween counts the whole calendar months from the month of a to that of b
+func monthsBetween(a, b time.Time) int {
+	return (b.Year()-a.Year())*12 + int(b.Month()) - int(a.Month())
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/edfloreshz/rent-contracts/src/dto"
+	"github.com/edfloreshz/rent-contracts/src/models"
+)
+
+func TestCashFlowForecastRenewal(t *testing.T) {
+	db := testDatabase(t)
+	p := seedPortfolio(t, db, "Monterrey", 1000)
+	contracts := NewContractService(db)
+
+	now := time.Now().UTC()
+	first := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if err := db.Model(&models.ContractVersion{}).Where("id = ?", p.version.ID).Updates(map[string]interface{}{
+		"startdate":              first.AddDate(0, -2, 0),
+		"enddate":                first.AddDate(0, 2, 0),
+		"rentincreasepercentage": 0,
+	}).Error; err != nil {
+		t.Fatalf("move the version: %v", err)
+	}
+	// A renewal signed ahead becomes the current version before it starts
+	if _, err := contracts.CreateContractVersion(p.ctx, &dto.CreateContractVersionRequest{
+		ContractID: p.contract.ID,
+		Rent:       1200,
+		Business:   "Vivienda",
+		Status:     string(models.ActiveContract),
+		Type:       string(models.YearlyContract),
+		StartDate:  first.AddDate(0, 2, 0),
+		EndDate:    first.AddDate(1, 2, 0),
+	}); err != nil {
+		t.Fatalf("create renewal: %v", err)
+	}
+
+	reports := NewReportService(db)
+	forecast, err := reports.GetCashFlowForecast(p.ctx, 4, ForecastAssumptions{RenewalProbability: 0.5, VacancyMonths: 1})
+	if err != nil {
+		t.Fatalf("forecast: %v", err)
+	}
+
+	for i, want := range []float64{1000, 1000, 1200, 1200} {
+		month := forecast.Months[i]
+		if month.Contracted != want || month.Renewals != 0 || month.Relets != 0 || month.ExpiringContracts != 0 {
+			t.Errorf("month %s: got contracted %v, renewals %v, relets %v, expiring %d, want contracted %v only",
+				month.Month, month.Contracted, month.Renewals, month.Relets, month.ExpiringContracts, want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/edfloreshz/rent-contracts/src/auth"
+	"github.com/edfloreshz/rent-contracts/src/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// historyRecord is a version of a row of one of the tables whose history is kept
+type historyRecord struct {
+	ID        int64      `gorm:"column:id;primaryKey"`
+	Table     string     `gorm:"column:tablename"`
+	RowID     uuid.UUID  `gorm:"column:rowid;type:uuid"`
+	SubID     *uuid.UUID `gorm:"column:subid;type:uuid"`
+	Data      string     `gorm:"column:data;type:jsonb"`
+	ValidFrom time.Time  `gorm:"column:validfrom"`
+	ValidTo   *time.Time `gorm:"column:validto"`
+}
+
+// historyQuery selects the rows of a table as they were at a point in time. The
+// history is read with raw SQL, so the organization of the caller is checked here
+// instead of by the organization scope.
+func historyQuery(ctx context.Context, db *gorm.DB, table string, asOf time.Time) *gorm.DB {
+	query := db.WithContext(ctx).
+		Model(&historyRecord{}).
+		Table("history").
+		Select(fmt.Sprintf("(jsonb_populate_record(NULL::%s, data)).*", table)).
+		Where("tablename = ? AND validfrom <= ? AND (validto IS NULL OR validto > ?)", table, asOf, asOf)
+
+	principal, ok := auth.PrincipalFromContext(ctx)
+	switch {
+	case !ok:
+		return query.Where("1 = 0")
+	case principal.System:
+		return query
+	default:
+		// Rows without an organization of their own are reached through one that has it
+		return query.Where("(data->>'organizationid' IS NULL OR data->>'organizationid' = ?)", principal.OrganizationID.String())
+	}
+}
+
+// liveHistoryQuery is historyQuery limited to rows that were not soft deleted at that time
+func liveHistoryQuery(ctx context.Context, db *gorm.DB, table string, asOf time.Time) *gorm.DB {
+	return historyQuery(ctx, db, table, asOf).Where("data->>'deletedat' IS NULL")
+}
+
+// findAsOf scans the version of the row identified by id selected by query into dest
+func findAsOf(query *gorm.DB, id uuid.UUID, dest any) error {
+	result := query.Where("rowid = ?", id).Limit(1).Scan(dest)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// userAsOf reads a user and its address as they were at asOf. Related users are
+// read even if they were already deleted, as the documents still name them.
+func userAsOf(ctx context.Context, db *gorm.DB, id uuid.UUID, asOf time.Time, live bool) (*models.User, error) {
+	query := historyQuery(ctx, db, "users", asOf)
+	if live {
+		query = liveHistoryQuery(ctx, db, "users", asOf)
+	}
+
+	var user models.User
+	if err := findAsOf(query, id, &user); err != nil {
+		return nil, err
+	}
+	if err := findAsOf(historyQuery(ctx, db, "addresses", asOf), user.AddressID, &user.Address); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return &user, nil
+}
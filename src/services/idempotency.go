@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/edfloreshz/rent-contracts/src/auth"
+	"github.com/edfloreshz/rent-contracts/src/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// idempotencyKeyTTL is how long a stored response can be replayed before its key may be reused
+const idempotencyKeyTTL = 24 * time.Hour
+
+// idempotencyKeyLease is how long a key stays reserved for a request that has not
+// finished. Past it the request is taken as lost and the key may be reserved again.
+const idempotencyKeyLease = time.Minute
+
+var (
+	// ErrIdempotencyKeyReused is returned when a key is sent again with a different request
+	ErrIdempotencyKeyReused = errors.New("idempotency key was already used with a different request")
+
+	// ErrIdempotencyKeyInProgress is returned when the original request for a key has not finished yet
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is still in progress")
+)
+
+// IdempotentResponse is the response stored for a key so retries can replay it
+type IdempotentResponse struct {
+	StatusCode  int
+	ContentType string
+	ETag        string
+	Location    string
+	Body        []byte
+}
+
+type IdempotencyService struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyService(db *gorm.DB) *IdempotencyService {
+	return &IdempotencyService{
+		db,
+	}
+}
+
+// Begin reserves the key of the caller in ctx for a request with the given
+// fingerprint. It returns the stored record when the request was already completed
+// and its response should be replayed, or nil when the caller should process the
+// request and call Complete. Keys are scoped to the organization and the caller, so
+// the same key sent by someone else is a different key.
+func (s *IdempotencyService) Begin(ctx context.Context, key, fingerprint string) (*models.IdempotencyKey, error) {
+	callerID, err := idempotencyCaller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	db := s.db.WithContext(ctx)
+
+	// Forget expired keys and abandoned reservations so they can be reserved again
+	now := time.Now()
+	if err := db.
+		Where("callerid = ? AND key = ?", callerID, key).
+		Where("createdat < ? OR (completedat IS NULL AND createdat < ?)", now.Add(-idempotencyKeyTTL), now.Add(-idempotencyKeyLease)).
+		Delete(&models.IdempotencyKey{}).Error; err != nil {
+		return nil, err
+	}
+
+	record := &models.IdempotencyKey{
+		CallerID:    callerID,
+		Key:         key,
+		Fingerprint: fingerprint,
+	}
+
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return nil, nil
+	}
+
+	var existing models.IdempotencyKey
+	if err := db.First(&existing, "callerid = ? AND key = ?", callerID, key).Error; err != nil {
+		return nil, err
+	}
+
+	if existing.Fingerprint != fingerprint {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if !existing.Completed() {
+		return nil, ErrIdempotencyKeyInProgress
+	}
+
+	return &existing, nil
+}
+
+// Complete stores the response for a key reserved by the caller in ctx so retries
+// can replay it
+func (s *IdempotencyService) Complete(ctx context.Context, key string, response IdempotentResponse) error {
+	callerID, err := idempotencyCaller(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	return s.db.WithContext(ctx).Model(&models.IdempotencyKey{}).
+		Where("callerid = ? AND key = ?", callerID, key).
+		Updates(map[string]interface{}{
+			"statuscode":   response.StatusCode,
+			"contenttype":  response.ContentType,
+			"etag":         response.ETag,
+			"location":     response.Location,
+			"responsebody": response.Body,
+			"completedat":  now,
+		}).Error
+}
+
+// Release removes a key reserved by the caller in ctx so the request can be retried
+func (s *IdempotencyService) Release(ctx context.Context, key string) error {
+	callerID, err := idempotencyCaller(ctx)
+	if err != nil {
+		return err
+	}
+	return s.db.WithContext(ctx).Where("callerid = ? AND key = ?", callerID, key).Delete(&models.IdempotencyKey{}).Error
+}
+
+// idempotencyCaller returns who idempotency keys are scoped to: the API key a
+// request was authenticated with, or else its user
+func idempotencyCaller(ctx context.Context) (uuid.UUID, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return uuid.Nil, errors.New("idempotency keys require an authenticated caller")
+	}
+	if principal.APIKeyID != nil {
+		return *principal.APIKeyID, nil
+	}
+	return principal.UserID, nil
+}
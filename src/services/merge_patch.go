@@ -0,0 +1,141 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// applyMergePatch applies an RFC 7396 JSON merge patch to document, which must be a
+// pointer to a struct. Members set to null are cleared, which is only allowed for
+// pointer, slice and map fields. It returns the sorted JSON names of the top-level
+// fields whose value changed.
+func applyMergePatch(document interface{}, patch []byte) ([]string, error) {
+	var patchValue interface{}
+	if err := json.Unmarshal(patch, &patchValue); err != nil {
+		return nil, err
+	}
+
+	patchObject, ok := patchValue.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("merge patch must be a JSON object")
+	}
+
+	original, err := toJSONObject(document)
+	if err != nil {
+		return nil, err
+	}
+
+	nullable := nullableFields(reflect.TypeOf(document).Elem())
+	for name, value := range patchObject {
+		allowNull, known := nullable[name]
+		if !known {
+			return nil, fmt.Errorf("unknown field %q", name)
+		}
+		if value == nil && !allowNull {
+			return nil, fmt.Errorf("field %q cannot be null", name)
+		}
+	}
+
+	merged := mergePatch(copyJSONObject(original), patchObject).(map[string]interface{})
+
+	encoded, err := json.Marshal(merged)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode into a fresh value so members removed by the patch become zero values
+	result := reflect.New(reflect.TypeOf(document).Elem())
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(result.Interface()); err != nil {
+		return nil, err
+	}
+
+	updated, err := toJSONObject(result.Interface())
+	if err != nil {
+		return nil, err
+	}
+
+	var changed []string
+	for name, value := range updated {
+		if !reflect.DeepEqual(original[name], value) {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+
+	reflect.ValueOf(document).Elem().Set(result.Elem())
+	return changed, nil
+}
+
+// mergePatch implements the MergePatch algorithm from RFC 7396
+func mergePatch(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = map[string]interface{}{}
+	}
+
+	for name, value := range patchObject {
+		if value == nil {
+			delete(targetObject, name)
+		} else {
+			targetObject[name] = mergePatch(targetObject[name], value)
+		}
+	}
+
+	return targetObject
+}
+
+// toJSONObject converts a struct into its generic JSON object representation
+func toJSONObject(value interface{}) (map[string]interface{}, error) {
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var object map[string]interface{}
+	if err := json.Unmarshal(encoded, &object); err != nil {
+		return nil, err
+	}
+
+	return object, nil
+}
+
+// copyJSONObject returns a shallow copy of a JSON object
+func copyJSONObject(object map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(object))
+	for name, value := range object {
+		copied[name] = value
+	}
+	return copied
+}
+
+// nullableFields maps the JSON names of a struct's fields to whether they accept null
+func nullableFields(t reflect.Type) map[string]bool {
+	fields := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		switch field.Type.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map:
+			fields[name] = true
+		default:
+			fields[name] = false
+		}
+	}
+	return fields
+}
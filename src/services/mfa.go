@@ -0,0 +1,405 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/edfloreshz/rent-contracts/src/auth"
+	"github.com/edfloreshz/rent-contracts/src/dto"
+	"github.com/edfloreshz/rent-contracts/src/models"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// mfaTokenTTL is how long a login challenge can be answered
+	mfaTokenTTL = 5 * time.Minute
+
+	// recoveryCodeCount is how many recovery codes are generated at a time
+	recoveryCodeCount = 10
+
+	// recoveryCodeLength is the number of characters in a recovery code, excluding the separator
+	recoveryCodeLength = 10
+
+	// maxMFAAttempts is how many wrong two-factor codes in a row lock verification
+	maxMFAAttempts = 5
+
+	// mfaLockout is how long verification stays locked after too many wrong codes
+	mfaLockout = 15 * time.Minute
+)
+
+var (
+	// ErrInvalidMFACode is returned when a TOTP or recovery code does not match
+	ErrInvalidMFACode = errors.New("invalid two-factor code")
+
+	// ErrMFANotEnrolled is returned when an operation needs two-factor authentication to be set up
+	ErrMFANotEnrolled = errors.New("two-factor authentication is not enabled")
+
+	// ErrMFALocked is returned while verification is locked after too many wrong codes
+	ErrMFALocked = errors.New("too many invalid two-factor codes, try again later")
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// VerifyLogin answers a login challenge with a TOTP or recovery code and starts the
+// session. Each challenge starts at most one session.
+func (s *AuthService) VerifyLogin(req *dto.VerifyLoginRequest) (*dto.TokenResponse, error) {
+	claims, err := s.parseToken(req.MFAToken, mfaTokenType)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	challengeID, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var tokens *dto.TokenResponse
+	var verifyErr error
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		credential, err := lockCredential(tx, userID)
+		if err != nil {
+			return err
+		}
+		if !credential.TOTPEnabled() {
+			return ErrInvalidToken
+		}
+
+		answered, err := exists(tx.Model(&models.Session{}).Where("mfachallengeid = ?", challengeID))
+		if err != nil {
+			return err
+		}
+		if answered {
+			return ErrInvalidToken
+		}
+
+		if verifyErr = checkSecondFactor(tx, credential, req.Code); verifyErr != nil {
+			// Commit the failed attempt
+			return nil
+		}
+
+		now := time.Now()
+		tokens, err = s.startSession(tx, userID, &now, &challengeID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if verifyErr != nil {
+		return nil, verifyErr
+	}
+
+	return tokens, nil
+}
+
+// EnrollTOTP generates a new TOTP secret for the caller. It is not enforced until
+// the caller confirms it with ConfirmTOTP.
+func (s *AuthService) EnrollTOTP(ctx context.Context) (*dto.TOTPEnrolmentResponse, error) {
+	principal, err := interactivePrincipal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var credential models.UserCredential
+	if err := s.db.WithContext(ctx).Joins("User").First(&credential, "usercredentials.userid = ?", principal.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("a password must be set before enabling two-factor authentication")
+		}
+		return nil, err
+	}
+	if credential.TOTPEnabled() {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.WithContext(ctx).Model(&credential).Updates(map[string]interface{}{
+		"totpsecret":   secret,
+		"totplaststep": 0,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	uri := totpURI(secret, credential.User.Email)
+	qrCode, err := totpQRCode(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.TOTPEnrolmentResponse{
+		Secret: secret,
+		URI:    uri,
+		QRCode: qrCode,
+	}, nil
+}
+
+// ConfirmTOTP enables two-factor authentication once the caller proves their
+// authenticator produces valid codes, and returns a fresh set of recovery codes
+func (s *AuthService) ConfirmTOTP(ctx context.Context, code string) (*dto.RecoveryCodesResponse, error) {
+	principal, err := interactivePrincipal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		credential, err := lockCredential(tx, principal.UserID)
+		if err != nil {
+			return err
+		}
+		if credential.TOTPSecret == nil {
+			return errors.New("two-factor enrolment has not been started")
+		}
+		if credential.TOTPEnabled() {
+			return errors.New("two-factor authentication is already enabled")
+		}
+
+		now := time.Now()
+		step, ok := verifyTOTP(*credential.TOTPSecret, code, now, credential.TOTPLastStep)
+		if !ok {
+			return ErrInvalidMFACode
+		}
+
+		if err := tx.Model(credential).Updates(map[string]interface{}{
+			"totpenabledat": now,
+			"totplaststep":  step,
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := markSessionVerified(tx, principal.SessionID, now); err != nil {
+			return err
+		}
+
+		codes, err = replaceRecoveryCodes(tx, principal.UserID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// VerifyMFA re-verifies the caller's second factor so the session may perform sensitive operations
+func (s *AuthService) VerifyMFA(ctx context.Context, code string) error {
+	principal, err := interactivePrincipal(ctx)
+	if err != nil {
+		return err
+	}
+
+	var verifyErr error
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		credential, err := lockCredential(tx, principal.UserID)
+		if err != nil {
+			return err
+		}
+		if !credential.TOTPEnabled() {
+			return ErrMFANotEnrolled
+		}
+
+		if verifyErr = checkSecondFactor(tx, credential, code); verifyErr != nil {
+			// Commit the failed attempt
+			return nil
+		}
+
+		return markSessionVerified(tx, principal.SessionID, time.Now())
+	})
+	if err != nil {
+		return err
+	}
+	return verifyErr
+}
+
+// RegenerateRecoveryCodes replaces the caller's recovery codes
+func (s *AuthService) RegenerateRecoveryCodes(ctx context.Context) (*dto.RecoveryCodesResponse, error) {
+	principal, err := interactivePrincipal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var codes []string
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		credential, err := lockCredential(tx, principal.UserID)
+		if err != nil {
+			return err
+		}
+		if !credential.TOTPEnabled() {
+			return ErrMFANotEnrolled
+		}
+
+		codes, err = replaceRecoveryCodes(tx, principal.UserID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// DisableTOTP turns off two-factor authentication for the caller
+func (s *AuthService) DisableTOTP(ctx context.Context) error {
+	principal, err := interactivePrincipal(ctx)
+	if err != nil {
+		return err
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.UserCredential{}).Where("userid = ?", principal.UserID).Updates(map[string]interface{}{
+			"totpsecret":    nil,
+			"totpenabledat": nil,
+			"totplaststep":  0,
+		}).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("userid = ?", principal.UserID).Delete(&models.RecoveryCode{}).Error
+	})
+}
+
+// issueMFAChallenge signs a short-lived token that proves the password was verified
+func (s *AuthService) issueMFAChallenge(userID uuid.UUID) (*dto.MFAChallengeResponse, error) {
+	now := time.Now()
+
+	token, err := s.signToken(&tokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Subject:   userID.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(mfaTokenTTL)),
+		},
+		TokenType: mfaTokenType,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.MFAChallengeResponse{
+		MFARequired: true,
+		MFAToken:    token,
+		ExpiresIn:   int(mfaTokenTTL.Seconds()),
+	}, nil
+}
+
+// interactivePrincipal returns the caller when it is a user signed in with a session
+func interactivePrincipal(ctx context.Context) (*auth.Principal, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok || principal.System || principal.APIKeyID != nil {
+		return nil, errors.New("two-factor authentication requires a user session")
+	}
+	return principal, nil
+}
+
+// lockCredential loads the credential of a user for update
+func lockCredential(tx *gorm.DB, userID uuid.UUID) (*models.UserCredential, error) {
+	var credential models.UserCredential
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&credential, "userid = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMFANotEnrolled
+		}
+		return nil, err
+	}
+	return &credential, nil
+}
+
+// checkSecondFactor verifies a code against the locked credential and counts the
+// failures, locking verification for mfaLockout after maxMFAAttempts wrong codes in
+// a row. Failures are recorded in tx, so callers commit it before returning the error.
+func checkSecondFactor(tx *gorm.DB, credential *models.UserCredential, code string) error {
+	now := time.Now()
+	if credential.MFALockedUntil != nil && now.Before(*credential.MFALockedUntil) {
+		return ErrMFALocked
+	}
+
+	err := verifySecondFactor(tx, credential, code)
+	if errors.Is(err, ErrInvalidMFACode) {
+		updates := map[string]interface{}{"mfafailedattempts": credential.MFAFailedAttempts + 1}
+		if credential.MFAFailedAttempts+1 >= maxMFAAttempts {
+			updates = map[string]interface{}{"mfafailedattempts": 0, "mfalockeduntil": now.Add(mfaLockout)}
+		}
+		if err := tx.Model(credential).Updates(updates).Error; err != nil {
+			return err
+		}
+		return ErrInvalidMFACode
+	}
+	if err != nil {
+		return err
+	}
+
+	if credential.MFAFailedAttempts > 0 || credential.MFALockedUntil != nil {
+		return tx.Model(credential).Updates(map[string]interface{}{"mfafailedattempts": 0, "mfalockeduntil": nil}).Error
+	}
+	return nil
+}
+
+// verifySecondFactor accepts either a current TOTP code or an unused recovery code
+func verifySecondFactor(tx *gorm.DB, credential *models.UserCredential, code string) error {
+	if step, ok := verifyTOTP(*credential.TOTPSecret, code, time.Now(), credential.TOTPLastStep); ok {
+		return tx.Model(credential).Update("totplaststep", step).Error
+	}
+
+	result := tx.Model(&models.RecoveryCode{}).
+		Where("userid = ? AND codehash = ? AND usedat IS NULL", credential.UserID, hashRecoveryCode(code)).
+		Update("usedat", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+func markSessionVerified(tx *gorm.DB, sessionID uuid.UUID, at time.Time) error {
+	return tx.Model(&models.Session{}).Where("id = ?", sessionID).Update("mfaverifiedat", at).Error
+}
+
+// replaceRecoveryCodes discards the user's recovery codes and returns a new set in plaintext
+func replaceRecoveryCodes(tx *gorm.DB, userID uuid.UUID) ([]string, error) {
+	if err := tx.Where("userid = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	records := make([]models.RecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		raw := make([]byte, recoveryCodeLength*5/8)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(recoveryCodeEncoding.EncodeToString(raw))
+		codes[i] = code[:recoveryCodeLength/2] + "-" + code[recoveryCodeLength/2:]
+		records[i] = models.RecoveryCode{
+			UserID:   userID,
+			CodeHash: hashRecoveryCode(code),
+		}
+	}
+
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// hashRecoveryCode normalizes a recovery code as typed by the user and hashes it
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/edfloreshz/rent-contracts/src/dto"
+	"github.com/edfloreshz/rent-contracts/src/models"
+)
+
+func TestVerifyLogin(t *testing.T) {
+	db := testDatabase(t)
+	p := seedPortfolio(t, db, "Monterrey", 1000)
+	authService := NewAuthService(db, []byte("test-secret"), time.Minute, time.Hour)
+
+	const password = "correct horse"
+	if err := authService.SetPassword(p.ctx, p.landlord.ID, password); err != nil {
+		t.Fatalf("set password: %v", err)
+	}
+	secret, err := newTOTPSecret()
+	if err != nil {
+		t.Fatalf("generate secret: %v", err)
+	}
+	if err := db.Model(&models.UserCredential{}).Where("userid = ?", p.landlord.ID).Updates(map[string]interface{}{
+		"totpsecret":    secret,
+		"totpenabledat": time.Now(),
+	}).Error; err != nil {
+		t.Fatalf("enable two-factor authentication: %v", err)
+	}
+
+	login := func() string {
+		t.Helper()
+		_, challenge, err := authService.Login(&dto.LoginRequest{Email: p.landlord.Email, Password: password})
+		if err != nil || challenge == nil {
+			t.Fatalf("login: got challenge %v, error %v", challenge, err)
+		}
+		return challenge.MFAToken
+	}
+
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("decode secret: %v", err)
+	}
+	step := time.Now().Unix() / int64(totpPeriod.Seconds())
+	code := func(offset int64) string {
+		return totpCode(key, step+offset)
+	}
+
+	t.Run("a challenge starts one session", func(t *testing.T) {
+		challenge := login()
+		if _, err := authService.VerifyLogin(&dto.VerifyLoginRequest{MFAToken: challenge, Code: code(0)}); err != nil {
+			t.Fatalf("verify login: %v", err)
+		}
+		if _, err := authService.VerifyLogin(&dto.VerifyLoginRequest{MFAToken: challenge, Code: code(1)}); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("answer the challenge again: got %v, want %v", err, ErrInvalidToken)
+		}
+	})
+
+	t.Run("wrong codes lock verification", func(t *testing.T) {
+		// A code of a step outside the accepted window is always wrong
+		wrong := code(10)
+		challenge := login()
+		for i := 0; i < maxMFAAttempts; i++ {
+			if _, err := authService.VerifyLogin(&dto.VerifyLoginRequest{MFAToken: challenge, Code: wrong}); !errors.Is(err, ErrInvalidMFACode) {
+				t.Fatalf("attempt %d: got %v, want %v", i+1, err, ErrInvalidMFACode)
+			}
+		}
+		if _, err := authService.VerifyLogin(&dto.VerifyLoginRequest{MFAToken: challenge, Code: code(1)}); !errors.Is(err, ErrMFALocked) {
+			t.Errorf("valid code after %d wrong ones: got %v, want %v", maxMFAAttempts, err, ErrMFALocked)
+		}
+	})
+}
@@ -0,0 +1,67 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/edfloreshz/rent-contracts/src/auth"
+	"github.com/edfloreshz/rent-contracts/src/dto"
+	"github.com/edfloreshz/rent-contracts/src/models"
+	"gorm.io/gorm"
+)
+
+type OrganizationService struct {
+	db *gorm.DB
+}
+
+func NewOrganizationService(db *gorm.DB) *OrganizationService {
+	return &OrganizationService{
+		db: db,
+	}
+}
+
+// GetCurrentOrganization returns the organization the caller is a member of
+func (s *OrganizationService) GetCurrentOrganization(ctx context.Context) (*models.Organization, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok || principal.System {
+		return nil, errors.New("organization not found")
+	}
+
+	var organization models.Organization
+	if err := s.db.WithContext(ctx).First(&organization, principal.OrganizationID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("organization not found")
+		}
+		return nil, err
+	}
+
+	return &organization, nil
+}
+
+// UpdateCurrentOrganization renames the organization the caller is a member of and
+// sets its management commission when given
+func (s *OrganizationService) UpdateCurrentOrganization(ctx context.Context, req *dto.UpdateOrganizationRequest) (*models.Organization, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, errors.New("name is required")
+	}
+	if req.ManagementCommission != nil && (*req.ManagementCommission < 0 || *req.ManagementCommission > 100) {
+		return nil, errors.New("managementCommission must be between 0 and 100")
+	}
+
+	organization, err := s.GetCurrentOrganization(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{"name": name}
+	if req.ManagementCommission != nil {
+		updates["managementcommission"] = *req.ManagementCommission
+	}
+	if err := s.db.WithContext(ctx).Model(organization).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+
+	return s.GetCurrentOrganization(ctx)
+}
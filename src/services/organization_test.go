@@ -0,0 +1,619 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/edfloreshz/rent-contracts/src/auth"
+	"github.com/edfloreshz/rent-contracts/src/database"
+	"github.com/edfloreshz/rent-contracts/src/dto"
+	"github.com/edfloreshz/rent-contracts/src/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDatabase creates the schema of scripts/init.sql in a schema of its own in
+// the database named by TEST_DATABASE_URL and drops it once the test is done.
+// Tests that need a database are skipped when the variable is not set.
+func testDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	config, err := pgx.ParseConfig(databaseURL)
+	if err != nil {
+		t.Fatalf("parse TEST_DATABASE_URL: %v", err)
+	}
+
+	schema := "test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	admin := stdlib.OpenDB(*config)
+	if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+			t.Errorf("drop schema: %v", err)
+		}
+		admin.Close()
+	})
+
+	scoped := config.Copy()
+	scoped.RuntimeParams["search_path"] = schema + ", public"
+	conn := stdlib.OpenDB(*scoped)
+	t.Cleanup(func() { conn.Close() })
+
+	script, err := os.ReadFile("../../scripts/init.sql")
+	if err != nil {
+		t.Fatalf("read init.sql: %v", err)
+	}
+	if _, err := conn.Exec(string(script)); err != nil {
+		t.Fatalf("run init.sql: %v", err)
+	}
+
+	return openTestDatabase(t, conn)
+}
+
+func openTestDatabase(t *testing.T, conn *sql.DB) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err := database.RegisterOrganizationScope(db); err != nil {
+		t.Fatalf("register organization scope: %v", err)
+	}
+	return db
+}
+
+// portfolio is an organization with a leased property, as seen by one of its admins
+type portfolio struct {
+	ctx          context.Context
+	organization models.Organization
+	property     *models.Address
+	home         *models.Address
+	landlord     *models.User
+	tenant       *models.User
+	contract     *models.Contract
+	version      *models.ContractVersion
+	payment      *models.Payment
+	expense      *models.Expense
+}
+
+// seedPortfolio creates an organization and leases a property in it through the
+// services, acting as an admin of the organization
+func seedPortfolio(t *testing.T, db *gorm.DB, name string, rent float64) *portfolio {
+	t.Helper()
+
+	s := &portfolio{organization: models.Organization{Name: name}}
+	if err := db.WithContext(auth.WithPrincipal(context.Background(), auth.System)).Create(&s.organization).Error; err != nil {
+		t.Fatalf("create organization %s: %v", name, err)
+	}
+	s.ctx = auth.WithPrincipal(context.Background(), &auth.Principal{
+		UserID:         uuid.New(),
+		OrganizationID: s.organization.ID,
+		Type:           models.AdminUser,
+	})
+
+	addresses := NewAddressService(db)
+	users := NewUserService(db)
+	contracts := NewContractService(db)
+	payments := NewPaymentService(db)
+	expenses := NewExpenseService(db)
+
+	var err error
+	address := func(addressType models.AddressType, street string) *models.Address {
+		address, err := addresses.CreateAddress(s.ctx, &dto.CreateAddressRequest{
+			Type:         string(addressType),
+			Street:       street,
+			Number:       "10",
+			Neighborhood: "Centro",
+			City:         name,
+			State:        "Nuevo León",
+			ZipCode:      "64000",
+			Country:      "México",
+		})
+		if err != nil {
+			t.Fatalf("create address in %s: %v", name, err)
+		}
+		return address
+	}
+	s.property = address(models.PropertyAddress, "Av. Constitución")
+	s.home = address(models.TenantAddress, "Calle Hidalgo")
+
+	user := func(userType models.UserType, firstName string) *models.User {
+		user, err := users.CreateUser(s.ctx, &dto.CreateUserRequest{
+			Type:      string(userType),
+			AddressID: s.home.ID,
+			FirstName: firstName,
+			LastName:  name,
+			Email:     strings.ToLower(firstName+"@"+name) + ".example",
+			Phone:     "8112345678",
+		})
+		if err != nil {
+			t.Fatalf("create user in %s: %v", name, err)
+		}
+		return user
+	}
+	s.landlord = user(models.AdminUser, "Landlord")
+	s.tenant = user(models.TenantUser, "Tenant")
+
+	s.contract, err = contracts.CreateContract(s.ctx, &dto.CreateContractRequest{
+		LandlordID: s.landlord.ID,
+		TenantID:   s.tenant.ID,
+		AddressID:  s.property.ID,
+		Deposit:    rent,
+	})
+	if err != nil {
+		t.Fatalf("create contract in %s: %v", name, err)
+	}
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	s.version, err = contracts.CreateContractVersion(s.ctx, &dto.CreateContractVersionRequest{
+		ContractID:             s.contract.ID,
+		Rent:                   rent,
+		RentIncreasePercentage: 5,
+		Business:               "Vivienda",
+		Status:                 string(models.ActiveContract),
+		Type:                   string(models.YearlyContract),
+		StartDate:              today.AddDate(0, -2, 0),
+		EndDate:                today.AddDate(0, 1, 0),
+	})
+	if err != nil {
+		t.Fatalf("create contract version in %s: %v", name, err)
+	}
+
+	s.payment, err = payments.CreatePayment(s.ctx, s.contract.ID, &dto.CreatePaymentRequest{
+		Amount: rent,
+		PaidAt: today,
+	})
+	if err != nil {
+		t.Fatalf("create payment in %s: %v", name, err)
+	}
+
+	s.expense, err = expenses.CreateExpense(s.ctx, &dto.CreateExpenseRequest{
+		LandlordID:  s.landlord.ID,
+		AddressID:   &s.property.ID,
+		Amount:      100,
+		PaidAt:      today,
+		Description: "Plumbing",
+	})
+	if err != nil {
+		t.Fatalf("create expense in %s: %v", name, err)
+	}
+
+	return s
+}
+
+func TestOrganizationIsolation(t *testing.T) {
+	db := testDatabase(t)
+	a := seedPortfolio(t, db, "Monterrey", 1000)
+	b := seedPortfolio(t, db, "Guadalajara", 2500)
+
+	addresses := NewAddressService(db)
+	users := NewUserService(db)
+	contracts := NewContractService(db)
+	payments := NewPaymentService(db)
+	expenses := NewExpenseService(db)
+	statistics := NewStatisticsService(db)
+	reports := NewReportService(db)
+
+	t.Run("create assigns the organization of the principal", func(t *testing.T) {
+		for _, s := range []*portfolio{a, b} {
+			organizationIDs := map[string]uuid.UUID{
+				"property": s.property.OrganizationID,
+				"home":     s.home.OrganizationID,
+				"landlord": s.landlord.OrganizationID,
+				"tenant":   s.tenant.OrganizationID,
+				"contract": s.contract.OrganizationID,
+				"payment":  s.payment.OrganizationID,
+				"expense":  s.expense.OrganizationID,
+			}
+			for record, organizationID := range organizationIDs {
+				if organizationID != s.organization.ID {
+					t.Errorf("%s of %s created in organization %s", record, s.organization.Name, organizationID)
+				}
+			}
+		}
+	})
+
+	t.Run("create cannot link records of another organization", func(t *testing.T) {
+		if _, err := contracts.CreateContract(b.ctx, &dto.CreateContractRequest{
+			LandlordID: a.landlord.ID,
+			TenantID:   a.tenant.ID,
+			AddressID:  a.property.ID,
+		}); err == nil {
+			t.Error("contract created with the parties and property of another organization")
+		}
+		if _, err := payments.CreatePayment(b.ctx, a.contract.ID, &dto.CreatePaymentRequest{Amount: 1, PaidAt: time.Now()}); err == nil {
+			t.Error("payment recorded towards the contract of another organization")
+		}
+		if _, err := contracts.CreateContractVersion(b.ctx, &dto.CreateContractVersionRequest{
+			ContractID: a.contract.ID, Rent: 1, Business: "Vivienda",
+			Status: string(models.ActiveContract), Type: string(models.YearlyContract),
+			StartDate: time.Now(), EndDate: time.Now().AddDate(1, 0, 0),
+		}); err == nil {
+			t.Error("version added to the contract of another organization")
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		for _, pair := range [][2]*portfolio{{a, b}, {b, a}} {
+			own, other := pair[0], pair[1]
+
+			addressList, err := addresses.GetAllAddresses(own.ctx, nil)
+			if err != nil {
+				t.Fatalf("list addresses: %v", err)
+			}
+			assertIDs(t, "addresses", addressIDs(addressList), own.property.ID, own.home.ID)
+
+			userList, err := users.GetAllUsers(own.ctx)
+			if err != nil {
+				t.Fatalf("list users: %v", err)
+			}
+			assertIDs(t, "users", userIDs(userList), own.landlord.ID, own.tenant.ID)
+
+			contractList, err := contracts.GetAllContracts(own.ctx)
+			if err != nil {
+				t.Fatalf("list contracts: %v", err)
+			}
+			assertIDs(t, "contracts", contractIDs(contractList), own.contract.ID)
+
+			expenseList, err := expenses.GetExpenses(own.ctx, nil)
+			if err != nil {
+				t.Fatalf("list expenses: %v", err)
+			}
+			if len(expenseList) != 1 || expenseList[0].ID != own.expense.ID {
+				t.Errorf("%s lists expenses %v", own.organization.Name, expenseList)
+			}
+
+			versions, err := contracts.GetContractVersionsByContractID(own.ctx, other.contract.ID)
+			if err != nil {
+				t.Fatalf("list contract versions: %v", err)
+			}
+			if len(versions) != 0 {
+				t.Errorf("%s lists the versions of a contract of %s", own.organization.Name, other.organization.Name)
+			}
+
+			if paymentList, err := payments.GetPayments(own.ctx, other.contract.ID); err == nil && len(paymentList) != 0 {
+				t.Errorf("%s lists the payments of a contract of %s", own.organization.Name, other.organization.Name)
+			}
+			if charges, err := payments.GetCharges(own.ctx, other.contract.ID); err == nil && len(charges) != 0 {
+				t.Errorf("%s lists the charges of a contract of %s", own.organization.Name, other.organization.Name)
+			}
+		}
+	})
+
+	t.Run("get", func(t *testing.T) {
+		if _, err := addresses.GetAddressByID(a.ctx, b.property.ID); err == nil {
+			t.Error("got the address of another organization")
+		}
+		if _, err := users.GetUserByID(a.ctx, b.tenant.ID); err == nil {
+			t.Error("got the user of another organization")
+		}
+		if _, err := contracts.GetContractByID(a.ctx, b.contract.ID); err == nil {
+			t.Error("got the contract of another organization")
+		}
+		if _, err := contracts.GetContractVersionByID(a.ctx, b.version.ID); err == nil {
+			t.Error("got the contract version of another organization")
+		}
+		if _, err := contracts.GetContractByID(b.ctx, b.contract.ID); err != nil {
+			t.Errorf("get own contract: %v", err)
+		}
+	})
+
+	t.Run("history", func(t *testing.T) {
+		asOf := time.Now()
+		if _, err := addresses.GetAddressAsOf(a.ctx, b.property.ID, asOf); err == nil {
+			t.Error("got the past address of another organization")
+		}
+		if _, err := users.GetUserAsOf(a.ctx, b.tenant.ID, asOf); err == nil {
+			t.Error("got the past user of another organization")
+		}
+		if _, err := contracts.GetContractAsOf(a.ctx, b.contract.ID, asOf); err == nil {
+			t.Error("got the past contract of another organization")
+		}
+		if versions, err := contracts.GetContractVersionsAsOf(a.ctx, b.contract.ID, asOf); err == nil && len(versions) != 0 {
+			t.Error("got the past versions of a contract of another organization")
+		}
+		if _, err := contracts.GetContractDocument(a.ctx, b.contract.ID, nil, &asOf); err == nil {
+			t.Error("rendered the past document of a contract of another organization")
+		}
+	})
+
+	t.Run("statistics", func(t *testing.T) {
+		for _, s := range []*portfolio{a, b} {
+			stats, err := statistics.GetOverallContractStatistics(s.ctx, &StatisticsFilter{})
+			if err != nil {
+				t.Fatalf("statistics: %v", err)
+			}
+			if stats.TotalContracts != 1 || stats.ActiveContracts != 1 || stats.TotalProperties != 1 || stats.TotalTenants != 1 {
+				t.Errorf("%s counts %d contracts, %d active, %d properties and %d tenants, want 1 of each",
+					s.organization.Name, stats.TotalContracts, stats.ActiveContracts, stats.TotalProperties, stats.TotalTenants)
+			}
+			if stats.MonthlyRevenue != s.version.Rent {
+				t.Errorf("%s has a monthly revenue of %.2f, want %.2f", s.organization.Name, stats.MonthlyRevenue, s.version.Rent)
+			}
+		}
+
+		// Filtering by a landlord of another organization finds nothing
+		stats, err := statistics.GetOverallContractStatistics(a.ctx, &StatisticsFilter{LandlordID: &b.landlord.ID})
+		if err != nil {
+			t.Fatalf("statistics: %v", err)
+		}
+		if stats.TotalContracts != 0 || stats.MonthlyRevenue != 0 {
+			t.Errorf("statistics of the landlord of another organization: %d contracts, %.2f revenue", stats.TotalContracts, stats.MonthlyRevenue)
+		}
+	})
+
+	t.Run("reports", func(t *testing.T) {
+		for _, pair := range [][2]*portfolio{{a, b}, {b, a}} {
+			own, other := pair[0], pair[1]
+
+			rentRoll, err := reports.GetRentRoll(own.ctx)
+			if err != nil {
+				t.Fatalf("rent roll: %v", err)
+			}
+			if len(rentRoll.Rows) != 1 || rentRoll.Rows[0].PropertyID != own.property.ID {
+				t.Errorf("%s rent roll lists %v", own.organization.Name, rentRoll.Rows)
+			}
+
+			expirations, err := reports.GetLeaseExpirations(own.ctx)
+			if err != nil {
+				t.Fatalf("lease expirations: %v", err)
+			}
+			for _, expiration := range expirations.Contracts {
+				if expiration.ContractID == other.contract.ID {
+					t.Errorf("%s lease expirations list a contract of %s", own.organization.Name, other.organization.Name)
+				}
+			}
+
+			aging, err := reports.GetDelinquencyAging(own.ctx, AgingByLandlord)
+			if err != nil {
+				t.Fatalf("aging: %v", err)
+			}
+			for _, group := range aging.Groups {
+				if group.ID == other.landlord.ID {
+					t.Errorf("%s aging lists a landlord of %s", own.organization.Name, other.organization.Name)
+				}
+			}
+
+			taxes, err := reports.GetTaxSummary(own.ctx, time.Now().UTC().Year(), nil)
+			if err != nil {
+				t.Fatalf("tax summary: %v", err)
+			}
+			if len(taxes.Landlords) != 1 || taxes.Landlords[0].LandlordID != own.landlord.ID || taxes.Landlords[0].RentIncome != own.payment.Amount {
+				t.Errorf("%s tax summary lists %v", own.organization.Name, taxes.Landlords)
+			}
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		street := "Calle Falsa"
+		if _, err := addresses.UpdateAddress(a.ctx, b.property.ID, &dto.UpdateAddressRequest{Street: &street}, nil); err == nil {
+			t.Error("updated the address of another organization")
+		}
+		phone := "0000000000"
+		if _, err := users.UpdateUser(a.ctx, b.tenant.ID, &dto.UpdateUserRequest{Phone: &phone}, nil); err == nil {
+			t.Error("updated the user of another organization")
+		}
+		deposit := 1.0
+		if _, err := contracts.UpdateContract(a.ctx, b.contract.ID, &dto.UpdateContractRequest{Deposit: &deposit}, nil); err == nil {
+			t.Error("updated the contract of another organization")
+		}
+		if _, _, err := contracts.PatchContractVersion(a.ctx, b.contract.ID, b.version.ID, []byte(`{"rent": 1}`)); err == nil {
+			t.Error("patched the contract version of another organization")
+		}
+
+		property, err := addresses.GetAddressByID(b.ctx, b.property.ID)
+		if err != nil {
+			t.Fatalf("get address: %v", err)
+		}
+		tenant, err := users.GetUserByID(b.ctx, b.tenant.ID)
+		if err != nil {
+			t.Fatalf("get user: %v", err)
+		}
+		contract, err := contracts.GetContractByID(b.ctx, b.contract.ID)
+		if err != nil {
+			t.Fatalf("get contract: %v", err)
+		}
+		if property.Street == street || tenant.Phone == phone || contract.Deposit == deposit || contract.CurrentVersion.Rent != b.version.Rent {
+			t.Error("records of another organization were changed")
+		}
+	})
+
+	t.Run("delete and trash", func(t *testing.T) {
+		assertNotFound(t, "delete the contract of another organization", contracts.DeleteContract(a.ctx, b.contract.ID))
+		assertNotFound(t, "delete the address of another organization", addresses.DeleteAddress(a.ctx, b.home.ID, true))
+		assertNotFound(t, "delete the user of another organization", users.DeleteUser(a.ctx, b.tenant.ID, true))
+		assertNotFound(t, "delete the payment of another organization", payments.DeletePayment(a.ctx, b.contract.ID, b.payment.ID))
+		assertNotFound(t, "delete the expense of another organization", expenses.DeleteExpense(a.ctx, b.expense.ID))
+
+		if _, err := contracts.GetContractByID(b.ctx, b.contract.ID); err != nil {
+			t.Errorf("contract deleted by another organization: %v", err)
+		}
+		if _, err := addresses.GetAddressByID(b.ctx, b.home.ID); err != nil {
+			t.Errorf("address deleted by another organization: %v", err)
+		}
+		if _, err := users.GetUserByID(b.ctx, b.tenant.ID); err != nil {
+			t.Errorf("user deleted by another organization: %v", err)
+		}
+		if paymentList, err := payments.GetPayments(b.ctx, b.contract.ID); err != nil || len(paymentList) != 1 {
+			t.Errorf("payment deleted by another organization: %v", err)
+		}
+		if expenseList, err := expenses.GetExpenses(b.ctx, nil); err != nil || len(expenseList) != 1 {
+			t.Errorf("expense deleted by another organization: %v", err)
+		}
+
+		// Put an address of b in the trash and reach for it from a
+		trashed, err := addresses.CreateAddress(b.ctx, &dto.CreateAddressRequest{
+			Type: string(models.TenantAddress), Street: "Calle Juárez", Number: "1", Neighborhood: "Centro",
+			City: b.organization.Name, State: "Jalisco", ZipCode: "44100", Country: "México",
+		})
+		if err != nil {
+			t.Fatalf("create address: %v", err)
+		}
+		if err := addresses.DeleteAddress(b.ctx, trashed.ID, false); err != nil {
+			t.Fatalf("delete address: %v", err)
+		}
+
+		deletedAddresses, err := addresses.GetDeletedAddresses(a.ctx)
+		if err != nil {
+			t.Fatalf("list deleted addresses: %v", err)
+		}
+		if len(deletedAddresses) != 0 {
+			t.Errorf("trash lists the addresses of another organization: %v", addressIDs(deletedAddresses))
+		}
+		deletedUsers, err := users.GetDeletedUsers(a.ctx)
+		if err != nil {
+			t.Fatalf("list deleted users: %v", err)
+		}
+		if len(deletedUsers) != 0 {
+			t.Errorf("trash lists the users of another organization: %v", userIDs(deletedUsers))
+		}
+		deletedContracts, err := contracts.GetDeletedContracts(a.ctx)
+		if err != nil {
+			t.Fatalf("list deleted contracts: %v", err)
+		}
+		if len(deletedContracts) != 0 {
+			t.Errorf("trash lists the contracts of another organization: %v", contractIDs(deletedContracts))
+		}
+
+		if _, err := addresses.RestoreAddress(a.ctx, trashed.ID); err == nil {
+			t.Error("restored the address of another organization")
+		}
+		if err := addresses.PurgeAddress(a.ctx, trashed.ID); err == nil {
+			t.Error("purged the address of another organization")
+		}
+		deletedAddresses, err = addresses.GetDeletedAddresses(b.ctx)
+		if err != nil {
+			t.Fatalf("list deleted addresses: %v", err)
+		}
+		assertIDs(t, "deleted addresses", addressIDs(deletedAddresses), trashed.ID)
+	})
+
+	t.Run("no principal matches nothing", func(t *testing.T) {
+		ctx := context.Background()
+
+		addressList, err := addresses.GetAllAddresses(ctx, nil)
+		if err != nil {
+			t.Fatalf("list addresses: %v", err)
+		}
+		userList, err := users.GetAllUsers(ctx)
+		if err != nil {
+			t.Fatalf("list users: %v", err)
+		}
+		contractList, err := contracts.GetAllContracts(ctx)
+		if err != nil {
+			t.Fatalf("list contracts: %v", err)
+		}
+		if len(addressList) != 0 || len(userList) != 0 || len(contractList) != 0 {
+			t.Errorf("listed %d addresses, %d users and %d contracts without a principal", len(addressList), len(userList), len(contractList))
+		}
+
+		if _, err := addresses.GetAddressByID(ctx, a.property.ID); err == nil {
+			t.Error("got an address without a principal")
+		}
+		if _, err := contracts.GetContractAsOf(ctx, a.contract.ID, time.Now()); err == nil {
+			t.Error("got a past contract without a principal")
+		}
+
+		street := "Calle Falsa"
+		if _, err := addresses.UpdateAddress(ctx, a.property.ID, &dto.UpdateAddressRequest{Street: &street}, nil); err == nil {
+			t.Error("updated an address without a principal")
+		}
+		assertNotFound(t, "delete a contract without a principal", contracts.DeleteContract(ctx, a.contract.ID))
+		if _, err := contracts.GetContractByID(a.ctx, a.contract.ID); err != nil {
+			t.Errorf("contract deleted without a principal: %v", err)
+		}
+
+		stats, err := statistics.GetOverallContractStatistics(ctx, &StatisticsFilter{})
+		if err != nil {
+			t.Fatalf("statistics: %v", err)
+		}
+		if stats.TotalContracts != 0 || stats.TotalProperties != 0 || stats.MonthlyRevenue != 0 {
+			t.Errorf("statistics without a principal count %d contracts and %d properties", stats.TotalContracts, stats.TotalProperties)
+		}
+		rentRoll, err := reports.GetRentRoll(ctx)
+		if err != nil {
+			t.Fatalf("rent roll: %v", err)
+		}
+		if len(rentRoll.Rows) != 0 {
+			t.Errorf("rent roll without a principal lists %d properties", len(rentRoll.Rows))
+		}
+
+		// Records created without a principal belong to no organization and are rejected
+		if _, err := addresses.CreateAddress(ctx, &dto.CreateAddressRequest{
+			Type: string(models.PropertyAddress), Street: "Calle Morelos", Number: "2", Neighborhood: "Centro",
+			City: "Puebla", State: "Puebla", ZipCode: "72000", Country: "México",
+		}); err == nil {
+			t.Error("created an address without a principal")
+		}
+	})
+}
+
+// assertNotFound fails unless err reports that the record was not found
+func assertNotFound(t *testing.T, action string, err error) {
+	t.Helper()
+
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("%s: got %v, want not found", action, err)
+	}
+}
+
+// assertIDs fails unless ids holds exactly the expected ones
+func assertIDs(t *testing.T, records string, ids []uuid.UUID, expected ...uuid.UUID) {
+	t.Helper()
+
+	want := map[uuid.UUID]bool{}
+	for _, id := range expected {
+		want[id] = true
+	}
+	if len(ids) != len(want) {
+		t.Errorf("listed %s %v, want %v", records, ids, expected)
+		return
+	}
+	for _, id := range ids {
+		if !want[id] {
+			t.Errorf("listed %s %v, want %v", records, ids, expected)
+			return
+		}
+	}
+}
+
+func addressIDs(addresses []models.Address) []uuid.UUID {
+	ids := make([]uuid.UUID, len(addresses))
+	for i, address := range addresses {
+		ids[i] = address.ID
+	}
+	return ids
+}
+
+func userIDs(users []models.User) []uuid.UUID {
+	ids := make([]uuid.UUID, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
+	}
+	return ids
+}
+
+func contractIDs(contracts []models.Contract) []uuid.UUID {
+	ids := make([]uuid.UUID, len(contracts))
+	for i, contract := range contracts {
+		ids[i] = contract.ID
+	}
+	return ids
+}
@@ -0,0 +1,262 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/edfloreshz/rent-contracts/src/dto"
+	"github.com/edfloreshz/rent-contracts/src/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Charge is the rent due for one month of a contract and how much of it is paid.
+// Charges fall due every month from the start of each version, at its rent
+// increased on each anniversary, until the version ends, the next one starts or
+// it is terminated.
+type Charge struct {
+	ContractID  uuid.UUID `json:"contractId"`
+	DueDate     time.Time `json:"dueDate"`
+	Amount      float64   `json:"amount"`
+	Paid        float64   `json:"paid"`
+	Outstanding float64   `json:"outstanding"`
+	DaysOverdue int       `json:"daysOverdue"`
+}
+
+type PaymentService struct {
+	db  *gorm.DB
+	uow *UnitOfWork
+}
+
+func NewPaymentService(db *gorm.DB) *PaymentService {
+	return &PaymentService{
+		db:  db,
+		uow: NewUnitOfWork(db),
+	}
+}
+
+func (s *PaymentService) CreatePayment(ctx context.Context, contractID uuid.UUID, req *dto.CreatePaymentRequest) (*models.Payment, error) {
+	if req.Amount <= 0 {
+		return nil, errors.New("amount must be positive")
+	}
+
+	var payment *models.Payment
+	err := s.uow.Do(ctx, func(tx *gorm.DB) error {
+		contract, err := lockContract(tx, contractID)
+		if err != nil {
+			return err
+		}
+
+		paymentType := models.PaymentType(req.Type)
+		if paymentType == "" {
+			paymentType = models.RentPayment
+		}
+
+		payment = &models.Payment{
+			ContractID:        contract.ID,
+			Type:              paymentType,
+			Amount:            req.Amount,
+			PaidAt:            req.PaidAt,
+			Reference:         req.Reference,
+			InvoiceNumber:     req.InvoiceNumber,
+			VAT:               req.VAT,
+			IncomeTaxWithheld: req.IncomeTaxWithheld,
+		}
+		if err := tx.Create(payment).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, contract.OrganizationID, models.AuditCreate, AuditPayment, payment.ID, nil, payment)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+// GetPayments returns the payments of a contract the caller may see, oldest first
+func (s *PaymentService) GetPayments(ctx context.Context, contractID uuid.UUID) ([]models.Payment, error) {
+	if err := s.visibleContract(ctx, contractID); err != nil {
+		return nil, err
+	}
+
+	var payments []models.Payment
+	if err := s.db.WithContext(ctx).Where("contractid = ?", contractID).Order("paidat, createdat").Find(&payments).Error; err != nil {
+		return nil, err
+	}
+	return payments, nil
+}
+
+func (s *PaymentService) DeletePayment(ctx context.Context, contractID, id uuid.UUID) error {
+	return s.uow.Do(ctx, func(tx *gorm.DB) error {
+		var payment models.Payment
+		if err := tx.Where("contractid = ?", contractID).First(&payment, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("payment not found")
+			}
+			return err
+		}
+
+		if err := tx.Delete(&payment).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, payment.OrganizationID, models.AuditDelete, AuditPayment, payment.ID, &payment, nil)
+	})
+}
+
+// GetCharges returns the charges of a contract the caller may see up to today,
+// with the payments applied to them
+func (s *PaymentService) GetCharges(ctx context.Context, contractID uuid.UUID) ([]Charge, error) {
+	if err := s.visibleContract(ctx, contractID); err != nil {
+		return nil, err
+	}
+
+	ledgers, err := contractLedgers(s.db.WithContext(ctx), []uuid.UUID{contractID}, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	return ledgers[contractID], nil
+}
+
+// visibleContract checks the contract exists and the caller may see it
+func (s *PaymentService) visibleContract(ctx context.Context, contractID uuid.UUID) error {
+	found, err := exists(s.db.WithContext(ctx).Model(&models.Contract{}).Scopes(contractAccessScope(ctx)).Where("contracts.id = ?", contractID))
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.New("contract not found")
+	}
+	return nil
+}
+
+// contractLedgers returns the charges of each contract due up to asOf, with the
+// rent paid up to asOf applied to the oldest first
+func contractLedgers(db *gorm.DB, contractIDs []uuid.UUID, asOf time.Time) (map[uuid.UUID][]Charge, error) {
+	ledgers := make(map[uuid.UUID][]Charge, len(contractIDs))
+	if len(contractIDs) == 0 {
+		return ledgers, nil
+	}
+
+	var versions []models.ContractVersion
+	if err := db.Where("contractid IN ?", contractIDs).
+		Order("contractid, startdate, versionnumber").
+		Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	byContract := map[uuid.UUID][]models.ContractVersion{}
+	for _, version := range versions {
+		byContract[version.ContractID] = append(byContract[version.ContractID], version)
+	}
+
+	terminatedAt, err := versionTerminations(db, contractIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var paid []struct {
+		ContractID uuid.UUID `gorm:"column:contractid"`
+		Total      float64   `gorm:"column:total"`
+	}
+	if err := db.Model(&models.Payment{}).
+		Where("contractid IN ? AND type = ? AND paidat <= ?", contractIDs, models.RentPayment, asOf).
+		Select("contractid, SUM(amount) AS total").
+		Group("contractid").
+		Scan(&paid).Error; err != nil {
+		return nil, err
+	}
+	totals := make(map[uuid.UUID]float64, len(paid))
+	for _, row := range paid {
+		totals[row.ContractID] = row.Total
+	}
+
+	for _, contractID := range contractIDs {
+		charges := rentCharges(byContract[contractID], terminatedAt, asOf)
+		remaining := totals[contractID]
+		for i := range charges {
+			charge := &charges[i]
+			charge.Paid = roundCents(math.Min(remaining, charge.Amount))
+			charge.Outstanding = roundCents(charge.Amount - charge.Paid)
+			remaining -= charge.Paid
+			if charge.Outstanding > 0 && charge.DueDate.Before(asOf) {
+				charge.DaysOverdue = int(asOf.Sub(charge.DueDate).Hours() / 24)
+			}
+		}
+		ledgers[contractID] = charges
+	}
+
+	return ledgers, nil
+}
+
+// versionTerminations returns when each terminated version of the contracts was
+// terminated, which is when its history first shows it as such
+func versionTerminations(db *gorm.DB, contractIDs []uuid.UUID) (map[uuid.UUID]time.Time, error) {
+	terminatedAt := map[uuid.UUID]time.Time{}
+	if len(contractIDs) == 0 {
+		return terminatedAt, nil
+	}
+
+	var terminations []struct {
+		VersionID    uuid.UUID `gorm:"column:versionid"`
+		TerminatedAt time.Time `gorm:"column:terminatedat"`
+	}
+	contractKeys := make([]string, len(contractIDs))
+	for i, contractID := range contractIDs {
+		contractKeys[i] = contractID.String()
+	}
+	if err := db.Raw(`SELECT rowid AS versionid, MIN(validfrom) AS terminatedat FROM history
+		WHERE tablename = 'contractversions' AND data->>'contractid' IN ? AND data->>'status' = ?
+		GROUP BY rowid`, contractKeys, models.TerminatedContract).
+		Scan(&terminations).Error; err != nil {
+		return nil, err
+	}
+	for _, termination := range terminations {
+		terminatedAt[termination.VersionID] = termination.TerminatedAt
+	}
+	return terminatedAt, nil
+}
+
+// rentCharges lists the monthly charges of the versions of a contract due up to
+// asOf. Versions are expected in order of their start date. Terminated versions
+// are billed until their termination, or not at all when it is unknown.
+func rentCharges(versions []models.ContractVersion, terminatedAt map[uuid.UUID]time.Time, asOf time.Time) []Charge {
+	var charges []Charge
+	for i, version := range versions {
+		end := version.EndDate
+		if i+1 < len(versions) && versions[i+1].StartDate.Before(end) {
+			end = versions[i+1].StartDate
+		}
+		if version.Status == models.TerminatedContract {
+			terminated, ok := terminatedAt[version.ID]
+			if !ok {
+				continue
+			}
+			if terminated.Before(end) {
+				end = terminated
+			}
+		}
+
+		increase := 1 + version.RentIncreasePercentage/100
+		for months := 0; ; months++ {
+			dueDate := version.StartDate.AddDate(0, months, 0)
+			if !dueDate.Before(end) || dueDate.After(asOf) {
+				break
+			}
+			charges = append(charges, Charge{
+				ContractID: version.ContractID,
+				DueDate:    dueDate,
+				Amount:     roundCents(version.Rent * math.Pow(increase, float64(anniversaries(version.StartDate, dueDate)))),
+			})
+		}
+	}
+
+	sort.SliceStable(charges, func(i, j int) bool { return charges[i].DueDate.Before(charges[j].DueDate) })
+	return charges
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,353 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/edfloreshz/rent-contracts/src/auth"
+	"github.com/edfloreshz/rent-contracts/src/dto"
+	"github.com/edfloreshz/rent-contracts/src/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// dataSubjectResponseTime is the time the LFPDPPP gives to answer an ARCO request
+const dataSubjectResponseTime = 20 * 24 * time.Hour
+
+// anonymizedName replaces the names of anonymized users
+const anonymizedName = "ANONYMIZED"
+
+// ErrRetentionNotEnded is returned when anonymizing a person whose data must still be kept
+var ErrRetentionNotEnded = errors.New("personal data is within its legal retention period")
+
+// UserDataExport is everything held about a person, as handed over for an access request
+type UserDataExport struct {
+	GeneratedAt         time.Time                   `json:"generatedAt"`
+	User                models.User                 `json:"user"`
+	Addresses           []models.Address            `json:"addresses"`
+	Contracts           []models.Contract           `json:"contracts"`
+	DataSubjectRequests []models.DataSubjectRequest `json:"dataSubjectRequests"`
+	AuditLog            []models.AuditLog           `json:"auditLog"`
+}
+
+type PrivacyService struct {
+	db              *gorm.DB
+	contractService *ContractService
+	retention       time.Duration
+}
+
+func NewPrivacyService(db *gorm.DB, contractService *ContractService, retention time.Duration) *PrivacyService {
+	return &PrivacyService{
+		db:              db,
+		contractService: contractService,
+		retention:       retention,
+	}
+}
+
+// CreateRequest records an ARCO request made by a person, due within the legal response time
+func (s *PrivacyService) CreateRequest(ctx context.Context, req *dto.CreateDataSubjectRequest) (*models.DataSubjectRequest, error) {
+	if _, err := s.findUser(ctx, req.UserID); err != nil {
+		return nil, err
+	}
+
+	request := &models.DataSubjectRequest{
+		UserID:  req.UserID,
+		Type:    models.DataSubjectRequestType(req.Type),
+		Status:  models.PendingRequest,
+		Details: req.Details,
+		DueAt:   time.Now().Add(dataSubjectResponseTime),
+	}
+	if err := s.db.WithContext(ctx).Create(request).Error; err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// GetRequests returns the ARCO requests, optionally with a given status, the most urgent first
+func (s *PrivacyService) GetRequests(ctx context.Context, status *models.DataSubjectRequestStatus) ([]models.DataSubjectRequest, error) {
+	query := s.db.WithContext(ctx).Model(&models.DataSubjectRequest{})
+	if status != nil {
+		query = query.Where("status = ?", *status)
+	}
+
+	var requests []models.DataSubjectRequest
+	if err := query.Order("dueat, createdat").Find(&requests).Error; err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+// ResolveRequest completes or rejects a pending ARCO request
+func (s *PrivacyService) ResolveRequest(ctx context.Context, id uuid.UUID, req *dto.ResolveDataSubjectRequest) (*models.DataSubjectRequest, error) {
+	var request models.DataSubjectRequest
+	if err := s.db.WithContext(ctx).First(&request, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("request not found")
+		}
+		return nil, err
+	}
+	if request.Status != models.PendingRequest {
+		return nil, errors.New("request is already resolved")
+	}
+
+	now := time.Now()
+	request.Status = models.DataSubjectRequestStatus(req.Status)
+	request.Resolution = req.Resolution
+	request.ResolvedAt = &now
+	if principal, ok := auth.PrincipalFromContext(ctx); ok && !principal.System {
+		request.ResolvedBy = &principal.UserID
+	}
+
+	if err := s.db.WithContext(ctx).Save(&request).Error; err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// ExportUserData gathers everything held about a user: their profile and address,
+// the contracts they are a party or reference to with their properties, their ARCO
+// requests and the audit entries about them, their contracts or made by them.
+// Deleted records are included since they are still held.
+func (s *PrivacyService) ExportUserData(ctx context.Context, userID uuid.UUID) (*UserDataExport, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	db := s.db.WithContext(ctx)
+	export := &UserDataExport{GeneratedAt: time.Now(), User: *user}
+
+	if err := db.Unscoped().
+		Preload("Versions").
+		Where("id IN (?)", userContractIDs(db, userID)).
+		Order("createdat").
+		Find(&export.Contracts).Error; err != nil {
+		return nil, err
+	}
+
+	addressIDs := []uuid.UUID{user.AddressID}
+	var contractIDs, versionIDs []uuid.UUID
+	for _, contract := range export.Contracts {
+		addressIDs = append(addressIDs, contract.AddressID)
+		contractIDs = append(contractIDs, contract.ID)
+		for _, version := range contract.Versions {
+			versionIDs = append(versionIDs, version.ID)
+		}
+	}
+	if err := db.Unscoped().Where("id IN ?", addressIDs).Find(&export.Addresses).Error; err != nil {
+		return nil, err
+	}
+
+	if err := db.Where("userid = ?", userID).Order("createdat").Find(&export.DataSubjectRequests).Error; err != nil {
+		return nil, err
+	}
+
+	if err := db.
+		Where("(entitytype = ? AND entityid = ?) OR (entitytype = ? AND entityid = ?) OR actorid = ?",
+			AuditUser, userID, AuditAddress, user.AddressID, userID).
+		Or("entitytype IN ? AND entityid IN ?", []string{AuditContract, AuditConfirmation}, contractIDs).
+		Or("entitytype = ? AND entityid IN ?", AuditContractVersion, versionIDs).
+		Order("createdat, id").
+		Find(&export.AuditLog).Error; err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+// ExportUserDataArchive bundles the export of a user with the documents of their
+// contracts in a ZIP archive. Deleted contracts are rendered as they were just
+// before their deletion.
+func (s *PrivacyService) ExportUserDataArchive(ctx context.Context, userID uuid.UUID) ([]byte, error) {
+	export, err := s.ExportUserData(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	file, err := archive.Create("data.json")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Write(data); err != nil {
+		return nil, err
+	}
+
+	for _, contract := range export.Contracts {
+		var asOf *time.Time
+		if contract.DeletedAt.Valid {
+			beforeDeletion := contract.DeletedAt.Time.Add(-time.Second)
+			asOf = &beforeDeletion
+		}
+
+		document, err := s.contractService.GetContractDocument(ctx, contract.ID, nil, asOf)
+		if err != nil {
+			// Contracts without a version have no document
+			continue
+		}
+
+		file, err := archive.Create(fmt.Sprintf("documents/contract-%s.pdf", contract.ID))
+		if err != nil {
+			return nil, err
+		}
+		if _, err := file.Write(document); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// AnonymizeUser removes the personal data of a user once their retention period
+// has ended. The user record stays so that contracts and their financials remain
+// intact, but their names and contact details are replaced, their home address is
+// blanked when nobody else uses it, their credentials and keys are removed and the
+// values are scrubbed from their history and audit entries.
+func (s *PrivacyService) AnonymizeUser(ctx context.Context, userID uuid.UUID) (*models.User, error) {
+	user, err := s.findUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.AnonymizedAt != nil {
+		return user, nil
+	}
+
+	retentionEndsAt, err := s.retentionEndsAt(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if retentionEndsAt != nil && time.Now().Before(*retentionEndsAt) {
+		return nil, fmt.Errorf("%w until %s", ErrRetentionNotEnded, retentionEndsAt.Format(time.DateOnly))
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		anonymized := map[string]interface{}{
+			"firstname":    anonymizedName,
+			"middlename":   nil,
+			"lastname":     anonymizedName,
+			"email":        fmt.Sprintf("anonymized-%s@invalid", user.ID),
+			"phone":        "",
+			"anonymizedat": now,
+		}
+		if err := tx.Unscoped().Model(user).Updates(anonymized).Error; err != nil {
+			return err
+		}
+		if err := scrubHistory(tx, "users", user.ID, anonymized); err != nil {
+			return err
+		}
+		if err := scrubAuditLog(tx, AuditUser, user.ID); err != nil {
+			return err
+		}
+
+		shared, err := exists(tx.Unscoped().Model(&models.User{}).Where("addressid = ? AND id <> ?", user.AddressID, user.ID))
+		if err != nil {
+			return err
+		}
+		if !shared {
+			shared, err = exists(tx.Unscoped().Model(&models.Contract{}).Where("addressid = ?", user.AddressID))
+			if err != nil {
+				return err
+			}
+		}
+		if !shared {
+			blank := map[string]interface{}{"street": "", "number": "", "neighborhood": "", "zipcode": ""}
+			if err := tx.Unscoped().Model(&models.Address{ID: user.AddressID}).Updates(blank).Error; err != nil {
+				return err
+			}
+			if err := scrubHistory(tx, "addresses", user.AddressID, blank); err != nil {
+				return err
+			}
+			if err := scrubAuditLog(tx, AuditAddress, user.AddressID); err != nil {
+				return err
+			}
+		}
+
+		for _, model := range []interface{}{&models.UserCredential{}, &models.Session{}, &models.RecoveryCode{}, &models.APIKey{}} {
+			if err := tx.Where("userid = ?", user.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		return recordAudit(tx, user.OrganizationID, models.AuditAnonymize, AuditUser, user.ID, nil, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.findUser(ctx, userID)
+}
+
+// findUser loads a user of the caller's organization, even if deleted
+func (s *PrivacyService) findUser(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	var user models.User
+	if err := s.db.WithContext(ctx).Unscoped().First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// retentionEndsAt returns when the data of a user may be anonymized: the retention
+// period after the end of the last contract they are a party or reference to. It
+// returns nil when they are on no contract.
+func (s *PrivacyService) retentionEndsAt(ctx context.Context, userID uuid.UUID) (*time.Time, error) {
+	db := s.db.WithContext(ctx)
+
+	var lastEndDate sql.NullTime
+	if err := db.Model(&models.ContractVersion{}).
+		Where("contractid IN (?)", userContractIDs(db, userID)).
+		Select("MAX(enddate)").
+		Scan(&lastEndDate).Error; err != nil {
+		return nil, err
+	}
+	if !lastEndDate.Valid {
+		return nil, nil
+	}
+
+	endsAt := lastEndDate.Time.Add(s.retention)
+	return &endsAt, nil
+}
+
+// userContractIDs selects the IDs of the contracts, deleted or not, a user is a
+// party or reference to
+func userContractIDs(db *gorm.DB, userID uuid.UUID) *gorm.DB {
+	return db.Unscoped().Model(&models.Contract{}).
+		Where("tenantid = ? OR landlordid = ? OR id IN (?)", userID, userID,
+			db.Model(&models.ContractReference{}).Select("contractid").Where("referenceid = ?", userID)).
+		Select("id")
+}
+
+// scrubHistory overwrites fields in every recorded version of a row
+func scrubHistory(tx *gorm.DB, table string, rowID uuid.UUID, fields map[string]interface{}) error {
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	return tx.Exec("UPDATE history SET data = data || ?::jsonb WHERE tablename = ? AND rowid = ?", string(data), table, rowID).Error
+}
+
+// scrubAuditLog drops the values from the audit entries of a record, keeping which
+// fields changed and when
+func scrubAuditLog(tx *gorm.DB, entityType string, entityID uuid.UUID) error {
+	return tx.Exec(`UPDATE auditlogs
+		SET changes = COALESCE((SELECT jsonb_object_agg(key, '{"before": null, "after": null}'::jsonb) FROM jsonb_each(changes)), '{}')
+		WHERE entitytype = ? AND entityid = ?`, entityType, entityID).Error
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestExportUserData(t *testing.T) {
+	db := testDatabase(t)
+	p := seedPortfolio(t, db, "Monterrey", 1000)
+	privacyService := NewPrivacyService(db, NewContractService(db), 5*365*24*time.Hour)
+
+	export, err := privacyService.ExportUserData(p.ctx, p.tenant.ID)
+	if err != nil {
+		t.Fatalf("export user data: %v", err)
+	}
+
+	exported := map[string]map[uuid.UUID]bool{}
+	for _, entry := range export.AuditLog {
+		if exported[entry.EntityType] == nil {
+			exported[entry.EntityType] = map[uuid.UUID]bool{}
+		}
+		exported[entry.EntityType][entry.EntityID] = true
+	}
+
+	for entityType, id := range map[string]uuid.UUID{
+		AuditUser:            p.tenant.ID,
+		AuditContract:        p.contract.ID,
+		AuditContractVersion: p.version.ID,
+	} {
+		if !exported[entityType][id] {
+			t.Errorf("audit entries of %s %s are missing from the export", entityType, id)
+		}
+	}
+}
@@ -0,0 +1,564 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/edfloreshz/rent-contracts/src/models"
+	"github.com/google/uuid"
+	"github.com/johnfercher/maroto/v2"
+	"github.com/johnfercher/maroto/v2/pkg/components/row"
+	"github.com/johnfercher/maroto/v2/pkg/components/text"
+	"github.com/johnfercher/maroto/v2/pkg/config"
+	"github.com/johnfercher/maroto/v2/pkg/consts/align"
+	"github.com/johnfercher/maroto/v2/pkg/consts/fontstyle"
+	"github.com/johnfercher/maroto/v2/pkg/consts/orientation"
+	"github.com/johnfercher/maroto/v2/pkg/consts/pagesize"
+	"github.com/johnfercher/maroto/v2/pkg/core"
+	"github.com/johnfercher/maroto/v2/pkg/props"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+// rentRollColumns are the headers of the rent roll exports, in the order of
+// RentRollRow.values
+var rentRollColumns = []string{
+	"Property", "City", "Tenant", "Business", "Lease start", "Lease end", "Rent",
+	"Deposit", "Next increase", "Increase amount", "Balance owed",
+}
+
+// rentRollColumnWidths is the share of the twelve column PDF grid each column takes
+var rentRollColumnWidths = []int{2, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1}
+
+// leaseExpirationWindows are the horizons, in days, the lease expiration report
+// groups contracts by
+var leaseExpirationWindows = []int{30, 60, 90, 180}
+
+// Renewal statuses of an expiring contract
+const (
+	RenewalRenewed = "renewed"
+	RenewalPending = "pending"
+	RenewalOverdue = "overdue"
+)
+
+type ReportService struct {
+	db *gorm.DB
+}
+
+// RentRoll lists every property with the lease it is under
+type RentRoll struct {
+	GeneratedAt time.Time     `json:"generatedAt"`
+	Rows        []RentRollRow `json:"rows"`
+}
+
+// RentRollRow is a property and its active lease. The lease fields are null
+// when the property is vacant.
+type RentRollRow struct {
+	PropertyID         uuid.UUID  `json:"propertyId"`
+	Property           string     `json:"property"`
+	City               string     `json:"city"`
+	ContractID         *uuid.UUID `json:"contractId"`
+	TenantID           *uuid.UUID `json:"tenantId"`
+	Tenant             *string    `json:"tenant"`
+	Business           *string    `json:"business"`
+	LeaseStart         *time.Time `json:"leaseStart"`
+	LeaseEnd           *time.Time `json:"leaseEnd"`
+	CurrentRent        *float64   `json:"currentRent"`
+	Deposit            *float64   `json:"deposit"`
+	NextIncreaseDate   *time.Time `json:"nextIncreaseDate"`
+	NextIncreaseAmount *float64   `json:"nextIncreaseAmount"`
+
+	// BalanceOwed is the rent charged on the contract and not yet paid
+	BalanceOwed *float64 `json:"balanceOwed"`
+}
+
+// LeaseExpirations lists the contracts with a term ending within the longest
+// expiration window
+type LeaseExpirations struct {
+	GeneratedAt time.Time               `json:"generatedAt"`
+	Windows     []LeaseExpirationWindow `json:"windows"`
+	Contracts   []LeaseExpiration       `json:"contracts"`
+	RentAtRisk  []RentAtRisk            `json:"rentAtRisk"`
+}
+
+// LeaseExpirationWindow counts the contracts expiring within a number of days
+type LeaseExpirationWindow struct {
+	Days      int     `json:"days"`
+	Contracts int     `json:"contracts"`
+	Rent      float64 `json:"rent"`
+}
+
+// LeaseExpiration is a contract about to expire and how its renewal stands. The
+// expiring term is the version ending within the window, which is renewed once a
+// version starts when it ends. Its renewal is overdue when the renewal date of the
+// expiring version has passed without one.
+type LeaseExpiration struct {
+	ContractID      uuid.UUID  `json:"contractId"`
+	TenantID        uuid.UUID  `json:"tenantId"`
+	Tenant          string     `json:"tenant"`
+	PropertyID      uuid.UUID  `json:"propertyId"`
+	Property        string     `json:"property"`
+	EndDate         time.Time  `json:"endDate"`
+	DaysUntilExpiry int        `json:"daysUntilExpiry"`
+	Window          int        `json:"window"`
+	CurrentRent     float64    `json:"currentRent"`
+	RenewalStatus   string     `json:"renewalStatus"`
+	RenewalDate     *time.Time `json:"renewalDate"`
+
+	// ProposedRent is the rent of the renewal, or the current rent with its
+	// increase applied while there is none
+	ProposedRent float64 `json:"proposedRent"`
+}
+
+// RentAtRisk is the rent of the contracts expiring in a month that are not renewed
+type RentAtRisk struct {
+	Month     string  `json:"month"`
+	Contracts int     `json:"contracts"`
+	Rent      float64 `json:"rent"`
+}
+
+func NewReportService(db *gorm.DB) *ReportService {
+	return &ReportService{
+		db: db,
+	}
+}
+
+// GetRentRoll lists the properties that are not deleted, each with the active
+// contract that started last on it. Its rent includes the increases of the
+// anniversaries passed so far, and the next increase is a percentage of that.
+func (s *ReportService) GetRentRoll(ctx context.Context) (*RentRoll, error) {
+	db := s.db.WithContext(ctx)
+	now := time.Now()
+
+	var properties []models.Address
+	if err := db.Where("type = ?", models.PropertyAddress).
+		Order("city, street, number").
+		Find(&properties).Error; err != nil {
+		return nil, err
+	}
+
+	var contracts []models.Contract
+	if err := db.Preload("CurrentVersion").Preload("Tenant").
+		Joins("JOIN contractversions ON contracts.currentversionid = contractversions.id").
+		Where("contractversions.status = ?", models.ActiveContract).
+		Order("contractversions.startdate").
+		Find(&contracts).Error; err != nil {
+		return nil, err
+	}
+	leases := make(map[uuid.UUID]models.Contract, len(contracts))
+	for _, contract := range contracts {
+		leases[contract.AddressID] = contract
+	}
+
+	contractIDs := make([]uuid.UUID, 0, len(leases))
+	for _, contract := range leases {
+		contractIDs = append(contractIDs, contract.ID)
+	}
+	ledgers, err := contractLedgers(db, contractIDs, now)
+	if err != nil {
+		return nil, err
+	}
+
+	rentRoll := &RentRoll{GeneratedAt: now, Rows: make([]RentRollRow, 0, len(properties))}
+	for _, property := range properties {
+		rentRollRow := RentRollRow{
+			PropertyID: property.ID,
+			Property:   property.Street + " " + property.Number + ", " + property.Neighborhood,
+			City:       property.City,
+		}
+
+		if contract, ok := leases[property.ID]; ok {
+			version := contract.CurrentVersion
+			tenant := contract.Tenant.FullName()
+			deposit := contract.Deposit
+			rentRollRow.ContractID = &contract.ID
+			rentRollRow.TenantID = &contract.TenantID
+			rentRollRow.Tenant = &tenant
+			rentRollRow.Business = &version.Business
+			rentRollRow.LeaseStart = &version.StartDate
+			rentRollRow.LeaseEnd = &version.EndDate
+			rentRollRow.Deposit = &deposit
+
+			increase := 1 + version.RentIncreasePercentage/100
+			currentRent := roundCents(version.Rent * math.Pow(increase, float64(anniversaries(version.StartDate, now))))
+			rentRollRow.CurrentRent = &currentRent
+
+			var balance float64
+			for _, charge := range ledgers[contract.ID] {
+				balance += charge.Outstanding
+			}
+			rentRollRow.BalanceOwed = &balance
+
+			if increaseDate := nextRentIncrease(version, now); increaseDate != nil {
+				amount := roundCents(currentRent * version.RentIncreasePercentage / 100)
+				rentRollRow.NextIncreaseDate = increaseDate
+				rentRollRow.NextIncreaseAmount = &amount
+			}
+		}
+
+		rentRoll.Rows = append(rentRoll.Rows, rentRollRow)
+	}
+
+	return rentRoll, nil
+}
+
+// GetLeaseExpirations lists the contracts with a term ending within the next 180
+// days, the soonest first. Contracts whose current version is terminated are left
+// out.
+func (s *ReportService) GetLeaseExpirations(ctx context.Context) (*LeaseExpirations, error) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	horizon := today.AddDate(0, 0, leaseExpirationWindows[len(leaseExpirationWindows)-1])
+
+	var contracts []models.Contract
+	if err := s.db.WithContext(ctx).
+		Preload("Versions").Preload("Tenant").Preload("Address").
+		Joins("JOIN contractversions ON contracts.currentversionid = contractversions.id").
+		Where("contractversions.status <> ?", models.TerminatedContract).
+		Where(`EXISTS (SELECT 1 FROM contractversions expiring WHERE expiring.contractid = contracts.id
+			AND expiring.status <> ? AND expiring.enddate >= ? AND expiring.enddate <= ?)`, models.TerminatedContract, today, horizon).
+		Find(&contracts).Error; err != nil {
+		return nil, err
+	}
+
+	report := &LeaseExpirations{
+		GeneratedAt: now,
+		Windows:     make([]LeaseExpirationWindow, len(leaseExpirationWindows)),
+		Contracts:   make([]LeaseExpiration, 0, len(contracts)),
+		RentAtRisk:  []RentAtRisk{},
+	}
+	for i, days := range leaseExpirationWindows {
+		report.Windows[i].Days = days
+	}
+
+	for _, contract := range contracts {
+		version, renewal := expiringTerm(contract.Versions, today, horizon)
+		if version == nil {
+			continue
+		}
+
+		expiration := LeaseExpiration{
+			ContractID:      contract.ID,
+			TenantID:        contract.TenantID,
+			Tenant:          contract.Tenant.FullName(),
+			PropertyID:      contract.AddressID,
+			Property:        contract.Address.FullAddress(),
+			EndDate:         version.EndDate,
+			DaysUntilExpiry: int(version.EndDate.Sub(today).Hours() / 24),
+			CurrentRent:     version.Rent,
+			RenewalStatus:   RenewalPending,
+			RenewalDate:     version.RenewalDate,
+			ProposedRent:    version.Rent * (1 + version.RentIncreasePercentage/100),
+		}
+		if renewal != nil {
+			expiration.RenewalStatus = RenewalRenewed
+			expiration.ProposedRent = renewal.Rent
+		} else if version.RenewalDate != nil && version.RenewalDate.Before(today) {
+			expiration.RenewalStatus = RenewalOverdue
+		}
+		report.Contracts = append(report.Contracts, expiration)
+	}
+	sort.SliceStable(report.Contracts, func(i, j int) bool {
+		if !report.Contracts[i].EndDate.Equal(report.Contracts[j].EndDate) {
+			return report.Contracts[i].EndDate.Before(report.Contracts[j].EndDate)
+		}
+		return report.Contracts[i].ContractID.String() < report.Contracts[j].ContractID.String()
+	})
+
+	for k := range report.Contracts {
+		expiration := &report.Contracts[k]
+		for i, days := range leaseExpirationWindows {
+			if expiration.DaysUntilExpiry > days {
+				continue
+			}
+			if expiration.Window == 0 {
+				expiration.Window = days
+			}
+			report.Windows[i].Contracts++
+			report.Windows[i].Rent += expiration.CurrentRent
+		}
+
+		if expiration.RenewalStatus != RenewalRenewed {
+			month := expiration.EndDate.Format("2006-01")
+			last := len(report.RentAtRisk) - 1
+			if last < 0 || report.RentAtRisk[last].Month != month {
+				report.RentAtRisk = append(report.RentAtRisk, RentAtRisk{Month: month})
+				last++
+			}
+			report.RentAtRisk[last].Contracts++
+			report.RentAtRisk[last].Rent += expiration.CurrentRent
+		}
+	}
+
+	return report, nil
+}
+
+// expiringTerm finds the version of a contract whose term ends soonest between
+// from and to, skipping terminated versions and those amended by a version that
+// starts before they end. It also returns the version renewing it, the first one
+// starting once it ends, or nil while it is not renewed.
+func expiringTerm(versions []models.ContractVersion, from, to time.Time) (expiring, renewal *models.ContractVersion) {
+	for i := range versions {
+		version := &versions[i]
+		if version.Status == models.TerminatedContract || version.EndDate.Before(from) || version.EndDate.After(to) {
+			continue
+		}
+		if expiring != nil && !version.EndDate.Before(expiring.EndDate) {
+			continue
+		}
+
+		amended := false
+		for _, other := range versions {
+			if other.StartDate.After(version.StartDate) && other.StartDate.Before(version.EndDate) {
+				amended = true
+				break
+			}
+		}
+		if !amended {
+			expiring = version
+		}
+	}
+	if expiring == nil {
+		return nil, nil
+	}
+
+	for i := range versions {
+		candidate := &versions[i]
+		if candidate.ID == expiring.ID || candidate.StartDate.Before(expiring.EndDate) {
+			continue
+		}
+		if renewal == nil || candidate.StartDate.Before(renewal.StartDate) {
+			renewal = candidate
+		}
+	}
+	return expiring, renewal
+}
+
+// nextRentIncrease returns the next anniversary of a version after now, when its
+// rent increases. It returns nil when the version has no increase or ends first.
+func nextRentIncrease(version *models.ContractVersion, now time.Time) *time.Time {
+	if version.RentIncreasePercentage <= 0 {
+		return nil
+	}
+	for years := 1; ; years++ {
+		anniversary := version.StartDate.AddDate(years, 0, 0)
+		if !anniversary.Before(version.EndDate) {
+			return nil
+		}
+		if anniversary.After(now) {
+			return &anniversary
+		}
+	}
+}
+
+// values formats a row in the order of rentRollColumns, leaving null fields empty
+func (r RentRollRow) values() []string {
+	date := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(time.DateOnly)
+	}
+	amount := func(f *float64) string {
+		if f == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*f, 'f', 2, 64)
+	}
+	str := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+
+	return []string{
+		r.Property, r.City, str(r.Tenant), str(r.Business), date(r.LeaseStart), date(r.LeaseEnd),
+		amount(r.CurrentRent), amount(r.Deposit), date(r.NextIncreaseDate), amount(r.NextIncreaseAmount),
+		amount(r.BalanceOwed),
+	}
+}
+
+// RentRollCSV renders a rent roll as CSV with a header row
+func RentRollCSV(rentRoll *RentRoll) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+
+	if err := writer.Write(rentRollColumns); err != nil {
+		return nil, err
+	}
+	for _, r := range rentRoll.Rows {
+		if err := writer.Write(r.values()); err != nil {
+			return nil, err
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// RentRollXLSX renders a rent roll as a spreadsheet, keeping dates and amounts
+// as typed cells so they can be sorted and summed
+func RentRollXLSX(rentRoll *RentRoll) ([]byte, error) {
+	file := excelize.NewFile()
+	defer file.Close()
+
+	const sheet = "Rent roll"
+	if err := file.SetSheetName("Sheet1", sheet); err != nil {
+		return nil, err
+	}
+
+	bold, err := file.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return nil, err
+	}
+	dateFormat := "yyyy-mm-dd"
+	date, err := file.NewStyle(&excelize.Style{CustomNumFmt: &dateFormat})
+	if err != nil {
+		return nil, err
+	}
+	money, err := file.NewStyle(&excelize.Style{NumFmt: 4})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := file.SetSheetRow(sheet, "A1", &rentRollColumns); err != nil {
+		return nil, err
+	}
+	last, err := excelize.CoordinatesToCellName(len(rentRollColumns), 1)
+	if err != nil {
+		return nil, err
+	}
+	if err := file.SetCellStyle(sheet, "A1", last, bold); err != nil {
+		return nil, err
+	}
+
+	for i, r := range rentRoll.Rows {
+		cells := []interface{}{
+			r.Property, r.City, r.Tenant, r.Business, r.LeaseStart, r.LeaseEnd, r.CurrentRent,
+			r.Deposit, r.NextIncreaseDate, r.NextIncreaseAmount, r.BalanceOwed,
+		}
+		for j, value := range cells {
+			cell, err := excelize.CoordinatesToCellName(j+1, i+2)
+			if err != nil {
+				return nil, err
+			}
+
+			style := 0
+			switch v := value.(type) {
+			case *string:
+				if v == nil {
+					continue
+				}
+				value = *v
+			case *time.Time:
+				if v == nil {
+					continue
+				}
+				value, style = *v, date
+			case *float64:
+				if v == nil {
+					continue
+				}
+				value, style = *v, money
+			}
+
+			if err := file.SetCellValue(sheet, cell, value); err != nil {
+				return nil, err
+			}
+			if style != 0 {
+				if err := file.SetCellStyle(sheet, cell, cell, style); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if err := file.SetColWidth(sheet, "A", "A", 40); err != nil {
+		return nil, err
+	}
+	if err := file.SetColWidth(sheet, "B", "K", 16); err != nil {
+		return nil, err
+	}
+
+	buffer, err := file.WriteToBuffer()
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// RentRollPDF renders a rent roll as a landscape table
+func RentRollPDF(rentRoll *RentRoll) ([]byte, error) {
+	cfg := config.NewBuilder().
+		WithPageSize(pagesize.Letter).
+		WithOrientation(orientation.Horizontal).
+		WithLeftMargin(10).
+		WithTopMargin(10).
+		WithRightMargin(10).
+		WithBottomMargin(10).
+		WithPageNumber().
+		Build()
+
+	m := maroto.New(cfg)
+
+	m.AddRows(
+		text.NewRow(10, "RENT ROLL", props.Text{
+			Style: fontstyle.Bold,
+			Size:  14,
+			Align: align.Center,
+		}),
+		text.NewRow(6, fmt.Sprintf("Generated on %s", rentRoll.GeneratedAt.Format(time.DateOnly)), props.Text{
+			Size:  8,
+			Align: align.Center,
+		}),
+	)
+
+	header := row.New(8)
+	for i, column := range rentRollColumns {
+		header.Add(text.NewCol(rentRollColumnWidths[i], column, props.Text{Size: 7, Top: 2, Align: align.Center, Style: fontstyle.Bold, Color: &props.WhiteColor}))
+	}
+	header.WithStyle(&props.Cell{BackgroundColor: darkGrayColor()})
+	rows := []core.Row{header}
+
+	var totalRent, totalDeposit float64
+	for i, r := range rentRoll.Rows {
+		content := row.New(8)
+		for j, value := range r.values() {
+			content.Add(text.NewCol(rentRollColumnWidths[j], value, props.Text{Size: 7, Top: 1, Align: align.Center}))
+		}
+		if i%2 == 0 {
+			content.WithStyle(&props.Cell{BackgroundColor: grayColor()})
+		}
+		rows = append(rows, content)
+
+		if r.CurrentRent != nil {
+			totalRent += *r.CurrentRent
+			totalDeposit += *r.Deposit
+		}
+	}
+
+	m.AddRows(rows...)
+
+	m.AddRows(row.New(8).Add(
+		text.NewCol(7, "TOTAL", props.Text{Size: 7, Top: 2, Style: fontstyle.Bold, Align: align.Right}),
+		text.NewCol(1, strconv.FormatFloat(totalRent, 'f', 2, 64), props.Text{Size: 7, Top: 2, Style: fontstyle.Bold, Align: align.Center}),
+		text.NewCol(1, strconv.FormatFloat(totalDeposit, 'f', 2, 64), props.Text{Size: 7, Top: 2, Style: fontstyle.Bold, Align: align.Center}),
+		text.NewCol(3, "", props.Text{}),
+	))
+
+	document, err := m.Generate()
+	if err != nil {
+		return nil, err
+	}
+	return document.GetBytes(), nil
+}
@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/edfloreshz/rent-contracts/src/models"
+	"github.com/google/uuid"
+)
+
+// renewalGrace is the longest gap between two terms of a tenant at the same
+// address for the second one to count as a renewal rather than a new tenancy
+const renewalGrace = 31 * 24 * time.Hour
+
+// maxChurnMonths limits how many months of churn can be requested
+const maxChurnMonths = 120
+
+// ErrInvalidChurnMonths is returned when more months of churn are requested than allowed
+var ErrInvalidChurnMonths = errors.New("months must be between 1 and 120")
+
+// RetentionStatistics describe how long tenants stay and how long properties sit
+// empty between them. A tenancy is the run of contract versions of one tenant at
+// one address, across contracts, with gaps of at most a month between them.
+type RetentionStatistics struct {
+	// Share of the terms that have ended which were followed by another term of
+	// the same tenancy, in percent
+	RenewalRate  float64 `json:"renewalRate"`
+	EndedTerms   int     `json:"endedTerms"`
+	RenewedTerms int     `json:"renewedTerms"`
+
+	// Average length of the tenancies in days, counting ongoing ones up to today
+	AverageTenure int `json:"averageTenure"`
+	Tenancies     int `json:"tenancies"`
+
+	// Average days a property stayed empty between two tenancies
+	AverageVacancy int `json:"averageVacancy"`
+	Vacancies      int `json:"vacancies"`
+
+	Churn []ChurnMonth `json:"churn"`
+
+	Groups []RetentionGroup `json:"groups,omitempty"`
+}
+
+// RetentionGroup holds the retention statistics of one group of contracts
+type RetentionGroup struct {
+	Key string `json:"key"`
+	RetentionStatistics
+}
+
+// ChurnMonth counts the tenancies that ended in a month against those active at its start
+type ChurnMonth struct {
+	Month           string  `json:"month"`
+	ActiveTenancies int     `json:"activeTenancies"`
+	MoveOuts        int     `json:"moveOuts"`
+	ChurnRate       float64 `json:"churnRate"`
+}
+
+// retentionTerm is a contract version with the fields retention needs
+type retentionTerm struct {
+	Key        string                `gorm:"column:key"`
+	VersionID  uuid.UUID             `gorm:"column:versionid"`
+	ContractID uuid.UUID             `gorm:"column:contractid"`
+	TenantID   uuid.UUID             `gorm:"column:tenantid"`
+	AddressID  uuid.UUID             `gorm:"column:addressid"`
+	Status     models.ContractStatus `gorm:"column:status"`
+	StartDate  time.Time             `gorm:"column:startdate"`
+	EndDate    time.Time             `gorm:"column:enddate"`
+}
+
+// tenancy is a run of terms of one tenant at one address
+type tenancy struct {
+	AddressID uuid.UUID
+	Start     time.Time
+	End       time.Time
+}
+
+// GetRetention computes renewal, tenure and vacancy figures from every version of
+// the contracts that are not deleted and match the filter, terminated ones up to
+// their termination, and the churn of the last months, broken down as the filter
+// asks
+func (s *StatisticsService) GetRetention(ctx context.Context, filter *StatisticsFilter, months int) (*RetentionStatistics, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	if months < 1 || months > maxChurnMonths {
+		return nil, ErrInvalidChurnMonths
+	}
+
+	key := "''"
+	if filter != nil && filter.GroupBy != nil {
+		key = statisticsGroupKeys[*filter.GroupBy]
+	}
+	db := s.db.WithContext(ctx)
+	var terms []retentionTerm
+	if err := db.Model(&models.Contract{}).Scopes(filter.contractScope).
+		Joins("JOIN contractversions ON contractversions.contractid = contracts.id").
+		Joins("JOIN addresses ON addresses.id = contracts.addressid").
+		Where("contracts.deletedat IS NULL").
+		Select("contractversions.id AS versionid, contracts.id AS contractid, contracts.tenantid, contracts.addressid, " +
+			"contractversions.status, contractversions.startdate, contractversions.enddate, " + key + " AS key").
+		Order("contracts.addressid, contracts.tenantid, contractversions.startdate").
+		Scan(&terms).Error; err != nil {
+		return nil, err
+	}
+
+	// A terminated version ends when it was terminated, and does not count at all
+	// when that is unknown or before it started
+	var contractIDs []uuid.UUID
+	seen := map[uuid.UUID]bool{}
+	for _, term := range terms {
+		if term.Status == models.TerminatedContract && !seen[term.ContractID] {
+			seen[term.ContractID] = true
+			contractIDs = append(contractIDs, term.ContractID)
+		}
+	}
+	terminatedAt, err := versionTerminations(db, contractIDs)
+	if err != nil {
+		return nil, err
+	}
+	kept := terms[:0]
+	for _, term := range terms {
+		if term.Status == models.TerminatedContract {
+			terminated, ok := terminatedAt[term.VersionID]
+			if !ok || !terminated.After(term.StartDate) {
+				continue
+			}
+			if terminated.Before(term.EndDate) {
+				term.EndDate = terminated
+			}
+		}
+		kept = append(kept, term)
+	}
+	terms = kept
+
+	now := time.Now()
+	stats := retention(terms, months, now)
+	if filter == nil || filter.GroupBy == nil {
+		return stats, nil
+	}
+
+	// Splitting keeps the terms of each group in order
+	byKey := map[string][]retentionTerm{}
+	for _, term := range terms {
+		byKey[term.Key] = append(byKey[term.Key], term)
+	}
+	stats.Groups = make([]RetentionGroup, 0, len(byKey))
+	for key, groupTerms := range byKey {
+		stats.Groups = append(stats.Groups, RetentionGroup{Key: key, RetentionStatistics: *retention(groupTerms, months, now)})
+	}
+	sort.Slice(stats.Groups, func(i, j int) bool { return stats.Groups[i].Key < stats.Groups[j].Key })
+
+	return stats, nil
+}
+
+// retention computes the statistics of terms sorted by address, tenant and start
+func retention(terms []retentionTerm, months int, now time.Time) *RetentionStatistics {
+	stats := &RetentionStatistics{Churn: make([]ChurnMonth, months)}
+
+	// Terms come sorted by address, tenant and start, so each tenancy is a run of them
+	var tenancies []tenancy
+	for i, term := range terms {
+		continues := i > 0 && terms[i-1].AddressID == term.AddressID && terms[i-1].TenantID == term.TenantID &&
+			term.StartDate.Sub(tenancies[len(tenancies)-1].End) <= renewalGrace
+		if continues {
+			current := &tenancies[len(tenancies)-1]
+			if term.EndDate.After(current.End) {
+				current.End = term.EndDate
+			}
+		} else {
+			tenancies = append(tenancies, tenancy{AddressID: term.AddressID, Start: term.StartDate, End: term.EndDate})
+		}
+
+		if term.EndDate.After(now) {
+			continue
+		}
+		next := i + 1
+		sameTenancy := next < len(terms) && terms[next].AddressID == term.AddressID && terms[next].TenantID == term.TenantID
+		if sameTenancy && term.EndDate.Sub(terms[next].StartDate) > renewalGrace {
+			// The next version amends this one rather than following it
+			continue
+		}
+		stats.EndedTerms++
+		if sameTenancy && terms[next].StartDate.Sub(term.EndDate) <= renewalGrace {
+			stats.RenewedTerms++
+		}
+	}
+	if stats.EndedTerms > 0 {
+		stats.RenewalRate = float64(stats.RenewedTerms) / float64(stats.EndedTerms) * 100
+	}
+
+	var tenure, vacancy time.Duration
+	byAddress := map[uuid.UUID][]tenancy{}
+	for _, t := range tenancies {
+		end := t.End
+		if end.After(now) {
+			end = now
+		}
+		if end.After(t.Start) {
+			tenure += end.Sub(t.Start)
+		}
+		byAddress[t.AddressID] = append(byAddress[t.AddressID], t)
+	}
+
+	// A property is vacant from the end of a tenancy until the next one starts
+	for _, addressTenancies := range byAddress {
+		sort.Slice(addressTenancies, func(i, j int) bool { return addressTenancies[i].Start.Before(addressTenancies[j].Start) })
+		occupiedUntil := addressTenancies[0].End
+		for _, t := range addressTenancies[1:] {
+			if t.Start.After(occupiedUntil) {
+				vacancy += t.Start.Sub(occupiedUntil)
+				stats.Vacancies++
+			}
+			if t.End.After(occupiedUntil) {
+				occupiedUntil = t.End
+			}
+		}
+	}
+	stats.Tenancies = len(tenancies)
+	if stats.Tenancies > 0 {
+		stats.AverageTenure = int(tenure.Hours() / 24 / float64(stats.Tenancies))
+	}
+	if stats.Vacancies > 0 {
+		stats.AverageVacancy = int(vacancy.Hours() / 24 / float64(stats.Vacancies))
+	}
+
+	first := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -(months - 1), 0)
+	for i := range stats.Churn {
+		month := first.AddDate(0, i, 0)
+		monthEnd := month.AddDate(0, 1, 0)
+		churn := &stats.Churn[i]
+		churn.Month = month.Format("2006-01")
+
+		for _, t := range tenancies {
+			if t.Start.Before(month) && !t.End.Before(month) {
+				churn.ActiveTenancies++
+			}
+			if !t.End.Before(month) && t.End.Before(monthEnd) && !t.End.After(now) {
+				churn.MoveOuts++
+			}
+		}
+		if churn.ActiveTenancies > 0 {
+			churn.ChurnRate = float64(churn.MoveOuts) / float64(churn.ActiveTenancies) * 100
+		}
+	}
+
+	return stats
+}
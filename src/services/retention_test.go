@@ -0,0 +1,30 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/edfloreshz/rent-contracts/src/models"
+)
+
+func TestRetentionTermination(t *testing.T) {
+	db := testDatabase(t)
+	p := seedPortfolio(t, db, "Monterrey", 1000)
+
+	// The seeded version runs for another month, until it is terminated now
+	if err := db.Model(&models.ContractVersion{}).Where("id = ?", p.version.ID).
+		Update("status", models.TerminatedContract).Error; err != nil {
+		t.Fatalf("terminate version: %v", err)
+	}
+
+	stats, err := NewStatisticsService(db).GetRetention(p.ctx, nil, 1)
+	if err != nil {
+		t.Fatalf("retention: %v", err)
+	}
+
+	if stats.EndedTerms != 1 || stats.RenewedTerms != 0 {
+		t.Errorf("got %d ended and %d renewed terms, want the terminated one ended", stats.EndedTerms, stats.RenewedTerms)
+	}
+	if churn := stats.Churn[0]; churn.MoveOuts != 1 {
+		t.Errorf("got %d move-outs in %s, want 1", churn.MoveOuts, churn.Month)
+	}
+}
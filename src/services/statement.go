@@ -0,0 +1,420 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/edfloreshz/rent-contracts/src/models"
+	"github.com/google/uuid"
+	"github.com/johnfercher/maroto/v2"
+	"github.com/johnfercher/maroto/v2/pkg/components/row"
+	"github.com/johnfercher/maroto/v2/pkg/components/text"
+	"github.com/johnfercher/maroto/v2/pkg/config"
+	"github.com/johnfercher/maroto/v2/pkg/consts/align"
+	"github.com/johnfercher/maroto/v2/pkg/consts/fontstyle"
+	"github.com/johnfercher/maroto/v2/pkg/consts/pagesize"
+	"github.com/johnfercher/maroto/v2/pkg/core"
+	"github.com/johnfercher/maroto/v2/pkg/props"
+	"gorm.io/gorm"
+)
+
+// Sections of the lines of an owner statement
+const (
+	StatementRent    = "rent"
+	StatementLateFee = "lateFee"
+	StatementExpense = "expense"
+	StatementDeposit = "deposit"
+)
+
+// statementSectionTitles label the sections in the exported files
+var statementSectionTitles = map[string]string{
+	StatementRent:    "Rent collected",
+	StatementLateFee: "Late fees",
+	StatementExpense: "Expenses",
+	StatementDeposit: "Deposits held",
+}
+
+// OwnerStatement is what was collected and spent for a landlord in a month and
+// what is left to disburse to them. Deposits held are listed but not disbursed.
+type OwnerStatement struct {
+	LandlordID      uuid.UUID       `json:"landlordId"`
+	Landlord        string          `json:"landlord"`
+	PeriodStart     time.Time       `json:"periodStart"`
+	PeriodEnd       time.Time       `json:"periodEnd"`
+	RentCollected   float64         `json:"rentCollected"`
+	LateFees        float64         `json:"lateFees"`
+	DepositsHeld    float64         `json:"depositsHeld"`
+	Expenses        float64         `json:"expenses"`
+	CommissionRate  float64         `json:"commissionRate"`
+	Commission      float64         `json:"commission"`
+	NetDisbursement float64         `json:"netDisbursement"`
+	Lines           []StatementLine `json:"lines"`
+	Documents       []uuid.UUID     `json:"documents"`
+}
+
+// StatementLine is a payment, expense or deposit on an owner statement
+type StatementLine struct {
+	Section     string     `json:"section"`
+	Date        *time.Time `json:"date"`
+	Property    string     `json:"property"`
+	Tenant      string     `json:"tenant"`
+	Description string     `json:"description"`
+	Amount      float64    `json:"amount"`
+}
+
+type StatementService struct {
+	db  *gorm.DB
+	uow *UnitOfWork
+}
+
+func NewStatementService(db *gorm.DB) *StatementService {
+	return &StatementService{
+		db:  db,
+		uow: NewUnitOfWork(db),
+	}
+}
+
+// CreateOwnerStatement computes the statement of a landlord for the month starting
+// at period and archives it as PDF and CSV. Each call archives a new copy, so a
+// statement can be reissued after late payments or expenses are recorded.
+func (s *StatementService) CreateOwnerStatement(ctx context.Context, landlordID uuid.UUID, period time.Time) (*OwnerStatement, error) {
+	periodStart := time.Date(period.Year(), period.Month(), 1, 0, 0, 0, 0, time.UTC)
+	periodEnd := periodStart.AddDate(0, 1, 0)
+
+	var statement *OwnerStatement
+	err := s.uow.Do(ctx, func(tx *gorm.DB) error {
+		var err error
+		statement, err = ownerStatement(tx, landlordID, periodStart, periodEnd)
+		if err != nil {
+			return err
+		}
+
+		pdf, err := OwnerStatementPDF(statement)
+		if err != nil {
+			return err
+		}
+		csv, err := OwnerStatementCSV(statement)
+		if err != nil {
+			return err
+		}
+
+		baseName := fmt.Sprintf("statement-%s-%s", landlordID, periodStart.Format("2006-01"))
+		for _, file := range []struct {
+			extension   string
+			contentType string
+			content     []byte
+		}{
+			{"pdf", "application/pdf", pdf},
+			{"csv", "text/csv", csv},
+		} {
+			document := &models.Document{
+				Kind:        models.OwnerStatementDocument,
+				SubjectID:   landlordID,
+				PeriodStart: periodStart,
+				PeriodEnd:   periodEnd.AddDate(0, 0, -1),
+				FileName:    baseName + "." + file.extension,
+				ContentType: file.contentType,
+				Content:     file.content,
+			}
+			if err := tx.Create(document).Error; err != nil {
+				return err
+			}
+			statement.Documents = append(statement.Documents, document.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return statement, nil
+}
+
+// GetOwnerStatements lists the archived statement documents, optionally of one
+// landlord, the latest period first. Their content is not loaded.
+func (s *StatementService) GetOwnerStatements(ctx context.Context, landlordID *uuid.UUID) ([]models.Document, error) {
+	query := s.db.WithContext(ctx).Omit("content").Where("kind = ?", models.OwnerStatementDocument)
+	if landlordID != nil {
+		query = query.Where("subjectid = ?", *landlordID)
+	}
+
+	var documents []models.Document
+	if err := query.Order("periodstart DESC, createdat DESC").Find(&documents).Error; err != nil {
+		return nil, err
+	}
+	return documents, nil
+}
+
+// GetDocument loads an archived document with its content
+func (s *StatementService) GetDocument(ctx context.Context, id uuid.UUID) (*models.Document, error) {
+	var document models.Document
+	if err := s.db.WithContext(ctx).First(&document, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("document not found")
+		}
+		return nil, err
+	}
+	return &document, nil
+}
+
+// ownerStatement gathers the payments towards the contracts of a landlord, the
+// expenses paid for them and the deposits of their contracts in force at the end of
+// the period
+func ownerStatement(tx *gorm.DB, landlordID uuid.UUID, periodStart, periodEnd time.Time) (*OwnerStatement, error) {
+	var landlord models.User
+	if err := tx.Unscoped().First(&landlord, landlordID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("landlord not found")
+		}
+		return nil, err
+	}
+
+	var organization models.Organization
+	if err := tx.First(&organization, landlord.OrganizationID).Error; err != nil {
+		return nil, err
+	}
+
+	statement := &OwnerStatement{
+		LandlordID:     landlord.ID,
+		Landlord:       landlord.FullName(),
+		PeriodStart:    periodStart,
+		PeriodEnd:      periodEnd.AddDate(0, 0, -1),
+		CommissionRate: organization.ManagementCommission,
+		Lines:          []StatementLine{},
+		Documents:      []uuid.UUID{},
+	}
+
+	var contracts []models.Contract
+	if err := tx.Unscoped().Preload("Tenant", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("Address", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("Versions").
+		Where("landlordid = ?", landlordID).
+		Find(&contracts).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]models.Contract, len(contracts))
+	contractIDs := make([]uuid.UUID, len(contracts))
+	for i, contract := range contracts {
+		byID[contract.ID] = contract
+		contractIDs[i] = contract.ID
+	}
+
+	var payments []models.Payment
+	if len(contractIDs) > 0 {
+		if err := tx.Where("contractid IN ? AND paidat >= ? AND paidat < ?", contractIDs, periodStart, periodEnd).
+			Order("paidat, createdat").
+			Find(&payments).Error; err != nil {
+			return nil, err
+		}
+	}
+	for _, payment := range payments {
+		contract := byID[payment.ContractID]
+		line := StatementLine{
+			Section:  StatementRent,
+			Date:     &payment.PaidAt,
+			Property: contract.Address.FullAddress(),
+			Tenant:   contract.Tenant.FullName(),
+			Amount:   payment.Amount,
+		}
+		if payment.Reference != nil {
+			line.Description = *payment.Reference
+		}
+		if payment.Type == models.LateFeePayment {
+			line.Section = StatementLateFee
+			statement.LateFees += payment.Amount
+		} else {
+			statement.RentCollected += payment.Amount
+		}
+		statement.Lines = append(statement.Lines, line)
+	}
+
+	var expenses []models.Expense
+	if err := tx.Where("landlordid = ? AND paidat >= ? AND paidat < ?", landlordID, periodStart, periodEnd).
+		Order("paidat, createdat").
+		Find(&expenses).Error; err != nil {
+		return nil, err
+	}
+	for _, expense := range expenses {
+		line := StatementLine{
+			Section:     StatementExpense,
+			Date:        &expense.PaidAt,
+			Description: expense.Description,
+			Amount:      expense.Amount,
+		}
+		if expense.AddressID != nil {
+			var address models.Address
+			if err := tx.Unscoped().First(&address, *expense.AddressID).Error; err == nil {
+				line.Property = address.FullAddress()
+			}
+		}
+		statement.Expenses += expense.Amount
+		statement.Lines = append(statement.Lines, line)
+	}
+
+	// A deposit is held while a version of its contract covers the last day of the period
+	lastDay := periodEnd.AddDate(0, 0, -1)
+	for _, contract := range contracts {
+		if contract.DeletedAt.Valid || contract.Deposit <= 0 {
+			continue
+		}
+		for _, version := range contract.Versions {
+			if !version.StartDate.After(lastDay) && !version.EndDate.Before(lastDay) {
+				statement.DepositsHeld += contract.Deposit
+				statement.Lines = append(statement.Lines, StatementLine{
+					Section:  StatementDeposit,
+					Property: contract.Address.FullAddress(),
+					Tenant:   contract.Tenant.FullName(),
+					Amount:   contract.Deposit,
+				})
+				break
+			}
+		}
+	}
+
+	statement.Commission = roundCents((statement.RentCollected + statement.LateFees) * statement.CommissionRate / 100)
+	statement.NetDisbursement = roundCents(statement.RentCollected + statement.LateFees - statement.Expenses - statement.Commission)
+
+	return statement, nil
+}
+
+// summary lists the totals of a statement as label and amount pairs
+func (s *OwnerStatement) summary() [][2]string {
+	amount := func(f float64) string { return strconv.FormatFloat(f, 'f', 2, 64) }
+	// Deductions are shown negative, subtracting from zero so none show as -0.00
+	return [][2]string{
+		{"Rent collected", amount(s.RentCollected)},
+		{"Late fees", amount(s.LateFees)},
+		{"Expenses", amount(0 - s.Expenses)},
+		{fmt.Sprintf("Management commission (%s%%)", strconv.FormatFloat(s.CommissionRate, 'f', -1, 64)), amount(0 - s.Commission)},
+		{"Net to disburse", amount(s.NetDisbursement)},
+		{"Deposits held", amount(s.DepositsHeld)},
+	}
+}
+
+// values formats a line for the exported files
+func (l StatementLine) values() []string {
+	date := ""
+	if l.Date != nil {
+		date = l.Date.Format(time.DateOnly)
+	}
+	return []string{statementSectionTitles[l.Section], date, l.Property, l.Tenant, l.Description, strconv.FormatFloat(l.Amount, 'f', 2, 64)}
+}
+
+// OwnerStatementCSV renders a statement as CSV: its lines under a header row,
+// followed by the totals
+func OwnerStatementCSV(statement *OwnerStatement) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+
+	records := [][]string{
+		{"Landlord", statement.Landlord},
+		{"Period", statement.PeriodStart.Format(time.DateOnly), statement.PeriodEnd.Format(time.DateOnly)},
+		{},
+		{"Section", "Date", "Property", "Tenant", "Description", "Amount"},
+	}
+	for _, line := range statement.Lines {
+		records = append(records, line.values())
+	}
+	records = append(records, []string{})
+	for _, total := range statement.summary() {
+		records = append(records, []string{"Summary", "", "", "", total[0], total[1]})
+	}
+
+	if err := writer.WriteAll(records); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// OwnerStatementPDF renders a statement as a PDF with a table per section and
+// the totals at the end
+func OwnerStatementPDF(statement *OwnerStatement) ([]byte, error) {
+	cfg := config.NewBuilder().
+		WithPageSize(pagesize.Letter).
+		WithLeftMargin(12).
+		WithTopMargin(12).
+		WithRightMargin(12).
+		WithBottomMargin(12).
+		WithPageNumber().
+		Build()
+
+	m := maroto.New(cfg)
+
+	m.AddRows(
+		text.NewRow(10, "OWNER STATEMENT", props.Text{
+			Style: fontstyle.Bold,
+			Size:  14,
+			Align: align.Center,
+		}),
+		text.NewRow(6, statement.Landlord, props.Text{
+			Style: fontstyle.Bold,
+			Size:  9,
+			Align: align.Center,
+		}),
+		text.NewRow(8, fmt.Sprintf("%s to %s", statement.PeriodStart.Format(time.DateOnly), statement.PeriodEnd.Format(time.DateOnly)), props.Text{
+			Size:  8,
+			Align: align.Center,
+		}),
+	)
+
+	for _, section := range []string{StatementRent, StatementLateFee, StatementExpense, StatementDeposit} {
+		var lines []StatementLine
+		for _, line := range statement.Lines {
+			if line.Section == section {
+				lines = append(lines, line)
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+
+		rows := []core.Row{
+			text.NewRow(7, statementSectionTitles[section], props.Text{Size: 9, Top: 2, Style: fontstyle.Bold}),
+			row.New(5).Add(
+				text.NewCol(2, "Date", props.Text{Size: 8, Align: align.Center, Style: fontstyle.Bold, Color: &props.WhiteColor}),
+				text.NewCol(4, "Property", props.Text{Size: 8, Align: align.Center, Style: fontstyle.Bold, Color: &props.WhiteColor}),
+				text.NewCol(2, "Tenant", props.Text{Size: 8, Align: align.Center, Style: fontstyle.Bold, Color: &props.WhiteColor}),
+				text.NewCol(2, "Description", props.Text{Size: 8, Align: align.Center, Style: fontstyle.Bold, Color: &props.WhiteColor}),
+				text.NewCol(2, "Amount", props.Text{Size: 8, Align: align.Center, Style: fontstyle.Bold, Color: &props.WhiteColor}),
+			).WithStyle(&props.Cell{BackgroundColor: darkGrayColor()}),
+		}
+		for i, line := range lines {
+			values := line.values()
+			r := row.New(6).Add(
+				text.NewCol(2, values[1], props.Text{Size: 7, Top: 1, Align: align.Center}),
+				text.NewCol(4, values[2], props.Text{Size: 7, Top: 1, Align: align.Left}),
+				text.NewCol(2, values[3], props.Text{Size: 7, Top: 1, Align: align.Center}),
+				text.NewCol(2, values[4], props.Text{Size: 7, Top: 1, Align: align.Center}),
+				text.NewCol(2, values[5], props.Text{Size: 7, Top: 1, Align: align.Right}),
+			)
+			if i%2 == 0 {
+				r.WithStyle(&props.Cell{BackgroundColor: grayColor()})
+			}
+			rows = append(rows, r)
+		}
+		m.AddRows(rows...)
+	}
+
+	m.AddRows(text.NewRow(8, "SUMMARY", props.Text{Size: 9, Top: 3, Style: fontstyle.Bold}))
+	for _, total := range statement.summary() {
+		style := fontstyle.Normal
+		if total[0] == "Net to disburse" {
+			style = fontstyle.Bold
+		}
+		m.AddRows(row.New(5).Add(
+			text.NewCol(10, total[0], props.Text{Size: 8, Style: style, Align: align.Right}),
+			text.NewCol(2, total[1], props.Text{Size: 8, Style: style, Align: align.Right}),
+		))
+	}
+
+	document, err := m.Generate()
+	if err != nil {
+		return nil, err
+	}
+	return document.GetBytes(), nil
+}
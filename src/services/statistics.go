@@ -1,12 +1,31 @@
 package services
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
 
 	"github.com/edfloreshz/rent-contracts/src/models"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// Time series intervals
+const (
+	MonthInterval   = "month"
+	QuarterInterval = "quarter"
+	YearInterval    = "year"
+)
+
+// maxTimeSeriesBuckets limits how many buckets a single time series can have
+const maxTimeSeriesBuckets = 240
+
+// ErrInvalidTimeSeries is returned when the range or interval of a time series is malformed
+var ErrInvalidTimeSeries = errors.New("invalid time series")
+
 type StatisticsService struct {
 	db *gorm.DB
 }
@@ -36,6 +55,77 @@ type OverallStatistics struct {
 	// Performance Statistics
 	OccupancyRate           float64 `json:"occupancyRate"`
 	AverageContractDuration int     `json:"averageContractDuration"` // in days
+
+	// Breakdown per group, when grouping was requested
+	Groups []StatisticsGroup `json:"groups,omitempty"`
+}
+
+// TimeSeriesFilter selects the range and bucket size of a time series. From is
+// truncated to the start of its bucket and To is exclusive.
+type TimeSeriesFilter struct {
+	StatisticsFilter
+	From     time.Time
+	To       time.Time
+	Interval string
+}
+
+// TimeSeriesMetrics are the statistics of one interval of a time series
+type TimeSeriesMetrics struct {
+	ActiveContracts int64   `json:"activeContracts"`
+	NewContracts    int64   `json:"newContracts"`
+	EndedContracts  int64   `json:"endedContracts"`
+	OccupancyRate   float64 `json:"occupancyRate"`
+
+	// BilledRent is the rent of every month of the bucket a contract covered
+	BilledRent float64 `json:"billedRent"`
+	// CollectedRent is the rent paid during the bucket
+	CollectedRent float64 `json:"collectedRent"`
+}
+
+// TimeSeriesGroup holds the statistics of one group in an interval of a time series
+type TimeSeriesGroup struct {
+	Key string `json:"key"`
+	TimeSeriesMetrics
+}
+
+// TimeSeriesBucket holds the statistics of one interval of a time series. The last
+// bucket ends at the end of the range rather than of its interval.
+type TimeSeriesBucket struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	TimeSeriesMetrics
+
+	// Breakdown per group, when grouping was requested
+	Groups []TimeSeriesGroup `json:"groups,omitempty"`
+}
+
+// timeSeriesVersion is a contract version with the fields the time series needs
+type timeSeriesVersion struct {
+	ContractID   uuid.UUID `gorm:"column:contractid"`
+	LandlordID   uuid.UUID `gorm:"column:landlordid"`
+	AddressID    uuid.UUID `gorm:"column:addressid"`
+	City         string    `gorm:"column:city"`
+	State        string    `gorm:"column:state"`
+	Neighborhood string    `gorm:"column:neighborhood"`
+	Rent         float64   `gorm:"column:rent"`
+	StartDate    time.Time `gorm:"column:startdate"`
+	EndDate      time.Time `gorm:"column:enddate"`
+}
+
+// timeSeriesPayment is a payment with the fields the time series needs
+type timeSeriesPayment struct {
+	ContractID uuid.UUID `gorm:"column:contractid"`
+	Amount     float64   `gorm:"column:amount"`
+	PaidAt     time.Time `gorm:"column:paidat"`
+}
+
+// timeSeriesProperty is a property with the fields the time series needs
+type timeSeriesProperty struct {
+	ID           uuid.UUID `gorm:"column:id"`
+	City         string    `gorm:"column:city"`
+	State        string    `gorm:"column:state"`
+	Neighborhood string    `gorm:"column:neighborhood"`
+	CreatedAt    time.Time `gorm:"column:createdat"`
 }
 
 func NewStatisticsService(db *gorm.DB) *StatisticsService {
@@ -44,12 +134,16 @@ func NewStatisticsService(db *gorm.DB) *StatisticsService {
 	}
 }
 
-// GetOverallContractStatistics returns comprehensive statistics for landlords
-func (s *StatisticsService) GetOverallContractStatistics() (*OverallStatistics, error) {
+// GetOverallContractStatistics returns comprehensive statistics for landlords,
+// narrowed down and broken down as the filter asks
+func (s *StatisticsService) GetOverallContractStatistics(ctx context.Context, filter *StatisticsFilter) (*OverallStatistics, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
 	stats := &OverallStatistics{}
 
 	// Total contracts
-	err := s.db.Model(&models.Contract{}).
+	err := s.db.WithContext(ctx).Model(&models.Contract{}).Scopes(filter.contractScope).
 		Where("deletedat IS NULL").
 		Count(&stats.TotalContracts).Error
 	if err != nil {
@@ -57,7 +151,7 @@ func (s *StatisticsService) GetOverallContractStatistics() (*OverallStatistics,
 	}
 
 	// Active contracts (contracts with status = 'active' in current version)
-	err = s.db.Table("contracts").
+	err = s.db.WithContext(ctx).Model(&models.Contract{}).Scopes(filter.contractScope).
 		Joins("LEFT JOIN contractversions ON contracts.currentversionid = contractversions.id").
 		Where("contracts.deletedat IS NULL AND contractversions.status = ?", models.ActiveContract).
 		Count(&stats.ActiveContracts).Error
@@ -66,7 +160,7 @@ func (s *StatisticsService) GetOverallContractStatistics() (*OverallStatistics,
 	}
 
 	// Expired contracts (contracts with status = 'expired' in current version)
-	err = s.db.Table("contracts").
+	err = s.db.WithContext(ctx).Model(&models.Contract{}).Scopes(filter.contractScope).
 		Joins("LEFT JOIN contractversions ON contracts.currentversionid = contractversions.id").
 		Where("contracts.deletedat IS NULL AND contractversions.status = ?", models.ExpiredContract).
 		Count(&stats.ExpiredContracts).Error
@@ -75,7 +169,7 @@ func (s *StatisticsService) GetOverallContractStatistics() (*OverallStatistics,
 	}
 
 	// Total properties (addresses of type 'property')
-	err = s.db.Model(&models.Address{}).
+	err = s.db.WithContext(ctx).Model(&models.Address{}).Scopes(filter.propertyScope).
 		Where("deletedat IS NULL AND type = ?", models.PropertyAddress).
 		Count(&stats.TotalProperties).Error
 	if err != nil {
@@ -83,7 +177,7 @@ func (s *StatisticsService) GetOverallContractStatistics() (*OverallStatistics,
 	}
 
 	// Occupied properties (properties with active contracts)
-	err = s.db.Table("addresses").
+	err = s.db.WithContext(ctx).Model(&models.Address{}).Scopes(filter.propertyScope, filter.contractScope).
 		Joins("JOIN contracts ON addresses.id = contracts.addressid").
 		Joins("LEFT JOIN contractversions ON contracts.currentversionid = contractversions.id").
 		Where("addresses.deletedat IS NULL AND addresses.type = ? AND contracts.deletedat IS NULL AND contractversions.status = ?", models.PropertyAddress, models.ActiveContract).
@@ -96,7 +190,7 @@ func (s *StatisticsService) GetOverallContractStatistics() (*OverallStatistics,
 	stats.VacantProperties = stats.TotalProperties - stats.OccupiedProperties
 
 	// Total tenants
-	err = s.db.Model(&models.User{}).
+	err = s.db.WithContext(ctx).Model(&models.User{}).Scopes(filter.userScope).
 		Where("deletedat IS NULL AND type = ?", "tenant").
 		Count(&stats.TotalTenants).Error
 	if err != nil {
@@ -104,7 +198,7 @@ func (s *StatisticsService) GetOverallContractStatistics() (*OverallStatistics,
 	}
 
 	// Total references
-	err = s.db.Model(&models.User{}).
+	err = s.db.WithContext(ctx).Model(&models.User{}).Scopes(filter.userScope).
 		Where("deletedat IS NULL AND type = ?", "reference").
 		Count(&stats.TotalReferences).Error
 	if err != nil {
@@ -112,7 +206,7 @@ func (s *StatisticsService) GetOverallContractStatistics() (*OverallStatistics,
 	}
 
 	// Active tenants (tenants with active contracts)
-	err = s.db.Table("users").
+	err = s.db.WithContext(ctx).Model(&models.User{}).Scopes(filter.userScope, filter.contractScope).
 		Joins("JOIN contracts ON users.id = contracts.tenantid").
 		Joins("LEFT JOIN contractversions ON contracts.currentversionid = contractversions.id").
 		Where("users.deletedat IS NULL AND users.type = ? AND contracts.deletedat IS NULL AND contractversions.status = ?", "tenant", models.ActiveContract).
@@ -122,7 +216,7 @@ func (s *StatisticsService) GetOverallContractStatistics() (*OverallStatistics,
 	}
 
 	// Monthly revenue (sum of rent from active contracts)
-	err = s.db.Table("contracts").
+	err = s.db.WithContext(ctx).Model(&models.Contract{}).Scopes(filter.contractScope).
 		Joins("LEFT JOIN contractversions ON contracts.currentversionid = contractversions.id").
 		Where("contracts.deletedat IS NULL AND contractversions.status = ?", models.ActiveContract).
 		Select("COALESCE(SUM(contractversions.rent), 0)").
@@ -145,11 +239,15 @@ func (s *StatisticsService) GetOverallContractStatistics() (*OverallStatistics,
 		stats.OccupancyRate = (float64(stats.OccupiedProperties) / float64(stats.TotalProperties)) * 100
 	}
 
-	// Average contract duration (in days)
+	// Average contract duration (in days), from the start of the first version of
+	// each contract to the end of its last one
 	var avgDuration sql.NullFloat64
-	err = s.db.Table("contractversions").
-		Where("startdate IS NOT NULL AND enddate IS NOT NULL").
-		Select("AVG(enddate - startdate)").
+	spans := s.db.WithContext(ctx).Model(&models.Contract{}).Scopes(filter.contractScope).
+		Joins("JOIN contractversions ON contractversions.contractid = contracts.id").
+		Select("MAX(contractversions.enddate) - MIN(contractversions.startdate) AS span").
+		Group("contracts.id")
+	err = s.db.WithContext(ctx).Table("(?) AS spans", spans).
+		Select("AVG(span)").
 		Scan(&avgDuration).Error
 	if err != nil {
 		return nil, err
@@ -158,5 +256,266 @@ func (s *StatisticsService) GetOverallContractStatistics() (*OverallStatistics,
 		stats.AverageContractDuration = int(avgDuration.Float64)
 	}
 
+	if filter != nil && filter.GroupBy != nil {
+		stats.Groups, err = s.groups(s.db.WithContext(ctx), filter)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return stats, nil
 }
+
+// GetTimeSeries returns the statistics of every interval between filter.From and
+// filter.To. A contract is active in a bucket when one of its versions overlaps
+// it, starts when its first version starts and ends when its last version ends.
+func (s *StatisticsService) GetTimeSeries(ctx context.Context, filter *TimeSeriesFilter) ([]TimeSeriesBucket, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	buckets, err := timeSeriesBuckets(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var versions []timeSeriesVersion
+	err = s.db.WithContext(ctx).Model(&models.Contract{}).
+		Scopes(filter.contractScope).
+		Joins("JOIN contractversions ON contractversions.contractid = contracts.id").
+		Joins("JOIN addresses ON addresses.id = contracts.addressid").
+		Where("contracts.deletedat IS NULL AND contractversions.startdate < ?", filter.To).
+		Select("contracts.id AS contractid, contracts.landlordid, contracts.addressid, addresses.city, addresses.state, addresses.neighborhood, " +
+			"contractversions.rent, contractversions.startdate, contractversions.enddate").
+		Order("contractversions.startdate").
+		Scan(&versions).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var properties []timeSeriesProperty
+	err = s.db.WithContext(ctx).Model(&models.Address{}).
+		Scopes(filter.propertyScope).
+		Where("addresses.deletedat IS NULL AND addresses.type = ? AND addresses.createdat < ?", models.PropertyAddress, filter.To).
+		Select("addresses.id, addresses.city, addresses.state, addresses.neighborhood, addresses.createdat").
+		Scan(&properties).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var payments []timeSeriesPayment
+	err = s.db.WithContext(ctx).Model(&models.Payment{}).
+		Joins("JOIN contracts ON contracts.id = payments.contractid").
+		Scopes(filter.contractScope).
+		Where("contracts.deletedat IS NULL AND payments.type = ? AND payments.paidat >= ? AND payments.paidat < ?", models.RentPayment, buckets[0].Start, filter.To).
+		Select("payments.contractid, payments.amount, payments.paidat").
+		Scan(&payments).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// The first and last day each contract covers
+	firstStart := map[uuid.UUID]time.Time{}
+	lastEnd := map[uuid.UUID]time.Time{}
+	for _, version := range versions {
+		if start, ok := firstStart[version.ContractID]; !ok || version.StartDate.Before(start) {
+			firstStart[version.ContractID] = version.StartDate
+		}
+		if end, ok := lastEnd[version.ContractID]; !ok || version.EndDate.After(end) {
+			lastEnd[version.ContractID] = version.EndDate
+		}
+	}
+
+	var groupVersions map[string][]timeSeriesVersion
+	var groupProperties map[string][]timeSeriesProperty
+	if filter.GroupBy != nil {
+		groupVersions, groupProperties = groupTimeSeries(*filter.GroupBy, versions, properties)
+	}
+
+	for i := range buckets {
+		bucket := &buckets[i]
+		bucket.TimeSeriesMetrics = timeSeriesMetrics(bucket.Start, bucket.End, versions, properties, payments, firstStart, lastEnd)
+
+		if filter.GroupBy == nil {
+			continue
+		}
+		keys := make([]string, 0, len(groupVersions)+len(groupProperties))
+		for key := range groupProperties {
+			keys = append(keys, key)
+		}
+		for key := range groupVersions {
+			if _, ok := groupProperties[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			bucket.Groups = append(bucket.Groups, TimeSeriesGroup{
+				Key:               key,
+				TimeSeriesMetrics: timeSeriesMetrics(bucket.Start, bucket.End, groupVersions[key], groupProperties[key], payments, firstStart, lastEnd),
+			})
+		}
+	}
+
+	return buckets, nil
+}
+
+// timeSeriesMetrics computes the statistics of the interval between start and end
+// from the versions and properties that fall in it. Only the payments towards the
+// contracts of the versions are counted.
+func timeSeriesMetrics(start, end time.Time, versions []timeSeriesVersion, properties []timeSeriesProperty, payments []timeSeriesPayment, firstStart, lastEnd map[uuid.UUID]time.Time) TimeSeriesMetrics {
+	var metrics TimeSeriesMetrics
+
+	// Only properties that exist by the end of the interval count as occupied, so
+	// the occupancy rate is measured against them
+	existing := map[uuid.UUID]bool{}
+	for _, property := range properties {
+		if property.CreatedAt.Before(end) {
+			existing[property.ID] = true
+		}
+	}
+
+	contracts := map[uuid.UUID]bool{}
+	active := map[uuid.UUID]bool{}
+	occupied := map[uuid.UUID]bool{}
+	for _, version := range versions {
+		contracts[version.ContractID] = true
+		if version.StartDate.Before(end) && version.EndDate.After(start) {
+			active[version.ContractID] = true
+			if existing[version.AddressID] {
+				occupied[version.AddressID] = true
+			}
+		}
+	}
+	metrics.ActiveContracts = int64(len(active))
+
+	for _, payment := range payments {
+		if contracts[payment.ContractID] && !payment.PaidAt.Before(start) && payment.PaidAt.Before(end) {
+			metrics.CollectedRent += payment.Amount
+		}
+	}
+
+	for contractID := range contracts {
+		if first := firstStart[contractID]; !first.Before(start) && first.Before(end) {
+			metrics.NewContracts++
+		}
+		if last := lastEnd[contractID]; !last.Before(start) && last.Before(end) {
+			metrics.EndedContracts++
+		}
+	}
+
+	if len(existing) > 0 {
+		metrics.OccupancyRate = float64(len(occupied)) / float64(len(existing)) * 100
+	}
+
+	// Each month is billed at the rent of the latest version of a contract covering it
+	for month := start; month.Before(end); month = month.AddDate(0, 1, 0) {
+		monthEnd := month.AddDate(0, 1, 0)
+		rents := map[uuid.UUID]float64{}
+		for _, version := range versions {
+			if version.StartDate.Before(monthEnd) && version.EndDate.After(month) {
+				rents[version.ContractID] = version.Rent
+			}
+		}
+		for _, rent := range rents {
+			metrics.BilledRent += rent
+		}
+	}
+
+	return metrics
+}
+
+// groupTimeSeries splits versions and properties by grouping. The properties of a
+// landlord are those they have had contracts for.
+func groupTimeSeries(groupBy string, versions []timeSeriesVersion, properties []timeSeriesProperty) (map[string][]timeSeriesVersion, map[string][]timeSeriesProperty) {
+	groupVersions := map[string][]timeSeriesVersion{}
+	groupProperties := map[string][]timeSeriesProperty{}
+
+	propertyKey := func(property timeSeriesProperty) string {
+		switch groupBy {
+		case GroupByCity:
+			return property.City
+		case GroupByState:
+			return property.State
+		case GroupByNeighborhood:
+			return property.Neighborhood
+		default:
+			return property.ID.String()
+		}
+	}
+
+	for _, version := range versions {
+		var key string
+		switch groupBy {
+		case GroupByLandlord:
+			key = version.LandlordID.String()
+		case GroupByCity:
+			key = version.City
+		case GroupByState:
+			key = version.State
+		case GroupByNeighborhood:
+			key = version.Neighborhood
+		default:
+			key = version.AddressID.String()
+		}
+		groupVersions[key] = append(groupVersions[key], version)
+	}
+
+	if groupBy != GroupByLandlord {
+		for _, property := range properties {
+			key := propertyKey(property)
+			groupProperties[key] = append(groupProperties[key], property)
+		}
+		return groupVersions, groupProperties
+	}
+
+	byID := map[uuid.UUID]timeSeriesProperty{}
+	for _, property := range properties {
+		byID[property.ID] = property
+	}
+	for key, landlordVersions := range groupVersions {
+		seen := map[uuid.UUID]bool{}
+		for _, version := range landlordVersions {
+			if property, ok := byID[version.AddressID]; ok && !seen[property.ID] {
+				seen[property.ID] = true
+				groupProperties[key] = append(groupProperties[key], property)
+			}
+		}
+	}
+	return groupVersions, groupProperties
+}
+
+// timeSeriesBuckets splits the range of a filter into buckets of its interval
+func timeSeriesBuckets(filter *TimeSeriesFilter) ([]TimeSeriesBucket, error) {
+	var months int
+	switch filter.Interval {
+	case MonthInterval:
+		months = 1
+	case QuarterInterval:
+		months = 3
+	case YearInterval:
+		months = 12
+	default:
+		return nil, fmt.Errorf("%w: invalid interval %q", ErrInvalidTimeSeries, filter.Interval)
+	}
+	if !filter.From.Before(filter.To) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidTimeSeries)
+	}
+
+	from := filter.From.UTC()
+	month := time.Month(1 + (int(from.Month())-1)/months*months)
+	start := time.Date(from.Year(), month, 1, 0, 0, 0, 0, time.UTC)
+
+	var buckets []TimeSeriesBucket
+	for start.Before(filter.To) {
+		if len(buckets) == maxTimeSeriesBuckets {
+			return nil, fmt.Errorf("%w: it can have at most %d buckets", ErrInvalidTimeSeries, maxTimeSeriesBuckets)
+		}
+		end := start.AddDate(0, months, 0)
+		if end.After(filter.To) {
+			end = filter.To
+		}
+		buckets = append(buckets, TimeSeriesBucket{Start: start, End: end})
+		start = end
+	}
+	return buckets, nil
+}
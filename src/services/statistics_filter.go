@@ -0,0 +1,206 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/edfloreshz/rent-contracts/src/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Statistics groupings
+const (
+	GroupByLandlord     = "landlord"
+	GroupByCity         = "city"
+	GroupByState        = "state"
+	GroupByNeighborhood = "neighborhood"
+	GroupByAddress      = "address"
+)
+
+// statisticsGroupKeys is the SQL expression each grouping splits records by. The
+// properties of a contract are joined as addresses.
+var statisticsGroupKeys = map[string]string{
+	GroupByLandlord:     "contracts.landlordid::text",
+	GroupByCity:         "addresses.city",
+	GroupByState:        "addresses.state",
+	GroupByNeighborhood: "addresses.neighborhood",
+	GroupByAddress:      "addresses.id::text",
+}
+
+// ErrInvalidStatisticsFilter is returned when a statistics filter is malformed
+var ErrInvalidStatisticsFilter = errors.New("invalid statistics filter")
+
+// StatisticsFilter narrows statistics down to the contracts of a landlord and to
+// properties by location. GroupBy breaks the figures down per landlord, city,
+// state, neighborhood or address.
+type StatisticsFilter struct {
+	LandlordID   *uuid.UUID
+	City         *string
+	State        *string
+	Neighborhood *string
+	AddressIDs   []uuid.UUID
+	GroupBy      *string
+}
+
+// StatisticsGroup holds the occupancy and revenue of one group of properties
+type StatisticsGroup struct {
+	Key                string  `json:"key"`
+	TotalProperties    int64   `json:"totalProperties"`
+	OccupiedProperties int64   `json:"occupiedProperties"`
+	OccupancyRate      float64 `json:"occupancyRate"`
+	ActiveContracts    int64   `json:"activeContracts"`
+	MonthlyRevenue     float64 `json:"monthlyRevenue"`
+	AverageRent        float64 `json:"averageRent"`
+}
+
+// Validate checks the grouping of the filter
+func (f *StatisticsFilter) Validate() error {
+	if f == nil || f.GroupBy == nil {
+		return nil
+	}
+	if _, ok := statisticsGroupKeys[*f.GroupBy]; !ok {
+		return fmt.Errorf("%w: invalid groupBy %q", ErrInvalidStatisticsFilter, *f.GroupBy)
+	}
+	return nil
+}
+
+// locates reports whether the filter restricts properties by location
+func (f *StatisticsFilter) locates() bool {
+	return f != nil && (f.City != nil || f.State != nil || f.Neighborhood != nil || len(f.AddressIDs) > 0)
+}
+
+// restricts reports whether the filter narrows the statistics down at all
+func (f *StatisticsFilter) restricts() bool {
+	return f != nil && (f.LandlordID != nil || f.locates())
+}
+
+// locationScope applies the location filters to a query on addresses
+func (f *StatisticsFilter) locationScope(db *gorm.DB) *gorm.DB {
+	if f.City != nil {
+		db = db.Where("addresses.city = ?", *f.City)
+	}
+	if f.State != nil {
+		db = db.Where("addresses.state = ?", *f.State)
+	}
+	if f.Neighborhood != nil {
+		db = db.Where("addresses.neighborhood = ?", *f.Neighborhood)
+	}
+	if len(f.AddressIDs) > 0 {
+		db = db.Where("addresses.id IN ?", f.AddressIDs)
+	}
+	return db
+}
+
+// contractScope restricts a query on contracts to those matching the filter
+func (f *StatisticsFilter) contractScope(db *gorm.DB) *gorm.DB {
+	if f == nil {
+		return db
+	}
+	if f.LandlordID != nil {
+		db = db.Where("contracts.landlordid = ?", *f.LandlordID)
+	}
+	if f.locates() {
+		properties := db.Session(&gorm.Session{NewDB: true}).Model(&models.Address{}).Scopes(f.locationScope).Select("addresses.id")
+		db = db.Where("contracts.addressid IN (?)", properties)
+	}
+	return db
+}
+
+// propertyScope restricts a query on addresses to the properties matching the
+// filter. The properties of a landlord are those they have had contracts for.
+func (f *StatisticsFilter) propertyScope(db *gorm.DB) *gorm.DB {
+	if f == nil {
+		return db
+	}
+	if f.LandlordID != nil {
+		rented := db.Session(&gorm.Session{NewDB: true}).Model(&models.Contract{}).Select("contracts.addressid").Where("contracts.landlordid = ?", *f.LandlordID)
+		db = db.Where("addresses.id IN (?)", rented)
+	}
+	return f.locationScope(db)
+}
+
+// userScope restricts a query on users to the tenants and references of the
+// contracts matching the filter
+func (f *StatisticsFilter) userScope(db *gorm.DB) *gorm.DB {
+	if !f.restricts() {
+		return db
+	}
+	contracts := db.Session(&gorm.Session{NewDB: true}).Model(&models.Contract{}).Scopes(f.contractScope)
+	tenants := contracts.Session(&gorm.Session{}).Select("contracts.tenantid")
+	references := db.Session(&gorm.Session{NewDB: true}).Model(&models.ContractReference{}).
+		Select("contractreferences.referenceid").
+		Where("contractreferences.contractid IN (?)", contracts.Session(&gorm.Session{}).Select("contracts.id"))
+	return db.Where("users.id IN (?) OR users.id IN (?)", tenants, references)
+}
+
+// groups breaks the occupancy and revenue of the properties matching the filter
+// down by its grouping, ordered by key
+func (s *StatisticsService) groups(db *gorm.DB, filter *StatisticsFilter) ([]StatisticsGroup, error) {
+	key := statisticsGroupKeys[*filter.GroupBy]
+	groups := map[string]*StatisticsGroup{}
+	group := func(key string) *StatisticsGroup {
+		if groups[key] == nil {
+			groups[key] = &StatisticsGroup{Key: key}
+		}
+		return groups[key]
+	}
+
+	var properties []struct {
+		Key   string
+		Total int64
+	}
+	query := db.Model(&models.Address{}).Scopes(filter.propertyScope)
+	if *filter.GroupBy == GroupByLandlord {
+		query = query.Joins("JOIN contracts ON contracts.addressid = addresses.id AND contracts.deletedat IS NULL")
+	}
+	err := query.
+		Where("addresses.deletedat IS NULL AND addresses.type = ?", models.PropertyAddress).
+		Select(key + " AS key, COUNT(DISTINCT addresses.id) AS total").
+		Group(key).
+		Scan(&properties).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range properties {
+		group(row.Key).TotalProperties = row.Total
+	}
+
+	var active []struct {
+		Key       string
+		Contracts int64
+		Occupied  int64
+		Revenue   float64
+	}
+	err = db.Model(&models.Contract{}).
+		Scopes(filter.contractScope).
+		Joins("JOIN contractversions ON contracts.currentversionid = contractversions.id").
+		Joins("JOIN addresses ON addresses.id = contracts.addressid").
+		Where("contracts.deletedat IS NULL AND contractversions.status = ?", models.ActiveContract).
+		Select(key + " AS key, COUNT(*) AS contracts, COUNT(DISTINCT contracts.addressid) AS occupied, COALESCE(SUM(contractversions.rent), 0) AS revenue").
+		Group(key).
+		Scan(&active).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range active {
+		g := group(row.Key)
+		g.ActiveContracts = row.Contracts
+		g.OccupiedProperties = row.Occupied
+		g.MonthlyRevenue = row.Revenue
+	}
+
+	result := make([]StatisticsGroup, 0, len(groups))
+	for _, g := range groups {
+		if g.ActiveContracts > 0 {
+			g.AverageRent = g.MonthlyRevenue / float64(g.ActiveContracts)
+		}
+		if g.TotalProperties > 0 {
+			g.OccupancyRate = float64(g.OccupiedProperties) / float64(g.TotalProperties) * 100
+		}
+		result = append(result, *g)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result, nil
+}
@@ -0,0 +1,290 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/edfloreshz/rent-contracts/src/models"
+	"github.com/google/uuid"
+	"github.com/johnfercher/maroto/v2"
+	"github.com/johnfercher/maroto/v2/pkg/components/page"
+	"github.com/johnfercher/maroto/v2/pkg/components/row"
+	"github.com/johnfercher/maroto/v2/pkg/components/text"
+	"github.com/johnfercher/maroto/v2/pkg/config"
+	"github.com/johnfercher/maroto/v2/pkg/consts/align"
+	"github.com/johnfercher/maroto/v2/pkg/consts/fontstyle"
+	"github.com/johnfercher/maroto/v2/pkg/consts/pagesize"
+	"github.com/johnfercher/maroto/v2/pkg/core"
+	"github.com/johnfercher/maroto/v2/pkg/props"
+)
+
+// ErrInvalidTaxYear is returned when a tax summary is asked for a year out of range
+var ErrInvalidTaxYear = errors.New("invalid year")
+
+// taxSummaryColumns are the headers of the tax summary CSV, in the order of
+// taxSummaryRecord
+var taxSummaryColumns = []string{
+	"Landlord", "Property", "Payments", "Invoiced payments", "Rent income", "Late fees", "VAT charged", "ISR withheld",
+}
+
+// TaxSummary is the income of each landlord in a calendar year and the taxes on
+// it, for their annual declarations. It is built from the payments received and
+// the tax figures of the invoices recorded with them.
+type TaxSummary struct {
+	Year        int                  `json:"year"`
+	GeneratedAt time.Time            `json:"generatedAt"`
+	Landlords   []LandlordTaxSummary `json:"landlords"`
+}
+
+// TaxTotals add up the payments received for a landlord or property
+type TaxTotals struct {
+	Payments          int     `json:"payments"`
+	InvoicedPayments  int     `json:"invoicedPayments"`
+	RentIncome        float64 `json:"rentIncome"`
+	LateFees          float64 `json:"lateFees"`
+	VAT               float64 `json:"vat"`
+	IncomeTaxWithheld float64 `json:"incomeTaxWithheld"`
+}
+
+// LandlordTaxSummary is the yearly income of a landlord, overall and per property
+type LandlordTaxSummary struct {
+	LandlordID uuid.UUID `json:"landlordId"`
+	Landlord   string    `json:"landlord"`
+	TaxTotals
+	Properties []PropertyTaxSummary `json:"properties"`
+}
+
+// PropertyTaxSummary is the yearly income of a landlord from one property
+type PropertyTaxSummary struct {
+	PropertyID uuid.UUID `json:"propertyId"`
+	Property   string    `json:"property"`
+	TaxTotals
+}
+
+// taxedPayments are the payments of one type towards the contracts of a landlord
+// on a property, added up
+type taxedPayments struct {
+	LandlordID        uuid.UUID          `gorm:"column:landlordid"`
+	AddressID         uuid.UUID          `gorm:"column:addressid"`
+	Type              models.PaymentType `gorm:"column:type"`
+	Payments          int                `gorm:"column:payments"`
+	InvoicedPayments  int                `gorm:"column:invoicedpayments"`
+	Amount            float64            `gorm:"column:amount"`
+	VAT               float64            `gorm:"column:vat"`
+	IncomeTaxWithheld float64            `gorm:"column:incometaxwithheld"`
+}
+
+// add counts payments of one type in the totals
+func (t *TaxTotals) add(payments taxedPayments) {
+	t.Payments += payments.Payments
+	t.InvoicedPayments += payments.InvoicedPayments
+	if payments.Type == models.LateFeePayment {
+		t.LateFees = roundCents(t.LateFees + payments.Amount)
+	} else {
+		t.RentIncome = roundCents(t.RentIncome + payments.Amount)
+	}
+	t.VAT = roundCents(t.VAT + payments.VAT)
+	t.IncomeTaxWithheld = roundCents(t.IncomeTaxWithheld + payments.IncomeTaxWithheld)
+}
+
+// GetTaxSummary adds up the payments received in a calendar year per landlord and
+// property, optionally for one landlord. Payments towards deleted contracts count,
+// since their income was received all the same.
+func (s *ReportService) GetTaxSummary(ctx context.Context, year int, landlordID *uuid.UUID) (*TaxSummary, error) {
+	if year < 1900 || year > 9999 {
+		return nil, ErrInvalidTaxYear
+	}
+	yearStart := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	db := s.db.WithContext(ctx)
+	query := db.Model(&models.Payment{}).
+		Joins("JOIN contracts ON contracts.id = payments.contractid").
+		Where("payments.paidat >= ? AND payments.paidat < ?", yearStart, yearStart.AddDate(1, 0, 0))
+	if landlordID != nil {
+		query = query.Where("contracts.landlordid = ?", *landlordID)
+	}
+
+	var rows []taxedPayments
+	if err := query.Select("contracts.landlordid, contracts.addressid, payments.type, COUNT(*) AS payments, " +
+		"COUNT(payments.invoicenumber) AS invoicedpayments, SUM(payments.amount) AS amount, " +
+		"SUM(payments.vat) AS vat, SUM(payments.incometaxwithheld) AS incometaxwithheld").
+		Group("contracts.landlordid, contracts.addressid, payments.type").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	var landlordIDs, addressIDs []uuid.UUID
+	for _, r := range rows {
+		landlordIDs = append(landlordIDs, r.LandlordID)
+		addressIDs = append(addressIDs, r.AddressID)
+	}
+	landlordNames := map[uuid.UUID]string{}
+	propertyNames := map[uuid.UUID]string{}
+	if len(rows) > 0 {
+		var landlords []models.User
+		if err := db.Unscoped().Where("id IN ?", landlordIDs).Find(&landlords).Error; err != nil {
+			return nil, err
+		}
+		for _, landlord := range landlords {
+			landlordNames[landlord.ID] = landlord.FullName()
+		}
+
+		var addresses []models.Address
+		if err := db.Unscoped().Where("id IN ?", addressIDs).Find(&addresses).Error; err != nil {
+			return nil, err
+		}
+		for _, address := range addresses {
+			propertyNames[address.ID] = address.FullAddress()
+		}
+	}
+
+	landlords := map[uuid.UUID]*LandlordTaxSummary{}
+	properties := map[[2]uuid.UUID]*PropertyTaxSummary{}
+	for _, r := range rows {
+		landlord := landlords[r.LandlordID]
+		if landlord == nil {
+			landlord = &LandlordTaxSummary{LandlordID: r.LandlordID, Landlord: landlordNames[r.LandlordID]}
+			landlords[r.LandlordID] = landlord
+		}
+		landlord.add(r)
+
+		key := [2]uuid.UUID{r.LandlordID, r.AddressID}
+		if properties[key] == nil {
+			properties[key] = &PropertyTaxSummary{PropertyID: r.AddressID, Property: propertyNames[r.AddressID]}
+		}
+		properties[key].add(r)
+	}
+	for key, property := range properties {
+		landlord := landlords[key[0]]
+		landlord.Properties = append(landlord.Properties, *property)
+	}
+
+	summary := &TaxSummary{Year: year, GeneratedAt: time.Now(), Landlords: []LandlordTaxSummary{}}
+	for _, landlord := range landlords {
+		sort.Slice(landlord.Properties, func(i, j int) bool { return landlord.Properties[i].Property < landlord.Properties[j].Property })
+		summary.Landlords = append(summary.Landlords, *landlord)
+	}
+	sort.Slice(summary.Landlords, func(i, j int) bool { return summary.Landlords[i].Landlord < summary.Landlords[j].Landlord })
+
+	return summary, nil
+}
+
+// taxSummaryRecord formats totals in the order of taxSummaryColumns
+func taxSummaryRecord(landlord, property string, totals TaxTotals) []string {
+	amount := func(f float64) string { return strconv.FormatFloat(f, 'f', 2, 64) }
+	return []string{
+		landlord,
+		property,
+		strconv.Itoa(totals.Payments),
+		strconv.Itoa(totals.InvoicedPayments),
+		amount(totals.RentIncome),
+		amount(totals.LateFees),
+		amount(totals.VAT),
+		amount(totals.IncomeTaxWithheld),
+	}
+}
+
+// TaxSummaryCSV renders a tax summary as CSV with a row per property of each
+// landlord followed by a row with the landlord's totals, whose property is "Total"
+func TaxSummaryCSV(summary *TaxSummary) ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := csv.NewWriter(&buffer)
+
+	if err := writer.Write(taxSummaryColumns); err != nil {
+		return nil, err
+	}
+	for _, landlord := range summary.Landlords {
+		for _, property := range landlord.Properties {
+			if err := writer.Write(taxSummaryRecord(landlord.Landlord, property.Property, property.TaxTotals)); err != nil {
+				return nil, err
+			}
+		}
+		if err := writer.Write(taxSummaryRecord(landlord.Landlord, "Total", landlord.TaxTotals)); err != nil {
+			return nil, err
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// TaxSummaryPDF renders a tax summary with a table of properties per landlord,
+// each landlord starting on a new page
+func TaxSummaryPDF(summary *TaxSummary) ([]byte, error) {
+	cfg := config.NewBuilder().
+		WithPageSize(pagesize.Letter).
+		WithLeftMargin(12).
+		WithTopMargin(12).
+		WithRightMargin(12).
+		WithBottomMargin(12).
+		WithPageNumber().
+		Build()
+
+	m := maroto.New(cfg)
+
+	title := fmt.Sprintf("ANNUAL TAX SUMMARY %d", summary.Year)
+	if len(summary.Landlords) == 0 {
+		m.AddRows(
+			text.NewRow(10, title, props.Text{Style: fontstyle.Bold, Size: 14, Align: align.Center}),
+			text.NewRow(8, "No payments were received this year.", props.Text{Size: 8, Align: align.Center}),
+		)
+	}
+
+	header := func(label string, size int) core.Col {
+		return text.NewCol(size, label, props.Text{Size: 7, Align: align.Center, Style: fontstyle.Bold, Color: &props.WhiteColor})
+	}
+	for _, landlord := range summary.Landlords {
+		rows := []core.Row{
+			text.NewRow(10, title, props.Text{Style: fontstyle.Bold, Size: 14, Align: align.Center}),
+			text.NewRow(8, landlord.Landlord, props.Text{Style: fontstyle.Bold, Size: 9, Align: align.Center}),
+			row.New(8).Add(
+				header("Property", 4),
+				header("Payments", 1),
+				header("Invoiced", 1),
+				header("Rent income", 2),
+				header("Late fees", 1),
+				header("VAT charged", 2),
+				header("ISR withheld", 1),
+			).WithStyle(&props.Cell{BackgroundColor: darkGrayColor()}),
+		}
+
+		cells := func(record []string, style fontstyle.Type) core.Row {
+			cell := func(value string, size int, alignment align.Type) core.Col {
+				return text.NewCol(size, value, props.Text{Size: 7, Top: 1, Style: style, Align: alignment})
+			}
+			return row.New(6).Add(
+				cell(record[1], 4, align.Left),
+				cell(record[2], 1, align.Center),
+				cell(record[3], 1, align.Center),
+				cell(record[4], 2, align.Right),
+				cell(record[5], 1, align.Right),
+				cell(record[6], 2, align.Right),
+				cell(record[7], 1, align.Right),
+			)
+		}
+		for j, property := range landlord.Properties {
+			r := cells(taxSummaryRecord(landlord.Landlord, property.Property, property.TaxTotals), fontstyle.Normal)
+			if j%2 == 0 {
+				r.WithStyle(&props.Cell{BackgroundColor: grayColor()})
+			}
+			rows = append(rows, r)
+		}
+		rows = append(rows, cells(taxSummaryRecord(landlord.Landlord, "TOTAL", landlord.TaxTotals), fontstyle.Bold))
+		m.AddPages(page.New().Add(rows...))
+	}
+
+	document, err := m.Generate()
+	if err != nil {
+		return nil, err
+	}
+	return document.GetBytes(), nil
+}
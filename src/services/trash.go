@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/edfloreshz/rent-contracts/src/auth"
+	"github.com/edfloreshz/rent-contracts/src/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrRestoreConflict is returned when a record cannot leave the trash because
+	// a record it depends on is still deleted or its place has been taken
+	ErrRestoreConflict = errors.New("record cannot be restored")
+
+	// ErrStillReferenced is returned when purging a record other records depend on
+	ErrStillReferenced = errors.New("record is still referenced")
+)
+
+// retentionInterval is how often expired records are purged from the trash
+const retentionInterval = time.Hour
+
+type TrashService struct {
+	db *gorm.DB
+}
+
+func NewTrashService(db *gorm.DB) *TrashService {
+	return &TrashService{
+		db: db,
+	}
+}
+
+// RunRetention purges the records deleted longer than retention ago, every
+// retentionInterval, until ctx is done
+func (s *TrashService) RunRetention(ctx context.Context, retention time.Duration) {
+	ticker := time.NewTicker(retentionInterval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := s.PurgeExpired(ctx, time.Now().Add(-retention))
+		if err != nil {
+			log.Println("Failed to purge expired records:", err)
+		} else if purged > 0 {
+			log.Printf("Purged %d expired records from the trash", purged)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeExpired permanently removes the records of every organization deleted before
+// cutoff. Contracts go first so that the users and addresses they held can follow,
+// and records still referenced by a record in use, or contracts with payments, are
+// kept.
+func (s *TrashService) PurgeExpired(ctx context.Context, cutoff time.Time) (int64, error) {
+	db := s.db.WithContext(auth.WithPrincipal(ctx, auth.System))
+	var purged int64
+
+	var contracts []models.Contract
+	if err := db.Unscoped().Where("deletedat < ?", cutoff).Find(&contracts).Error; err != nil {
+		return purged, err
+	}
+	for _, contract := range contracts {
+		err := db.Transaction(func(tx *gorm.DB) error { return purgeContract(tx, &contract) })
+		if errors.Is(err, ErrStillReferenced) {
+			continue
+		}
+		if err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	var users []models.User
+	if err := db.Unscoped().Where("deletedat < ?", cutoff).Find(&users).Error; err != nil {
+		return purged, err
+	}
+	for _, user := range users {
+		err := db.Transaction(func(tx *gorm.DB) error { return purgeUser(tx, &user) })
+		if errors.Is(err, ErrStillReferenced) {
+			continue
+		}
+		if err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	var addresses []models.Address
+	if err := db.Unscoped().Where("deletedat < ?", cutoff).Find(&addresses).Error; err != nil {
+		return purged, err
+	}
+	for _, address := range addresses {
+		err := db.Transaction(func(tx *gorm.DB) error { return purgeAddress(tx, &address) })
+		if errors.Is(err, ErrStillReferenced) {
+			continue
+		}
+		if err != nil {
+			return purged, err
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+// findDeleted loads a record from the trash
+func findDeleted(tx *gorm.DB, dest interface{}, id uuid.UUID) error {
+	return tx.Unscoped().Where("deletedat IS NOT NULL").First(dest, id).Error
+}
+
+// restoreRecord takes a record loaded by findDeleted out of the trash and reloads
+// it to pick up the version and timestamps set by the database
+func restoreRecord(tx *gorm.DB, record interface{}, id uuid.UUID) error {
+	if err := tx.Unscoped().Model(record).Update("deletedat", nil).Error; err != nil {
+		return err
+	}
+	return tx.First(record, id).Error
+}
+
+// exists reports whether any record matches the query
+func exists(query *gorm.DB) (bool, error) {
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// purgeAddress permanently removes a deleted address and its history unless a
+// user or contract, deleted or not, or an expense still points at it
+func purgeAddress(tx *gorm.DB, address *models.Address) error {
+	referenced, err := exists(tx.Unscoped().Model(&models.User{}).Where("addressid = ?", address.ID))
+	if err != nil {
+		return err
+	}
+	if !referenced {
+		referenced, err = exists(tx.Unscoped().Model(&models.Contract{}).Where("addressid = ?", address.ID))
+		if err != nil {
+			return err
+		}
+	}
+	if !referenced {
+		referenced, err = exists(tx.Model(&models.Expense{}).Where("addressid = ?", address.ID))
+		if err != nil {
+			return err
+		}
+	}
+	if referenced {
+		return ErrStillReferenced
+	}
+
+	if err := tx.Unscoped().Delete(address).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("DELETE FROM history WHERE tablename = 'addresses' AND rowid = ?", address.ID).Error; err != nil {
+		return err
+	}
+	return recordAudit(tx, address.OrganizationID, models.AuditPurge, AuditAddress, address.ID, nil, nil)
+}
+
+// purgeUser permanently removes a deleted user and its history unless a contract,
+// deleted or not, still names them as a party or reference, or expenses were paid
+// on their behalf
+func purgeUser(tx *gorm.DB, user *models.User) error {
+	referenced, err := exists(tx.Unscoped().Model(&models.Contract{}).Where("tenantid = ? OR landlordid = ?", user.ID, user.ID))
+	if err != nil {
+		return err
+	}
+	if !referenced {
+		referenced, err = exists(tx.Model(&models.ContractReference{}).Where("referenceid = ?", user.ID))
+		if err != nil {
+			return err
+		}
+	}
+	if !referenced {
+		referenced, err = exists(tx.Model(&models.Expense{}).Where("landlordid = ?", user.ID))
+		if err != nil {
+			return err
+		}
+	}
+	if referenced {
+		return ErrStillReferenced
+	}
+
+	if err := tx.Unscoped().Delete(user).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec("DELETE FROM history WHERE tablename = 'users' AND rowid = ?", user.ID).Error; err != nil {
+		return err
+	}
+	return recordAudit(tx, user.OrganizationID, models.AuditPurge, AuditUser, user.ID, nil, nil)
+}
+
+// purgeContract permanently removes a deleted contract with its versions, its
+// references and their history unless payments were recorded towards it, which
+// are kept for the financial records
+func purgeContract(tx *gorm.DB, contract *models.Contract) error {
+	referenced, err := exists(tx.Model(&models.Payment{}).Where("contractid = ?", contract.ID))
+	if err != nil {
+		return err
+	}
+	if referenced {
+		return ErrStillReferenced
+	}
+
+	// The current version is removed along with the contract, so let go of it first
+	if err := tx.Unscoped().Model(contract).Update("currentversionid", nil).Error; err != nil {
+		return err
+	}
+	if err := tx.Unscoped().Delete(contract).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec(`DELETE FROM history
+		WHERE (tablename IN ('contracts', 'contractreferences') AND rowid = ?)
+		   OR (tablename = 'contractversions' AND data->>'contractid' = ?)`, contract.ID, contract.ID.String()).Error; err != nil {
+		return err
+	}
+	return recordAudit(tx, contract.OrganizationID, models.AuditPurge, AuditContract, contract.ID, nil, nil)
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultMaxAttempts is how many times a unit of work runs before giving up on serialization failures
+	defaultMaxAttempts = 5
+
+	// retryBaseDelay is the initial backoff between attempts
+	retryBaseDelay = 20 * time.Millisecond
+)
+
+// PostgreSQL error codes that mean the transaction can safely be retried
+const (
+	serializationFailureCode = "40001"
+	deadlockDetectedCode     = "40P01"
+)
+
+// UnitOfWork runs multi-step operations atomically inside a database transaction
+type UnitOfWork struct {
+	db          *gorm.DB
+	maxAttempts int
+}
+
+func NewUnitOfWork(db *gorm.DB) *UnitOfWork {
+	return &UnitOfWork{
+		db:          db,
+		maxAttempts: defaultMaxAttempts,
+	}
+}
+
+// txOptions run units of work at REPEATABLE READ, so a transaction that reads a row
+// a concurrent one has since changed fails with a serialization failure and is
+// retried, instead of acting on what it read
+var txOptions = &sql.TxOptions{Isolation: sql.LevelRepeatableRead}
+
+// Do runs fn inside a transaction, committing when it returns nil and rolling back
+// otherwise. Serialization failures and deadlocks are retried with backoff, so fn
+// must not keep state between attempts. When the unit of work is already bound to
+// a transaction, fn runs in a savepoint and failures are left to the outer unit.
+func (u *UnitOfWork) Do(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	db := u.db.WithContext(ctx)
+	if inTransaction(db) {
+		return db.Transaction(fn)
+	}
+
+	var err error
+	for attempt := 1; attempt <= u.maxAttempts; attempt++ {
+		err = db.Transaction(fn, txOptions)
+		if err == nil || !isRetryable(err) || attempt == u.maxAttempts {
+			return err
+		}
+
+		// Back off exponentially with jitter before the next attempt
+		delay := retryBaseDelay << (attempt - 1)
+		time.Sleep(delay + time.Duration(rand.Int63n(int64(delay))))
+	}
+
+	return err
+}
+
+// WithTx returns a unit of work bound to an existing transaction
+func (u *UnitOfWork) WithTx(tx *gorm.DB) *UnitOfWork {
+	return &UnitOfWork{
+		db:          tx,
+		maxAttempts: u.maxAttempts,
+	}
+}
+
+// inTransaction reports whether db is bound to an open transaction
+func inTransaction(db *gorm.DB) bool {
+	committer, ok := db.Statement.ConnPool.(gorm.TxCommitter)
+	return ok && committer != nil
+}
+
+// isRetryable reports whether err is a transient concurrency failure
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == serializationFailureCode || pgErr.Code == deadlockDetectedCode
+	}
+	return false
+}
@@ -1,8 +1,12 @@
 package services
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"time"
 
+	"github.com/edfloreshz/rent-contracts/src/auth"
 	"github.com/edfloreshz/rent-contracts/src/dto"
 	"github.com/edfloreshz/rent-contracts/src/models"
 
@@ -20,7 +24,14 @@ func NewUserService(db *gorm.DB) *UserService {
 	}
 }
 
-func (s *UserService) CreateUser(req *dto.CreateUserRequest) (*models.User, error) {
+// WithTx returns a copy of the service that runs its queries in the given transaction
+func (s *UserService) WithTx(tx *gorm.DB) *UserService {
+	return &UserService{
+		tx,
+	}
+}
+
+func (s *UserService) CreateUser(ctx context.Context, req *dto.CreateUserRequest) (*models.User, error) {
 	user := &models.User{
 		Type:       models.UserType(req.Type),
 		AddressID:  req.AddressID,
@@ -31,16 +42,22 @@ func (s *UserService) CreateUser(req *dto.CreateUserRequest) (*models.User, erro
 		Phone:      req.Phone,
 	}
 
-	if err := s.db.Create(user).Error; err != nil {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		return recordAudit(tx, user.OrganizationID, models.AuditCreate, AuditUser, user.ID, nil, user)
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return user, nil
 }
 
-func (s *UserService) GetUserByID(id uuid.UUID) (*models.User, error) {
+func (s *UserService) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
 	var user models.User
-	if err := s.db.Preload("Address").First(&user, id).Error; err != nil {
+	if err := s.db.WithContext(ctx).Scopes(userAccessScope(ctx)).Preload("Address").First(&user, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("user not found")
 		}
@@ -49,31 +66,53 @@ func (s *UserService) GetUserByID(id uuid.UUID) (*models.User, error) {
 	return &user, nil
 }
 
-func (s *UserService) GetAllUsers() ([]models.User, error) {
+// GetUserAsOf returns a user as it was at asOf
+func (s *UserService) GetUserAsOf(ctx context.Context, id uuid.UUID, asOf time.Time) (*models.User, error) {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok || (!principal.SeesEverything() && principal.UserID != id) {
+		return nil, errors.New("user not found")
+	}
+
+	user, err := userAsOf(ctx, s.db, id, asOf, true)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *UserService) GetAllUsers(ctx context.Context) ([]models.User, error) {
 	var users []models.User
-	if err := s.db.Preload("Address").Find(&users).Error; err != nil {
+	if err := s.db.WithContext(ctx).Scopes(userAccessScope(ctx)).Preload("Address").Find(&users).Error; err != nil {
 		return nil, err
 	}
 	return users, nil
 }
 
-func (s *UserService) GetUsersByType(userType string) ([]models.User, error) {
+func (s *UserService) GetUsersByType(ctx context.Context, userType string) ([]models.User, error) {
 	var users []models.User
-	if err := s.db.Preload("Address").Where("type = ?", userType).Find(&users).Error; err != nil {
+	if err := s.db.WithContext(ctx).Scopes(userAccessScope(ctx)).Preload("Address").Where("type = ?", userType).Find(&users).Error; err != nil {
 		return nil, err
 	}
 	return users, nil
 }
 
-func (s *UserService) UpdateUser(id uuid.UUID, req *dto.UpdateUserRequest) (*models.User, error) {
+func (s *UserService) UpdateUser(ctx context.Context, id uuid.UUID, req *dto.UpdateUserRequest, expectedVersions []int) (*models.User, error) {
 	var user models.User
-	if err := s.db.First(&user, id).Error; err != nil {
+	if err := s.db.WithContext(ctx).First(&user, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("user not found")
 		}
 		return nil, err
 	}
 
+	if err := checkVersion(user.Version, expectedVersions); err != nil {
+		return nil, err
+	}
+	before := user
+
 	// Update only provided fields
 	if req.Type != nil {
 		user.Type = models.UserType(*req.Type)
@@ -97,16 +136,147 @@ func (s *UserService) UpdateUser(id uuid.UUID, req *dto.UpdateUserRequest) (*mod
 		user.Phone = *req.Phone
 	}
 
-	if err := s.db.Save(&user).Error; err != nil {
+	if err := s.saveUser(ctx, &before, &user); err != nil {
 		return nil, err
 	}
 
 	return &user, nil
 }
 
-func (s *UserService) DeleteUser(id uuid.UUID) error {
-	if err := s.db.Delete(&models.User{}, id).Error; err != nil {
-		return err
+// PatchUser applies a JSON merge patch to a user and returns the names of the changed fields
+func (s *UserService) PatchUser(ctx context.Context, id uuid.UUID, patch []byte, expectedVersions []int) (*models.User, []string, error) {
+	var user models.User
+	if err := s.db.WithContext(ctx).First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, errors.New("user not found")
+		}
+		return nil, nil, err
+	}
+
+	if err := checkVersion(user.Version, expectedVersions); err != nil {
+		return nil, nil, err
+	}
+	before := user
+
+	document := dto.UserPatch{
+		Type:       string(user.Type),
+		AddressID:  user.AddressID,
+		FirstName:  user.FirstName,
+		MiddleName: user.MiddleName,
+		LastName:   user.LastName,
+		Email:      user.Email,
+		Phone:      user.Phone,
 	}
-	return nil
+
+	changed, err := applyMergePatch(&document, patch)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(changed) == 0 {
+		return &user, changed, nil
+	}
+
+	user.Type = models.UserType(document.Type)
+	user.AddressID = document.AddressID
+	user.FirstName = document.FirstName
+	user.MiddleName = document.MiddleName
+	user.LastName = document.LastName
+	user.Email = document.Email
+	user.Phone = document.Phone
+
+	if err := s.saveUser(ctx, &before, &user); err != nil {
+		return nil, nil, err
+	}
+
+	return &user, changed, nil
+}
+
+// DeleteUser deletes a user. Unless cascade is set it fails with a DependencyError
+// while contracts still name the user.
+func (s *UserService) DeleteUser(ctx context.Context, id uuid.UUID, cascade bool) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.First(&user, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("user not found")
+			}
+			return err
+		}
+
+		return deleteUser(tx, &user, cascade)
+	})
+}
+
+// GetDeletedUsers returns the users in the trash, most recently deleted first
+func (s *UserService) GetDeletedUsers(ctx context.Context) ([]models.User, error) {
+	var users []models.User
+	if err := s.db.WithContext(ctx).Unscoped().Where("deletedat IS NOT NULL").Order("deletedat DESC").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// RestoreUser takes a user out of the trash. The address of the user must not be
+// deleted and no other user may have taken their email in the meantime.
+func (s *UserService) RestoreUser(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	var user models.User
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := findDeleted(tx, &user, id); err != nil {
+			return err
+		}
+
+		addressExists, err := exists(tx.Model(&models.Address{}).Where("id = ?", user.AddressID))
+		if err != nil {
+			return err
+		}
+		if !addressExists {
+			return fmt.Errorf("%w: address %s is deleted", ErrRestoreConflict, user.AddressID)
+		}
+
+		emailTaken, err := exists(tx.Model(&models.User{}).Where("email = ? AND id <> ?", user.Email, user.ID))
+		if err != nil {
+			return err
+		}
+		if emailTaken {
+			return fmt.Errorf("%w: email %s is used by another user", ErrRestoreConflict, user.Email)
+		}
+
+		before := user
+		if err := restoreRecord(tx, &user, id); err != nil {
+			return err
+		}
+		return recordAudit(tx, user.OrganizationID, models.AuditRestore, AuditUser, user.ID, &before, &user)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.New("user not found in trash")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// PurgeUser permanently removes a user from the trash
+func (s *UserService) PurgeUser(ctx context.Context, id uuid.UUID) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := findDeleted(tx, &user, id); err != nil {
+			return err
+		}
+		return purgeUser(tx, &user)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return errors.New("user not found in trash")
+	}
+	return err
+}
+
+// saveUser stores the changes made to a user and records them in the audit log
+func (s *UserService) saveUser(ctx context.Context, before, user *models.User) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := saveVersioned(tx, user, &user.Version); err != nil {
+			return err
+		}
+		return recordAudit(tx, user.OrganizationID, models.AuditUpdate, AuditUser, user.ID, before, user)
+	})
 }
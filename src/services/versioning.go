@@ -0,0 +1,55 @@
+package services
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrPreconditionFailed is returned when the version supplied by the client
+	// does not match the stored version of the record.
+	ErrPreconditionFailed = errors.New("record version does not match")
+
+	// ErrVersionConflict is returned when the record was modified by another
+	// request between reading and saving it.
+	ErrVersionConflict = errors.New("record was modified by another request")
+)
+
+// checkVersion compares the stored version of a record with the versions the
+// client expects. Nil expected versions skip the check.
+func checkVersion(current int, expected []int) error {
+	if expected == nil {
+		return nil
+	}
+	for _, version := range expected {
+		if version == current {
+			return nil
+		}
+	}
+	return ErrPreconditionFailed
+}
+
+// saveVersioned saves all fields of value only if its stored version still
+// matches the version it was read with, and increments the version on success.
+func saveVersioned(db *gorm.DB, value interface{}, version *int) error {
+	current := *version
+	*version = current + 1
+
+	result := db.Model(value).
+		Where("version = ?", current).
+		Select("*").
+		Omit(clause.Associations).
+		Updates(value)
+	if result.Error != nil {
+		*version = current
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		*version = current
+		return ErrVersionConflict
+	}
+
+	return nil
+}